@@ -0,0 +1,132 @@
+package addon
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+)
+
+type AddonRepository interface {
+	Addons(ctx context.Context, token string) ([]domain.Addon, error)
+	SetAddon(ctx context.Context, token, addonID string, enable bool) error
+}
+
+// ProfileRepository reads the amount due, which includes connected add-ons.
+type ProfileRepository interface {
+	Profile(ctx context.Context, token string) (domain.Profile, error)
+}
+
+// ProfileInvalidator drops the cached profiles of a contract once a change
+// makes them stale.
+type ProfileInvalidator interface {
+	InvalidateContract(contractID string)
+}
+
+type Service struct {
+	addonRepo   AddonRepository
+	profileRepo ProfileRepository
+	profiles    ProfileInvalidator
+	logger      *slog.Logger
+}
+
+// NewService creates a new Service instance with the provided repositories.
+func NewService(a AddonRepository, p ProfileRepository, i ProfileInvalidator, logger *slog.Logger) *Service {
+	return &Service{
+		addonRepo:   a,
+		profileRepo: p,
+		profiles:    i,
+		logger:      logger,
+	}
+}
+
+// Addons lists connected and available add-ons with their effect on the payment.
+func (s *Service) Addons(ctx context.Context, token string) (domain.AddonList, error) {
+	ctx, span := tracing.Start(ctx, "addon.Addons")
+	defer span.End()
+
+	profile, addons, err := s.load(ctx, token)
+	if err != nil {
+		return domain.AddonList{}, err
+	}
+
+	list := domain.AddonList{Items: addons, AddonsTotal: domain.RUB(0), ToPay: profile.ToPay}
+	for _, addon := range addons {
+		if addon.Connected {
+			list.AddonsTotal.Minor += addon.MonthlyPrice.Minor
+		}
+	}
+	return list, nil
+}
+
+// ChangeAddon enables or disables an add-on. Without confirm it only
+// returns the preview of the change, so the client can ask the user first.
+func (s *Service) ChangeAddon(ctx context.Context, token, addonID string, enable, confirm bool) (domain.AddonChange, error) {
+	ctx, span := tracing.Start(ctx, "addon.ChangeAddon")
+	defer span.End()
+
+	profile, addons, err := s.load(ctx, token)
+	if err != nil {
+		return domain.AddonChange{}, err
+	}
+
+	var addon *domain.Addon
+	for i := range addons {
+		if addons[i].ID == addonID {
+			addon = &addons[i]
+		}
+	}
+	if addon == nil {
+		return domain.AddonChange{}, domain.ErrNotFound
+	}
+	if addon.Connected == enable {
+		return domain.AddonChange{}, domain.ErrConflict
+	}
+
+	change := domain.AddonChange{
+		Addon:       *addon,
+		Enable:      enable,
+		ToPayBefore: profile.ToPay,
+	}
+	if profile.ToPay != nil {
+		after := domain.RUB(max(0, profile.ToPay.Minor+addon.ToPayEffect.Minor))
+		change.ToPayAfter = &after
+	}
+	if !confirm {
+		return change, nil
+	}
+
+	s.logger.InfoContext(ctx, "Setting add-on", "addon_id", addonID, "enable", enable)
+	if err := s.addonRepo.SetAddon(ctx, token, addonID, enable); err != nil {
+		s.logger.ErrorContext(ctx, "Error changing add-on", "error", err)
+		return domain.AddonChange{}, err
+	}
+	s.profiles.InvalidateContract(profile.ID)
+	change.Addon.Connected = enable
+	change.Addon.ToPayEffect = domain.RUB(-addon.ToPayEffect.Minor)
+	change.Confirmed = true
+	return change, nil
+}
+
+func (s *Service) load(ctx context.Context, token string) (domain.Profile, []domain.Addon, error) {
+	if token == "" || middleware.ContainsForbiddenChars(token) {
+		return domain.Profile{}, nil, domain.ErrInvalidToken
+	}
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		return domain.Profile{}, nil, err
+	}
+	addons, err := s.addonRepo.Addons(ctx, token)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching add-ons", "error", err)
+		return domain.Profile{}, nil, err
+	}
+	for i := range addons {
+		addons[i].ToPayEffect = addons[i].MonthlyPrice
+		if addons[i].Connected {
+			addons[i].ToPayEffect = domain.RUB(-addons[i].MonthlyPrice.Minor)
+		}
+	}
+	return profile, addons, nil
+}
@@ -0,0 +1,174 @@
+package addon
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"testing"
+)
+
+type stubAddonRepo struct {
+	addons []domain.Addon
+	err    error
+	setErr error
+	// set records the add-ons changed, as "id=true" or "id=false".
+	set []string
+}
+
+func (s *stubAddonRepo) Addons(context.Context, string) ([]domain.Addon, error) {
+	return append([]domain.Addon(nil), s.addons...), s.err
+}
+
+func (s *stubAddonRepo) SetAddon(_ context.Context, _, addonID string, enable bool) error {
+	if s.setErr != nil {
+		return s.setErr
+	}
+	if enable {
+		s.set = append(s.set, addonID+"=true")
+	} else {
+		s.set = append(s.set, addonID+"=false")
+	}
+	return nil
+}
+
+type stubProfileRepo struct {
+	profile domain.Profile
+	err     error
+}
+
+func (s stubProfileRepo) Profile(context.Context, string) (domain.Profile, error) {
+	return s.profile, s.err
+}
+
+type stubInvalidator struct {
+	contracts []string
+}
+
+func (s *stubInvalidator) InvalidateContract(contractID string) {
+	s.contracts = append(s.contracts, contractID)
+}
+
+func testAddons() []domain.Addon {
+	return []domain.Addon{
+		{ID: "tv", Name: "ТВ", MonthlyPrice: domain.RUB(25000), Connected: true},
+		{ID: "ip", Name: "Статический IP", MonthlyPrice: domain.RUB(15000)},
+		{ID: "av", Name: "Антивирус", MonthlyPrice: domain.RUB(9950), Connected: true},
+	}
+}
+
+func TestAddons(t *testing.T) {
+	profile := stubProfileRepo{profile: domain.Profile{ID: "1", ToPay: rub(65000)}}
+	svc := NewService(&stubAddonRepo{addons: testAddons()}, profile, &stubInvalidator{}, logging.Discard())
+
+	list, err := svc.Addons(context.Background(), "token")
+	if err != nil {
+		t.Fatalf("Addons() error = %v", err)
+	}
+	if list.AddonsTotal != domain.RUB(34950) {
+		t.Errorf("add-ons total = %v, want 349.50", list.AddonsTotal)
+	}
+	wantEffects := map[string]int64{"tv": -25000, "ip": 15000, "av": -9950}
+	for _, addon := range list.Items {
+		if addon.ToPayEffect.Minor != wantEffects[addon.ID] {
+			t.Errorf("%s effect = %v, want %d kopecks", addon.ID, addon.ToPayEffect, wantEffects[addon.ID])
+		}
+	}
+}
+
+func TestChangeAddon(t *testing.T) {
+	tests := []struct {
+		name      string
+		addonID   string
+		enable    bool
+		confirm   bool
+		toPay     *domain.Money
+		wantAfter *domain.Money
+		wantSet   []string
+	}{
+		{"Connect preview", "ip", true, false, rub(65000), rub(80000), nil},
+		{"Connect", "ip", true, true, rub(65000), rub(80000), []string{"ip=true"}},
+		{"Disconnect", "tv", false, true, rub(65000), rub(40000), []string{"tv=false"}},
+		{"Disconnect never makes the payment negative", "tv", false, true, rub(10000), rub(0), []string{"tv=false"}},
+		{"Unknown amount due", "ip", true, true, nil, nil, []string{"ip=true"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubAddonRepo{addons: testAddons()}
+			profiles := &stubInvalidator{}
+			svc := NewService(repo, stubProfileRepo{profile: domain.Profile{ID: "1", ToPay: tt.toPay}}, profiles, logging.Discard())
+
+			change, err := svc.ChangeAddon(context.Background(), "token", tt.addonID, tt.enable, tt.confirm)
+			if err != nil {
+				t.Fatalf("ChangeAddon() error = %v", err)
+			}
+			if (change.ToPayAfter == nil) != (tt.wantAfter == nil) || (change.ToPayAfter != nil && *change.ToPayAfter != *tt.wantAfter) {
+				t.Errorf("to pay after = %v, want %v", change.ToPayAfter, tt.wantAfter)
+			}
+			if change.Confirmed != tt.confirm || len(repo.set) != len(tt.wantSet) {
+				t.Fatalf("confirmed = %v with changes %v, want %v", change.Confirmed, repo.set, tt.wantSet)
+			}
+			for i := range tt.wantSet {
+				if repo.set[i] != tt.wantSet[i] {
+					t.Errorf("changes = %v, want %v", repo.set, tt.wantSet)
+				}
+			}
+			if !tt.confirm {
+				if len(profiles.contracts) != 0 {
+					t.Errorf("preview invalidated the profile")
+				}
+				return
+			}
+			if change.Addon.Connected != tt.enable {
+				t.Errorf("connected = %v, want %v", change.Addon.Connected, tt.enable)
+			}
+			if len(profiles.contracts) != 1 || profiles.contracts[0] != "1" {
+				t.Errorf("invalidated %v, want contract 1", profiles.contracts)
+			}
+		})
+	}
+}
+
+func TestChangeAddonErrors(t *testing.T) {
+	upstream := errors.New("billing unavailable")
+	tests := []struct {
+		name       string
+		token      string
+		addonID    string
+		enable     bool
+		profileErr error
+		addonsErr  error
+		setErr     error
+		wantErr    error
+	}{
+		{"Missing token", "", "ip", true, nil, nil, nil, domain.ErrInvalidToken},
+		{"Forbidden characters in token", "tok;en", "ip", true, nil, nil, nil, domain.ErrInvalidToken},
+		{"Unknown add-on", "token", "vpn", true, nil, nil, nil, domain.ErrNotFound},
+		{"Already connected", "token", "tv", true, nil, nil, nil, domain.ErrConflict},
+		{"Already disconnected", "token", "ip", false, nil, nil, nil, domain.ErrConflict},
+		{"Profile unavailable", "token", "ip", true, domain.ErrSessionExpired, nil, nil, domain.ErrSessionExpired},
+		{"Add-ons unavailable", "token", "ip", true, nil, upstream, nil, upstream},
+		{"Billing rejects the change", "token", "ip", true, nil, nil, upstream, upstream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubAddonRepo{addons: testAddons(), err: tt.addonsErr, setErr: tt.setErr}
+			profiles := &stubInvalidator{}
+			svc := NewService(repo, stubProfileRepo{profile: domain.Profile{ID: "1", ToPay: rub(65000)}, err: tt.profileErr}, profiles, logging.Discard())
+
+			if _, err := svc.ChangeAddon(context.Background(), tt.token, tt.addonID, tt.enable, true); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ChangeAddon() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(profiles.contracts) != 0 {
+				t.Errorf("invalidated %v after a failed change", profiles.contracts)
+			}
+		})
+	}
+}
+
+func rub(minor int64) *domain.Money {
+	m := domain.RUB(minor)
+	return &m
+}
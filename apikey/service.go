@@ -0,0 +1,294 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/storage"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// keyPrefix starts every issued key, so leaked keys are easy to search for.
+// The ID follows, then a dot and the secret.
+const keyPrefix = "spk_"
+
+// configKeyID identifies the key from the configuration file.
+const configKeyID = "config"
+
+type KeyRepository interface {
+	Create(ctx context.Context, key storage.APIKey) error
+	Get(ctx context.Context, id string) (storage.APIKey, error)
+	List(ctx context.Context) ([]storage.APIKey, error)
+	Expire(ctx context.Context, id string, at time.Time) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+	RecordUse(ctx context.Context, id string, at time.Time) error
+}
+
+type Service struct {
+	keys KeyRepository
+	// configHash is the hash of the key from the configuration, if any.
+	configHash []byte
+	now        func() time.Time
+	logger     *slog.Logger
+}
+
+// NewService creates a Service over the keys in keys. configKey, when not
+// empty, is accepted as well, as an admin key whose use isn't recorded.
+func NewService(keys KeyRepository, configKey string, logger *slog.Logger) *Service {
+	s := &Service{
+		keys:   keys,
+		now:    time.Now,
+		logger: logger,
+	}
+	if configKey != "" {
+		s.configHash = hash(configKey)
+	}
+	return s
+}
+
+// Create issues a key named name for scopes. A positive ttl makes the key
+// expire after it.
+func (s *Service) Create(ctx context.Context, name string, scopes []domain.APIKeyScope, ttl time.Duration) (domain.IssuedAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "apikey.Create")
+	defer span.End()
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(scopes) == 0 || ttl < 0 {
+		return domain.IssuedAPIKey{}, domain.ErrBadParamInput
+	}
+	for _, scope := range scopes {
+		if _, err := domain.ParseAPIKeyScope(string(scope)); err != nil {
+			return domain.IssuedAPIKey{}, domain.ErrBadParamInput
+		}
+	}
+	return s.issue(ctx, name, scopes, ttl)
+}
+
+func (s *Service) issue(ctx context.Context, name string, scopes []domain.APIKeyScope, ttl time.Duration) (domain.IssuedAPIKey, error) {
+	id, secret, err := newKey()
+	if err != nil {
+		return domain.IssuedAPIKey{}, err
+	}
+	record := storage.APIKey{
+		ID:         id,
+		Name:       name,
+		SecretHash: hex.EncodeToString(hash(secret)),
+		CreatedAt:  s.now(),
+	}
+	for _, scope := range scopes {
+		record.Scopes = append(record.Scopes, string(scope))
+	}
+	if ttl > 0 {
+		expires := record.CreatedAt.Add(ttl)
+		record.ExpiresAt = &expires
+	}
+	if err := s.keys.Create(ctx, record); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to store API key", "name", name, "error", err)
+		return domain.IssuedAPIKey{}, domain.ErrInternalServerError
+	}
+	s.logger.InfoContext(ctx, "API key created", "id", id, "name", name, "scopes", record.Scopes)
+	return domain.IssuedAPIKey{APIKey: toDomain(record), Secret: secret}, nil
+}
+
+// List returns every key, revoked and expired ones included, oldest first.
+func (s *Service) List(ctx context.Context) ([]domain.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "apikey.List")
+	defer span.End()
+
+	records, err := s.keys.List(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to list API keys", "error", err)
+		return nil, domain.ErrInternalServerError
+	}
+	keys := make([]domain.APIKey, 0, len(records))
+	for _, record := range records {
+		keys = append(keys, toDomain(record))
+	}
+	return keys, nil
+}
+
+// Rotate issues a replacement for the key id with the same name, scopes and
+// lifetime, and lets the old key work for grace more, so clients can switch
+// over without downtime. A key due to expire sooner keeps its expiry.
+func (s *Service) Rotate(ctx context.Context, id string, grace time.Duration) (domain.IssuedAPIKey, error) {
+	ctx, span := tracing.Start(ctx, "apikey.Rotate")
+	defer span.End()
+
+	if grace < 0 {
+		return domain.IssuedAPIKey{}, domain.ErrBadParamInput
+	}
+	old, err := s.get(ctx, id)
+	if err != nil {
+		return domain.IssuedAPIKey{}, err
+	}
+	if !old.Active(s.now()) {
+		return domain.IssuedAPIKey{}, domain.ErrNotEligible
+	}
+	var ttl time.Duration
+	if old.ExpiresAt != nil {
+		ttl = old.ExpiresAt.Sub(old.CreatedAt)
+	}
+	issued, err := s.issue(ctx, old.Name, old.Scopes, ttl)
+	if err != nil {
+		return domain.IssuedAPIKey{}, err
+	}
+	expires := s.now().Add(grace)
+	if old.ExpiresAt != nil && old.ExpiresAt.Before(expires) {
+		expires = *old.ExpiresAt
+	}
+	if err := s.keys.Expire(ctx, id, expires); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to expire rotated API key", "id", id, "error", err)
+		return domain.IssuedAPIKey{}, domain.ErrInternalServerError
+	}
+	s.logger.InfoContext(ctx, "API key rotated", "id", id, "replacement", issued.ID, "grace", grace)
+	return issued, nil
+}
+
+// Revoke disables the key id at once.
+func (s *Service) Revoke(ctx context.Context, id string) error {
+	ctx, span := tracing.Start(ctx, "apikey.Revoke")
+	defer span.End()
+
+	if err := s.keys.Revoke(ctx, id, s.now()); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.ErrNotFound
+		}
+		s.logger.ErrorContext(ctx, "Failed to revoke API key", "id", id, "error", err)
+		return domain.ErrInternalServerError
+	}
+	s.logger.InfoContext(ctx, "API key revoked", "id", id)
+	return nil
+}
+
+// Authenticate returns the active key whose secret is secret and records
+// its use.
+func (s *Service) Authenticate(ctx context.Context, secret string) (domain.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "apikey.Authenticate")
+	defer span.End()
+
+	key, err := s.identify(ctx, secret)
+	if err != nil {
+		return domain.APIKey{}, err
+	}
+	s.recordUse(ctx, key)
+	return key, nil
+}
+
+// RecordUse records the use of a key that Identify already returned for
+// the request, so the key isn't loaded twice.
+func (s *Service) RecordUse(ctx context.Context, key domain.APIKey) {
+	ctx, span := tracing.Start(ctx, "apikey.RecordUse")
+	defer span.End()
+
+	s.recordUse(ctx, key)
+}
+
+func (s *Service) recordUse(ctx context.Context, key domain.APIKey) {
+	if key.ID == configKeyID {
+		return
+	}
+	// Failing to count a request shouldn't fail the request.
+	if err := s.keys.RecordUse(ctx, key.ID, s.now()); err != nil {
+		s.logger.WarnContext(ctx, "Failed to record API key use", "id", key.ID, "error", err)
+	}
+}
+
+// Identify is Authenticate without recording the use, for telling clients
+// apart before the request is authorized.
+func (s *Service) Identify(ctx context.Context, secret string) (domain.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "apikey.Identify")
+	defer span.End()
+
+	return s.identify(ctx, secret)
+}
+
+func (s *Service) identify(ctx context.Context, secret string) (domain.APIKey, error) {
+	id, ok := parseID(secret)
+	if !ok {
+		if s.configHash != nil && subtle.ConstantTimeCompare(hash(secret), s.configHash) == 1 {
+			return domain.APIKey{ID: configKeyID, Name: configKeyID, Scopes: []domain.APIKeyScope{domain.ScopeAdmin}}, nil
+		}
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+
+	record, err := s.keys.Get(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to load API key", "id", id, "error", err)
+		return domain.APIKey{}, domain.ErrInternalServerError
+	}
+	want, err := hex.DecodeString(record.SecretHash)
+	if err != nil || subtle.ConstantTimeCompare(hash(secret), want) != 1 {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	key := toDomain(record)
+	if !key.Active(s.now()) {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	return key, nil
+}
+
+func (s *Service) get(ctx context.Context, id string) (domain.APIKey, error) {
+	record, err := s.keys.Get(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.APIKey{}, domain.ErrNotFound
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Failed to load API key", "id", id, "error", err)
+		return domain.APIKey{}, domain.ErrInternalServerError
+	}
+	return toDomain(record), nil
+}
+
+// newKey generates the ID and the secret of a key.
+func newKey() (id, secret string, err error) {
+	buf := make([]byte, 6+32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	id = hex.EncodeToString(buf[:6])
+	return id, keyPrefix + id + "." + base64.RawURLEncoding.EncodeToString(buf[6:]), nil
+}
+
+// parseID returns the ID of an issued key.
+func parseID(secret string) (string, bool) {
+	rest, ok := strings.CutPrefix(secret, keyPrefix)
+	if !ok {
+		return "", false
+	}
+	id, _, ok := strings.Cut(rest, ".")
+	return id, ok && id != ""
+}
+
+// hash keeps secrets out of storage. Keys are long and random, so a fast
+// hash is enough.
+func hash(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+func toDomain(record storage.APIKey) domain.APIKey {
+	key := domain.APIKey{
+		ID:         record.ID,
+		Name:       record.Name,
+		CreatedAt:  record.CreatedAt,
+		ExpiresAt:  record.ExpiresAt,
+		RevokedAt:  record.RevokedAt,
+		LastUsedAt: record.LastUsedAt,
+		UseCount:   record.UseCount,
+	}
+	for _, scope := range record.Scopes {
+		key.Scopes = append(key.Scopes, domain.APIKeyScope(scope))
+	}
+	return key
+}
@@ -0,0 +1,213 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/storage/memory"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestService(configKey string) (*Service, *time.Time) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewService(memory.New().APIKeys(), configKey, logging.Discard())
+	svc.now = func() time.Time { return now }
+	return svc, &now
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	svc, now := newTestService("")
+
+	issued, err := svc.Create(ctx, "partner-acme", []domain.APIKeyScope{domain.ScopePartner}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(issued.Secret, keyPrefix+issued.ID+".") {
+		t.Errorf("secret %q does not carry the ID %s", issued.Secret, issued.ID)
+	}
+	record, _ := svc.keys.Get(ctx, issued.ID)
+	if strings.Contains(record.SecretHash, issued.Secret) || record.SecretHash == "" {
+		t.Errorf("stored hash = %q", record.SecretHash)
+	}
+
+	if _, err := svc.Identify(ctx, issued.Secret); err != nil {
+		t.Fatalf("Identify: %v", err)
+	}
+	key, err := svc.Authenticate(ctx, issued.Secret)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if key.ID != issued.ID || key.Name != "partner-acme" {
+		t.Errorf("key = %+v", key)
+	}
+	keys, _ := svc.List(ctx)
+	if len(keys) != 1 || keys[0].UseCount != 1 || keys[0].LastUsedAt == nil || !keys[0].LastUsedAt.Equal(*now) {
+		t.Errorf("usage not recorded: %+v", keys)
+	}
+
+	tests := []struct {
+		name   string
+		secret string
+	}{
+		{"wrong secret", issued.Secret[:len(issued.Secret)-1] + "x"},
+		{"unknown id", keyPrefix + "000000000000.secret"},
+		{"no id", keyPrefix + ".secret"},
+		{"unprefixed", "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.Authenticate(ctx, tt.secret); !errors.Is(err, domain.ErrUnauthorized) {
+				t.Errorf("Authenticate = %v, want ErrUnauthorized", err)
+			}
+		})
+	}
+
+	*now = now.Add(time.Hour)
+	if _, err := svc.Authenticate(ctx, issued.Secret); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("expired key: got %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestCreateInvalid(t *testing.T) {
+	svc, _ := newTestService("")
+	tests := []struct {
+		name   string
+		key    string
+		scopes []domain.APIKeyScope
+		ttl    time.Duration
+	}{
+		{"no name", " ", []domain.APIKeyScope{domain.ScopeAdmin}, 0},
+		{"no scopes", "ops", nil, 0},
+		{"unknown scope", "ops", []domain.APIKeyScope{"root"}, 0},
+		{"negative ttl", "ops", []domain.APIKeyScope{domain.ScopeAdmin}, -time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := svc.Create(context.Background(), tt.key, tt.scopes, tt.ttl); !errors.Is(err, domain.ErrBadParamInput) {
+				t.Errorf("Create = %v, want ErrBadParamInput", err)
+			}
+		})
+	}
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	svc, now := newTestService("")
+
+	old, err := svc.Create(ctx, "ops", []domain.APIKeyScope{domain.ScopeAdmin, domain.ScopeSwagger}, 30*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(time.Hour)
+	replacement, err := svc.Rotate(ctx, old.ID, 10*time.Minute)
+	if err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	if replacement.ID == old.ID || replacement.Name != "ops" || len(replacement.Scopes) != 2 ||
+		!replacement.ExpiresAt.Equal(now.Add(30*24*time.Hour)) {
+		t.Errorf("replacement = %+v", replacement)
+	}
+
+	// Both keys work during the grace period, then only the new one.
+	for _, secret := range []string{old.Secret, replacement.Secret} {
+		if _, err := svc.Authenticate(ctx, secret); err != nil {
+			t.Errorf("during grace: %v", err)
+		}
+	}
+	*now = now.Add(10 * time.Minute)
+	if _, err := svc.Authenticate(ctx, old.Secret); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("old key after grace: got %v, want ErrUnauthorized", err)
+	}
+	if _, err := svc.Authenticate(ctx, replacement.Secret); err != nil {
+		t.Errorf("new key after grace: %v", err)
+	}
+
+	if _, err := svc.Rotate(ctx, old.ID, 0); !errors.Is(err, domain.ErrNotEligible) {
+		t.Errorf("rotating an expired key: got %v, want ErrNotEligible", err)
+	}
+	if _, err := svc.Rotate(ctx, "missing", 0); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("rotating a missing key: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestRotateKeepsSoonerExpiry(t *testing.T) {
+	ctx := context.Background()
+	svc, now := newTestService("")
+
+	old, err := svc.Create(ctx, "ops", []domain.APIKeyScope{domain.ScopeAdmin}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	*now = now.Add(50 * time.Minute)
+	if _, err := svc.Rotate(ctx, old.ID, 24*time.Hour); err != nil {
+		t.Fatalf("Rotate: %v", err)
+	}
+	record, _ := svc.keys.Get(ctx, old.ID)
+	if !record.ExpiresAt.Equal(*old.ExpiresAt) {
+		t.Errorf("old key expires at %v, want %v", record.ExpiresAt, old.ExpiresAt)
+	}
+}
+
+func TestRecordUse(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService("static-key")
+
+	issued, err := svc.Create(ctx, "ops", []domain.APIKeyScope{domain.ScopeAdmin}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := svc.Identify(ctx, issued.Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.RecordUse(ctx, key)
+	record, _ := svc.keys.Get(ctx, issued.ID)
+	if record.UseCount != 1 {
+		t.Errorf("UseCount = %d, want 1", record.UseCount)
+	}
+
+	config, err := svc.Identify(ctx, "static-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The config key isn't stored, so there is nothing to record.
+	svc.RecordUse(ctx, config)
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestService("")
+
+	issued, err := svc.Create(ctx, "ops", []domain.APIKeyScope{domain.ScopeAdmin}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Revoke(ctx, issued.ID); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, err := svc.Authenticate(ctx, issued.Secret); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("revoked key: got %v, want ErrUnauthorized", err)
+	}
+	if err := svc.Revoke(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Revoke missing: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestConfigKey(t *testing.T) {
+	svc, _ := newTestService("static-key")
+
+	key, err := svc.Authenticate(context.Background(), "static-key")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if key.ID != configKeyID || !key.Permits(domain.ScopeSwagger, "GET") {
+		t.Errorf("key = %+v", key)
+	}
+	if _, err := svc.Authenticate(context.Background(), "static-kez"); !errors.Is(err, domain.ErrUnauthorized) {
+		t.Errorf("wrong key: got %v, want ErrUnauthorized", err)
+	}
+}
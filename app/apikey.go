@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/apikey"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/config"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const apiKeyUsage = `usage: apikey <command> [flags]
+
+commands:
+  create -name NAME -scopes SCOPE[,SCOPE] [-ttl DURATION]
+  list
+  rotate [-grace DURATION] ID
+  revoke ID
+
+scopes: swagger, admin, partner (read-only)`
+
+// runAPIKeyCommand manages the API keys in the configured storage. The
+// server reads them from there, so the storage must be persistent.
+func runAPIKeyCommand(cfg config.Config, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(apiKeyUsage)
+	}
+	if scheme, _, _ := strings.Cut(cfg.Storage.DSN, ":"); scheme != "sqlite" {
+		return fmt.Errorf("API keys need persistent storage, set storage.dsn to sqlite:<path> (now %q)", cfg.Storage.DSN)
+	}
+
+	ctx := context.Background()
+	logger := logging.Discard()
+	store, err := openStore(ctx, cfg.Storage.DSN, logger)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	svc := apikey.NewService(store.APIKeys(), "", logger)
+
+	command, args := args[0], args[1:]
+	flags := flag.NewFlagSet("apikey "+command, flag.ContinueOnError)
+	flags.SetOutput(out)
+	switch command {
+	case "create":
+		name := flags.String("name", "", "who the key is for, e.g. partner-acme")
+		scopes := flags.String("scopes", "", "comma-separated scopes: swagger, admin, partner")
+		ttl := flags.Duration("ttl", 0, "how long the key is valid; 0 never expires")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		var list []domain.APIKeyScope
+		for _, s := range strings.Split(*scopes, ",") {
+			scope, err := domain.ParseAPIKeyScope(strings.TrimSpace(s))
+			if err != nil {
+				return err
+			}
+			list = append(list, scope)
+		}
+		if strings.TrimSpace(*name) == "" {
+			return errors.New("-name is required")
+		}
+		issued, err := svc.Create(ctx, *name, list, *ttl)
+		if err != nil {
+			return err
+		}
+		printIssued(out, issued)
+		return nil
+
+	case "list":
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		keys, err := svc.List(ctx)
+		if err != nil {
+			return err
+		}
+		printKeys(out, keys, time.Now())
+		return nil
+
+	case "rotate":
+		grace := flags.Duration("grace", 24*time.Hour, "how long the old key keeps working")
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: apikey rotate [-grace DURATION] ID")
+		}
+		issued, err := svc.Rotate(ctx, flags.Arg(0), *grace)
+		if errors.Is(err, domain.ErrNotEligible) {
+			return fmt.Errorf("key %s is revoked or expired, create a new one instead", flags.Arg(0))
+		}
+		if err != nil {
+			return keyError(flags.Arg(0), err)
+		}
+		fmt.Fprintf(out, "Key %s stops working within %s.\n", flags.Arg(0), *grace)
+		printIssued(out, issued)
+		return nil
+
+	case "revoke":
+		if err := flags.Parse(args); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return errors.New("usage: apikey revoke ID")
+		}
+		if err := svc.Revoke(ctx, flags.Arg(0)); err != nil {
+			return keyError(flags.Arg(0), err)
+		}
+		fmt.Fprintf(out, "Key %s revoked.\n", flags.Arg(0))
+		return nil
+
+	default:
+		return fmt.Errorf("unknown command %q\n%s", command, apiKeyUsage)
+	}
+}
+
+func keyError(id string, err error) error {
+	if errors.Is(err, domain.ErrNotFound) {
+		return fmt.Errorf("no key with ID %s", id)
+	}
+	return err
+}
+
+func printIssued(out io.Writer, key domain.IssuedAPIKey) {
+	expires := "never"
+	if key.ExpiresAt != nil {
+		expires = key.ExpiresAt.Format(time.RFC3339)
+	}
+	fmt.Fprintf(out, "ID:      %s\nName:    %s\nScopes:  %s\nExpires: %s\n\n%s\n\nThe key is shown only once; store it now.\n",
+		key.ID, key.Name, joinScopes(key.Scopes), expires, key.Secret)
+}
+
+func printKeys(out io.Writer, keys []domain.APIKey, now time.Time) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSCOPES\tSTATUS\tCREATED\tEXPIRES\tLAST USED\tUSES")
+	for _, key := range keys {
+		status := "active"
+		switch {
+		case key.RevokedAt != nil:
+			status = "revoked"
+		case !key.Active(now):
+			status = "expired"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n", key.ID, key.Name, joinScopes(key.Scopes), status,
+			key.CreatedAt.Format(time.RFC3339), formatTime(key.ExpiresAt), formatTime(key.LastUsedAt), key.UseCount)
+	}
+	w.Flush()
+}
+
+func joinScopes(scopes []domain.APIKeyScope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ",")
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -8,104 +8,459 @@
 package main
 
 import (
-	"github.com/joho/godotenv"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
 	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/addon"
+	"github.com/llchhh/spektr-account-api/apikey"
 	"github.com/llchhh/spektr-account-api/auth"
+	"github.com/llchhh/spektr-account-api/autopay"
 	_ "github.com/llchhh/spektr-account-api/docs" // Import generated docs
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/health"
+	"github.com/llchhh/spektr-account-api/internal/certs"
+	"github.com/llchhh/spektr-account-api/internal/config"
+	"github.com/llchhh/spektr-account-api/internal/i18n"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
+	"github.com/llchhh/spektr-account-api/internal/ratelimit"
 	"github.com/llchhh/spektr-account-api/internal/repository/api"
+	"github.com/llchhh/spektr-account-api/internal/repository/provider"
 	"github.com/llchhh/spektr-account-api/internal/rest"
 	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/storage"
+	"github.com/llchhh/spektr-account-api/internal/storage/memory"
+	"github.com/llchhh/spektr-account-api/internal/storage/sqlite"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
 	"github.com/llchhh/spektr-account-api/notification"
+	"github.com/llchhh/spektr-account-api/payment"
 	"github.com/llchhh/spektr-account-api/profile"
 	"github.com/llchhh/spektr-account-api/repair"
+	"github.com/llchhh/spektr-account-api/suspension"
+	"github.com/llchhh/spektr-account-api/tariff"
+	"github.com/llchhh/spektr-account-api/usage"
 	"github.com/swaggo/http-swagger" // Swagger UI handler
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
 	"os"
-	"strconv"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
-const (
-	defaultTimeout = 30
-	defaultAddress = ":9090"
-)
+// watchInterval is how often suspension start and end notifications are checked.
+const watchInterval = 15 * time.Minute
 
-func init() {
-	err := godotenv.Load()
+func main() {
+	configFile := flag.String("config", "", "YAML or TOML configuration file (default $"+config.FileEnv+")")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration with secrets masked and exit")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [flags] [apikey <command>]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	cfg, err := config.Load(*configFile)
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if flag.Arg(0) == "apikey" {
+		if err := runAPIKeyCommand(cfg, flag.Args()[1:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if err := run(cfg); err != nil {
+		log.Fatal(err)
 	}
 }
 
-func main() {
+// run serves the API until SIGINT or SIGTERM, then shuts down in order:
+// readiness goes unready, the server stops taking connections and finishes
+// in-flight requests, the background workers stop, and finally storage is
+// closed and pending spans are flushed.
+func run(cfg config.Config) error {
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return err
+	}
+	// Whatever still uses the log package goes through the same handler.
+	slog.SetDefault(logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter, os.Stdout)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("Failed to flush traces", "error", err)
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// prepare echo
 	e := echo.New()
-	e.Use(middleware.CORS)
+	e.HideBanner = true
+	e.HTTPErrorHandler = rest.ErrorHandler
+	e.IPExtractor = ipExtractor(cfg.Server.TrustedProxies)
+	e.Use(middleware.RequestID())
+	e.Use(middleware.Tracing())
+	e.Use(middleware.Metrics())
+	e.Use(middleware.AccessLog(logger))
+	if cfg.TLS.HSTSMaxAge > 0 {
+		e.Use(middleware.HSTS(cfg.TLS.HSTSMaxAge, cfg.TLS.HSTSIncludeSubdomains))
+	}
+	e.Use(corsMiddleware(cfg.CORS))
 
-	timeoutStr := os.Getenv("CONTEXT_TIMEOUT")
-	timeout, err := strconv.Atoi(timeoutStr)
+	bundle := i18n.Default()
+	if dir := cfg.I18NDir; dir != "" {
+		loaded, err := i18n.Load(os.DirFS(dir), ".")
+		if err != nil {
+			return fmt.Errorf("failed to load message catalogues from %s: %w", dir, err)
+		}
+		bundle = loaded
+	}
+	e.Use(middleware.Language(bundle))
+
+	secret, err := sessionSecret(cfg.Security.SessionSecret, logger)
 	if err != nil {
-		log.Println("failed to parse timeout, using default timeout")
-		timeout = defaultTimeout
+		return err
+	}
+	tokens := auth.NewTokenCodec(secret)
+	e.Use(middleware.Principal(tokens))
+
+	e.Use(middleware.SetRequestContextWithTimeout(cfg.Server.RequestTimeout))
+
+	// Bound how long a client may hold a connection.
+	for _, srv := range []*http.Server{e.Server, e.TLSServer} {
+		srv.ReadHeaderTimeout = cfg.Server.ReadHeaderTimeout
+		srv.ReadTimeout = cfg.Server.ReadTimeout
+		srv.WriteTimeout = cfg.Server.WriteTimeout
+		srv.IdleTimeout = cfg.Server.IdleTimeout
+	}
+
+	store, err := openStore(ctx, cfg.Storage.DSN, logger)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	keySvc := apikey.NewService(store.APIKeys(), cfg.Security.APIKey, logger)
+	if cfg.RateLimit.Enabled {
+		// Clients are told apart by API key or session, so this runs after
+		// the Principal middleware.
+		e.Use(rateLimitMiddleware(cfg.RateLimit, keySvc, logger))
+	}
+
+	// Background workers run until shutdown; run waits for them before
+	// closing storage.
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	var workers sync.WaitGroup
+	startWorker := func(run func(context.Context)) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			run(workerCtx)
+		}()
 	}
-	timeoutContext := time.Duration(timeout) * time.Second
-	e.Use(middleware.SetRequestContextWithTimeout(timeoutContext))
 
 	// Prepare Repositories
-	authRepo := api.NewAuthRepository(os.Getenv("BASE_URL"))
-	authSvc := auth.NewService(authRepo)
+	baseURL := cfg.Upstream.BaseURL
+	client := api.NewHTTPClient(cfg.Upstream.Timeout, cfg.Upstream.Retry.MaxAttempts, cfg.Upstream.Retry.Backoff)
+
+	authRepo := api.NewAuthRepository(client, baseURL, logger)
+	authSvc := auth.NewService(authRepo, tokens, logger)
 	rest.NewAuthHandler(e, authSvc)
 
-	profileRepo := api.NewProfileRepository(os.Getenv("BASE_URL"))
-	profileSvc := profile.NewService(profileRepo)
-	rest.NewProfileHandler(e, profileSvc)
+	profileRepo := api.NewProfileRepository(client, baseURL, logger)
+	profileCache := profile.NewCache(cfg.Cache.ProfileTTL)
 
-	notiRepo := api.NewNotificationRepository(os.Getenv("BASE_URL"))
-	notiSvc := notification.NewService(notiRepo)
+	notiRepo := api.NewNotificationRepository(client, baseURL, logger)
+	notiSvc := notification.NewService(notiRepo, profileRepo, notification.NewInbox(store.Inbox()), logger)
 	rest.NewNotificationHandler(e, notiSvc)
 
-	repairRepo := api.NewRepairRepository(os.Getenv("BASE_URL"))
-	repairSvc := repair.NewService(repairRepo)
+	suspensionRepo := api.NewSuspensionRepository(client, baseURL, logger)
+	suspensionWatcher := suspension.NewWatcher(store.WatchedSuspensions(), notiSvc, watchInterval, logger)
+	suspensionSvc := suspension.NewService(suspensionRepo, profileRepo, profileCache, suspensionWatcher, logger)
+	rest.NewSuspensionHandler(e, suspensionSvc)
+	startWorker(suspensionWatcher.Run)
+
+	tariffRepo := api.NewTariffRepository(client, baseURL, logger)
+	profileSvc := profile.NewService(profileRepo, suspensionSvc, tariffRepo, profileCache, logger)
+	rest.NewProfileHandler(e, profileSvc)
+
+	usageRepo := api.NewUsageRepository(client, baseURL, logger)
+	usageSvc := usage.NewService(usageRepo, profileRepo, logger)
+	rest.NewUsageHandler(e, usageSvc)
+
+	repairRepo := api.NewRepairRepository(client, baseURL, logger)
+	repairSvc := repair.NewService(repairRepo, logger)
 	rest.NewRepairHandler(e, *repairSvc)
 
-	// Получаем API ключ из переменной окружения
-	apiKey := os.Getenv("API_KEY")
-	if apiKey == "" {
-		log.Fatal("API_KEY not set in environment variables")
+	paymentRepo := api.NewPaymentRepository(client, baseURL, logger)
+	paymentSvc := payment.NewService(paymentRepo, logger)
+	var topUpSvc *payment.TopUpService
+	if cfg.Payment.Provider == "" {
+		logger.Warn("No payment provider configured, top-ups and autopay are disabled")
+		rest.NewPaymentHandler(e, paymentSvc, nil)
+	} else {
+		paymentProvider, err := newPaymentProvider(cfg.Payment, logger)
+		if err != nil {
+			return err
+		}
+		topUpSvc = payment.NewTopUpService(paymentProvider, profileRepo, paymentRepo, store.PaymentIntents(), profileCache, logger)
+		rest.NewPaymentHandler(e, paymentSvc, topUpSvc)
 	}
 
-	// Start Server
-	address := os.Getenv("SERVER_ADDRESS")
-	if address == "" {
-		address = defaultAddress
+	tariffSvc := tariff.NewService(tariffRepo, profileRepo, profileCache, logger)
+	rest.NewTariffHandler(e, tariffSvc)
+
+	addonRepo := api.NewAddonRepository(client, baseURL, logger)
+	addonSvc := addon.NewService(addonRepo, profileRepo, profileCache, logger)
+	rest.NewAddonHandler(e, addonSvc)
+
+	// Validation makes sure autopay has a payment provider.
+	if cfg.Features.Autopay {
+		autopayRules := autopay.SealTokens(store.AutopayRules(), cfg.Security.EncryptionKey)
+		autopaySvc := autopay.NewService(profileRepo, topUpSvc, autopayRules, logger)
+		rest.NewAutopayHandler(e, autopaySvc)
+
+		scheduler := autopay.NewScheduler(autopayRules, profileRepo, topUpSvc, notiSvc, cfg.Autopay.Interval, logger)
+		startWorker(scheduler.Run)
 	}
 
-	// Указываем путь до сертификатов
-	certFile := "/etc/letsencrypt/live/www.969975-cv27771.tmweb.ru/fullchain.pem"
-	keyFile := "/etc/letsencrypt/live/www.969975-cv27771.tmweb.ru/privkey.pem"
+	if cfg.Features.Metrics {
+		e.GET("/metrics", echo.WrapHandler(metrics.Handler()))
+	}
 
-	// Проверка наличия файлов сертификата и ключа
-	_, certErr := os.Stat(certFile)
-	_, keyErr := os.Stat(keyFile)
+	healthRepo := api.NewHealthRepository(client, baseURL, logger)
+	healthSvc := health.NewService([]health.Check{
+		{Name: "billing", Run: healthRepo.Ping},
+		{Name: "storage", Run: store.Ping},
+		{Name: "config", Run: func(context.Context) error { return cfg.Validate() }},
+	}, logger)
+	rest.NewHealthHandler(e, healthSvc, keySvc)
 
-	// Если сертификат или ключ отсутствуют, запускаем сервер без SSL
-	if os.IsNotExist(certErr) || os.IsNotExist(keyErr) {
-		log.Println("SSL certificates not found, starting HTTP server instead of HTTPS.")
-		e.GET("/swagger/*", echo.WrapHandler(httpSwagger.WrapHandler))
+	if cfg.Features.Swagger {
+		e.GET("/swagger/*", echo.WrapHandler(httpSwagger.WrapHandler), middleware.APIKey(keySvc, domain.ScopeSwagger))
+	}
 
-		swaggerGroup := e.Group("/swagger")
-		swaggerGroup.Use(middleware.APIKey(apiKey)) // Используем middleware для проверки API ключа
+	// Start Server
+	address := cfg.Server.Address
+	tlsConfig, redirect, err := setupTLS(cfg, logger, startWorker)
+	if err != nil {
+		return err
+	}
 
-		swaggerGroup.GET("/*", echo.WrapHandler(httpSwagger.WrapHandler))
-		log.Fatal(e.Start(address)) // Start HTTP server without SSL
+	serverErr := make(chan error, 2)
+	if tlsConfig != nil {
+		logger.Info("Starting HTTPS server", "address", address, "tls_mode", cfg.TLS.Mode)
+		e.TLSServer.Addr = address
+		e.TLSServer.TLSConfig = tlsConfig
+		go func() {
+			serverErr <- e.StartServer(e.TLSServer)
+		}()
 	} else {
-		log.Printf("SSL certificates found, starting HTTPS server on %s...\n", address)
-		e.GET("/swagger/*", echo.WrapHandler(httpSwagger.WrapHandler))
+		logger.Warn("TLS disabled, starting HTTP server", "address", address)
+		go func() {
+			serverErr <- e.Start(address)
+		}()
+	}
+
+	var redirectServer *http.Server
+	if cfg.TLS.RedirectAddress != "" {
+		redirectServer = &http.Server{
+			Addr:              cfg.TLS.RedirectAddress,
+			Handler:           redirect,
+			ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+			ReadTimeout:       cfg.Server.ReadTimeout,
+			WriteTimeout:      cfg.Server.WriteTimeout,
+			IdleTimeout:       cfg.Server.IdleTimeout,
+		}
+		logger.Info("Redirecting HTTP to HTTPS", "address", cfg.TLS.RedirectAddress)
+		go func() {
+			serverErr <- redirectServer.ListenAndServe()
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		logger.Info("Shutdown requested")
+	case err := <-serverErr:
+		// A server could not start, e.g. the address is taken.
+		return fmt.Errorf("server failed: %w", err)
+	}
+	stop()
+
+	// Report unready first and give the orchestrator time to notice and
+	// stop routing traffic here, then stop the server.
+	healthSvc.Drain()
+	logger.Info("Draining", "delay", cfg.Server.DrainDelay)
+	time.Sleep(cfg.Server.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		logger.Error("In-flight requests did not finish in time", "error", err)
+	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(shutdownCtx)
+	}
 
-		swaggerGroup := e.Group("/swagger")
-		swaggerGroup.Use(middleware.APIKey(apiKey)) // Используем middleware для проверки API ключа
+	stopWorkers()
+	workers.Wait()
+	logger.Info("Server stopped")
+	return nil
+}
+
+// setupTLS prepares the certificates for the configured TLS mode and the
+// handler of the plain HTTP listener. It returns a nil config when TLS is
+// disabled, and fails rather than fall back to plain HTTP when TLS is
+// configured but can't work.
+func setupTLS(cfg config.Config, logger *slog.Logger, startWorker func(func(context.Context))) (*tls.Config, http.Handler, error) {
+	httpsPort := ""
+	if _, port, err := net.SplitHostPort(cfg.Server.Address); err == nil {
+		httpsPort = port
+	}
+	redirect := certs.RedirectHandler(httpsPort)
+
+	switch cfg.TLS.Mode {
+	case config.TLSStatic:
+		reloader, err := certs.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, logger)
+		if err != nil {
+			return nil, nil, err
+		}
+		startWorker(func(ctx context.Context) {
+			reloader.Watch(ctx, cfg.TLS.ReloadInterval)
+		})
+		return &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}, redirect, nil
+	case config.TLSACME:
+		acme := cfg.TLS.ACME
+		manager, err := certs.NewACMEManager(acme.Domains, acme.Email, acme.CacheDir, acme.DirectoryURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		tlsConfig := manager.TLSConfig()
+		tlsConfig.MinVersion = tls.VersionTLS12
+		return tlsConfig, manager.HTTPHandler(redirect), nil
+	default:
+		return nil, nil, nil
+	}
+}
+
+// corsMiddleware applies the configured CORS policy and its group overrides.
+func corsMiddleware(cfg config.CORS) echo.MiddlewareFunc {
+	policy := func(p config.CORSPolicy) middleware.CORSPolicy {
+		return middleware.CORSPolicy{
+			AllowOrigins:     p.AllowOrigins,
+			AllowCredentials: p.AllowCredentials,
+			AllowHeaders:     p.AllowHeaders,
+			ExposeHeaders:    p.ExposeHeaders,
+			MaxAge:           p.MaxAge,
+		}
+	}
+	groups := make(map[string]middleware.CORSPolicy, len(cfg.Groups))
+	for _, group := range cfg.Groups {
+		groups[group.Prefix] = policy(group.CORSPolicy)
+	}
+	return middleware.CORS(policy(cfg.CORSPolicy), groups)
+}
+
+// rateLimitMiddleware applies the configured quotas. Probes and metrics
+// scrapes come from the infrastructure and are never limited.
+func rateLimitMiddleware(cfg config.RateLimit, keys middleware.APIKeyIdentifier, logger *slog.Logger) echo.MiddlewareFunc {
+	rules := []middleware.RateLimitRule{
+		{Prefix: "/healthz"},
+		{Prefix: "/readyz"},
+		{Prefix: "/metrics"},
+	}
+	for _, group := range cfg.Groups {
+		rules = append(rules, middleware.RateLimitRule{
+			Prefix:  group.Prefix,
+			Methods: group.Methods,
+			Limit:   ratelimit.Limit{Rate: group.Rate, Burst: group.Burst},
+		})
+	}
+	limit := ratelimit.Limit{Rate: cfg.Rate, Burst: cfg.Burst}
+	return middleware.RateLimit(ratelimit.NewMemoryStore(), limit, rules, keys, logger)
+}
+
+// ipExtractor takes the client IP from X-Forwarded-For when the request
+// comes through a private network or one of trusted, and from the
+// connection otherwise, so clients can't pick their IP.
+func ipExtractor(trusted []string) echo.IPExtractor {
+	var options []echo.TrustOption
+	for _, cidr := range trusted {
+		// Validated with the configuration.
+		_, network, _ := net.ParseCIDR(cidr)
+		options = append(options, echo.TrustIPRange(network))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
+
+// sessionSecret returns the key bearer tokens are signed with. Without a
+// configured secret a random key is used and tokens don't survive a restart.
+func sessionSecret(configured string, logger *slog.Logger) ([]byte, error) {
+	if configured != "" {
+		return []byte(configured), nil
+	}
+	logger.Warn("SESSION_SECRET not set, signing tokens with a random key")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate session secret: %w", err)
+	}
+	return secret, nil
+}
+
+// newPaymentProvider builds the configured payment provider. The fake one
+// credits payments nobody made, so it is refused unless explicitly allowed.
+func newPaymentProvider(cfg config.Payment, logger *slog.Logger) (payment.Provider, error) {
+	switch cfg.Provider {
+	case "fake":
+		if !cfg.AllowFake {
+			return nil, errors.New("the fake payment provider is for development only; set PAYMENT_ALLOW_FAKE to use it")
+		}
+		logger.Warn("Using the fake payment provider, top-ups are credited without taking money")
+		return provider.NewFakeProvider(cfg.ConfirmURL, cfg.WebhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unknown payment provider %q", cfg.Provider)
+	}
+}
 
-		swaggerGroup.GET("/*", echo.WrapHandler(httpSwagger.WrapHandler))
-		log.Fatal(e.StartTLS(address, certFile, keyFile)) // Start HTTPS server
+// openStore opens the local state storage selected by dsn: "memory" (the
+// default, lost on restart) or "sqlite:<path>".
+func openStore(ctx context.Context, dsn string, logger *slog.Logger) (storage.Store, error) {
+	scheme, path, _ := strings.Cut(dsn, ":")
+	switch scheme {
+	case "", "memory":
+		logger.Warn("Using in-memory storage, local state will not survive a restart")
+		return memory.New(), nil
+	case "sqlite":
+		return sqlite.Open(ctx, path)
+	default:
+		return nil, fmt.Errorf("unknown storage %q", scheme)
 	}
 }
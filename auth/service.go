@@ -4,21 +4,35 @@ import (
 	"context"
 	"errors"
 	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
 	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
 )
 
 type AuthRepository interface {
 	Login(ctx context.Context, user domain.Auth) (string, error)
 	RequestPasswordResetToken(ctx context.Context, login string) error
 	UpdatePassword(ctx context.Context, token, password string) error
+	Contracts(ctx context.Context, token string) ([]domain.Contract, error)
+}
+
+// TokenIssuer turns a principal into the bearer token handed to the client.
+type TokenIssuer interface {
+	Issue(p domain.Principal) string
 }
 
 type Service struct {
 	authRepo AuthRepository
+	tokens   TokenIssuer
+	logger   *slog.Logger
 }
 
 // RequestPasswordResetToken requests a password reset token for the user
 func (s *Service) RequestPasswordResetToken(ctx context.Context, login string) error {
+	ctx, span := tracing.Start(ctx, "auth.RequestPasswordResetToken")
+	defer span.End()
+
 	// You might want to validate user input first, e.g., check if email or username exists
 	if middleware.ContainsForbiddenChars(login) {
 		return domain.ErrInvalidCredentials
@@ -40,6 +54,9 @@ func (s *Service) RequestPasswordResetToken(ctx context.Context, login string) e
 
 // UpdatePassword updates the user's password using a reset token
 func (s *Service) UpdatePassword(ctx context.Context, token, password string) error {
+	ctx, span := tracing.Start(ctx, "auth.UpdatePassword")
+	defer span.End()
+
 	// Validate the token and password here (e.g., check the token's expiration)
 	if middleware.ContainsForbiddenChars(password) {
 		return domain.ErrInvalidCredentials
@@ -61,33 +78,114 @@ func (s *Service) UpdatePassword(ctx context.Context, token, password string) er
 	return nil
 }
 
-func NewService(a AuthRepository) *Service {
+func NewService(a AuthRepository, t TokenIssuer, logger *slog.Logger) *Service {
 	return &Service{
 		authRepo: a,
+		tokens:   t,
+		logger:   logger,
 	}
 }
 
-func (s *Service) Login(ctx context.Context, user domain.Auth) (string, error) {
+// Login signs the user in with a login or phone and selects the contract the
+// billing marks as current, or the first one linked to the login.
+func (s *Service) Login(ctx context.Context, user domain.Auth) (domain.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.Login")
+	defer span.End()
+
 	// Marshal the user data into a JSON object for arg1
 	if middleware.ContainsForbiddenChars(user.Login) {
-		return "", domain.ErrInvalidCredentials
+		metrics.LoginFailed("invalid_credentials")
+		return domain.Session{}, domain.ErrInvalidCredentials
 	}
 
 	if middleware.ContainsForbiddenChars(user.Password) {
-		return "", domain.ErrInvalidCredentials
+		metrics.LoginFailed("invalid_credentials")
+		return domain.Session{}, domain.ErrInvalidCredentials
 	}
-	token, err := s.authRepo.Login(ctx, user)
+	suid, err := s.authRepo.Login(ctx, user)
 	if err != nil {
 		// Map repository errors to domain-specific errors
 		if errors.Is(err, domain.ErrInvalidCredentials) {
-			return "", domain.ErrInvalidCredentials
+			metrics.LoginFailed("invalid_credentials")
+			return domain.Session{}, domain.ErrInvalidCredentials
 		}
 		if errors.Is(err, domain.ErrAccountLocked) {
-			return "", domain.ErrAccountLocked
+			metrics.LoginFailed("account_locked")
+			return domain.Session{}, domain.ErrAccountLocked
 		}
 		// Handle other errors appropriately
-		return "", domain.ErrInternalServerError
+		metrics.LoginFailed("error")
+		return domain.Session{}, domain.ErrInternalServerError
+	}
+
+	contracts, err := s.authRepo.Contracts(ctx, suid)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching contracts after login", "error", err)
+		metrics.LoginFailed("error")
+		return domain.Session{}, domain.ErrInternalServerError
+	}
+	contractID := ""
+	for _, contract := range contracts {
+		if contract.Current {
+			contractID = contract.ID
+			break
+		}
+	}
+	if contractID == "" && len(contracts) > 0 {
+		contractID = contracts[0].ID
+	}
+	return s.session(domain.Principal{Token: suid, ContractID: contractID}, contracts), nil
+}
+
+// Contracts lists the contracts linked to the login of the principal.
+func (s *Service) Contracts(ctx context.Context, principal domain.Principal) ([]domain.Contract, error) {
+	ctx, span := tracing.Start(ctx, "auth.Contracts")
+	defer span.End()
+
+	if principal.Token == "" || middleware.ContainsForbiddenChars(principal.Token) {
+		return nil, domain.ErrInvalidToken
 	}
+	contracts, err := s.authRepo.Contracts(ctx, principal.Token)
+	if err != nil {
+		return nil, err
+	}
+	markCurrent(contracts, principal.ContractID)
+	return contracts, nil
+}
+
+// SwitchContract issues a token for the same session scoped to another
+// contract linked to the login.
+func (s *Service) SwitchContract(ctx context.Context, principal domain.Principal, contractID string) (domain.Session, error) {
+	ctx, span := tracing.Start(ctx, "auth.SwitchContract")
+	defer span.End()
 
-	return token, nil
+	if contractID == "" || middleware.ContainsForbiddenChars(contractID) {
+		return domain.Session{}, domain.ErrBadParamInput
+	}
+	contracts, err := s.Contracts(ctx, principal)
+	if err != nil {
+		return domain.Session{}, err
+	}
+	for _, contract := range contracts {
+		if contract.ID == contractID {
+			s.logger.InfoContext(ctx, "Switching session to contract", "contract_id", contractID)
+			return s.session(domain.Principal{Token: principal.Token, ContractID: contractID}, contracts), nil
+		}
+	}
+	return domain.Session{}, domain.ErrNotFound
+}
+
+func (s *Service) session(principal domain.Principal, contracts []domain.Contract) domain.Session {
+	markCurrent(contracts, principal.ContractID)
+	return domain.Session{
+		Token:      s.tokens.Issue(principal),
+		ContractID: principal.ContractID,
+		Contracts:  contracts,
+	}
+}
+
+func markCurrent(contracts []domain.Contract, contractID string) {
+	for i := range contracts {
+		contracts[i].Current = contracts[i].ID == contractID
+	}
 }
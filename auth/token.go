@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/llchhh/spektr-account-api/domain"
+	"strings"
+)
+
+// TokenCodec issues the bearer tokens handed to clients. A token carries
+// the billing session and the selected contract, signed so the contract
+// can't be swapped without going through SwitchContract.
+type TokenCodec struct {
+	secret []byte
+}
+
+// NewTokenCodec creates a TokenCodec signing with secret.
+func NewTokenCodec(secret []byte) *TokenCodec {
+	return &TokenCodec{secret: secret}
+}
+
+type tokenPayload struct {
+	Session    string `json:"s"`
+	ContractID string `json:"c,omitempty"`
+}
+
+// Issue encodes p into a token.
+func (t *TokenCodec) Issue(p domain.Principal) string {
+	payload, _ := json.Marshal(tokenPayload{Session: p.Token, ContractID: p.ContractID})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(t.sign(encoded))
+}
+
+// Parse verifies the token and returns the principal it carries.
+func (t *TokenCodec) Parse(token string) (domain.Principal, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return domain.Principal{}, domain.ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, t.sign(encoded)) {
+		return domain.Principal{}, domain.ErrInvalidToken
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return domain.Principal{}, domain.ErrInvalidToken
+	}
+	var payload tokenPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.Session == "" {
+		return domain.Principal{}, domain.ErrInvalidToken
+	}
+	return domain.Principal{Token: payload.Session, ContractID: payload.ContractID}, nil
+}
+
+func (t *TokenCodec) sign(encoded string) []byte {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
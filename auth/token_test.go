@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"strings"
+	"testing"
+)
+
+func TestTokenCodec(t *testing.T) {
+	codec := NewTokenCodec([]byte("secret"))
+	principal := domain.Principal{Token: "abc123", ContractID: "42"}
+
+	token := codec.Issue(principal)
+	if middleware.ContainsForbiddenChars(token) {
+		t.Fatalf("token %q contains forbidden characters", token)
+	}
+	got, err := codec.Parse(token)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got != principal {
+		t.Errorf("Parse() = %+v, want %+v", got, principal)
+	}
+
+	// Swap the contract while keeping the signature.
+	_, signature, _ := strings.Cut(token, ".")
+	forged, _, _ := strings.Cut(codec.Issue(domain.Principal{Token: "abc123", ContractID: "43"}), ".")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"Raw billing session", "abc123"},
+		{"Swapped contract", forged + "." + signature},
+		{"Other key", NewTokenCodec([]byte("other")).Issue(principal)},
+		{"Empty", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := codec.Parse(tt.token); !errors.Is(err, domain.ErrInvalidToken) {
+				t.Errorf("Parse() error = %v, want %v", err, domain.ErrInvalidToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package autopay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/notification"
+	"log/slog"
+	"time"
+)
+
+// retryBackoff is the wait before each retry of a failed charge. A charge
+// is given up for the period once every retry has failed.
+var retryBackoff = []time.Duration{time.Hour, 6 * time.Hour, 24 * time.Hour}
+
+// notificationType groups autopay messages with other payment notifications.
+const notificationType = "payment"
+
+// Scheduler periodically evaluates every active rule and charges the ones
+// that are due.
+type Scheduler struct {
+	rules       RuleStore
+	profileRepo ProfileRepository
+	charger     Charger
+	notifier    Notifier
+	interval    time.Duration
+	now         func() time.Time
+	logger      *slog.Logger
+}
+
+// NewScheduler creates a Scheduler that wakes up every interval.
+func NewScheduler(r RuleStore, p ProfileRepository, c Charger, n Notifier, interval time.Duration, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		rules:       r,
+		profileRepo: p,
+		charger:     c,
+		notifier:    n,
+		interval:    interval,
+		now:         time.Now,
+		logger:      logger,
+	}
+}
+
+// Run evaluates the rules every interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce evaluates every rule a single time.
+func (s *Scheduler) RunOnce(ctx context.Context) {
+	rules, err := s.rules.List(ctx)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Autopay: failed to list rules", "error", err)
+		return
+	}
+	for _, rule := range rules {
+		if ctx.Err() != nil {
+			return
+		}
+		if rule.Status != domain.AutopayActive || s.now().Before(rule.NextAttemptAt) {
+			continue
+		}
+		s.process(ctx, rule)
+	}
+}
+
+// process charges a single rule if it is due and records the outcome.
+func (s *Scheduler) process(ctx context.Context, rule domain.AutopayRule) {
+	if rule.Principal.Token == "" {
+		// The stored session could not be decrypted.
+		s.pause(ctx, rule, domain.ErrSessionExpired)
+		return
+	}
+	ctx = domain.ContextWithPrincipal(ctx, rule.Principal)
+	profile, err := s.profileRepo.Profile(ctx, rule.Principal.Token)
+	if err != nil {
+		if errors.Is(err, domain.ErrSessionExpired) {
+			s.pause(ctx, rule, err)
+			return
+		}
+		s.logger.ErrorContext(ctx, "Autopay: failed to fetch profile", "contract_id", rule.ContractID, "error", err)
+		return
+	}
+
+	amount, period, due := s.due(ctx, rule, profile)
+	if !due || period == rule.LastPeriod {
+		return
+	}
+	if rule.Attempts > 0 && rule.RetryPeriod != "" {
+		period = rule.RetryPeriod
+	}
+
+	// One key per period: a retry after a lost response finds the
+	// accepted charge instead of charging again.
+	key := fmt.Sprintf("autopay:%s:%s", rule.ContractID, period)
+	intent, err := s.charger.Charge(ctx, rule.ContractID, rule.Method.ID, amount, key)
+	now := s.now()
+	if err != nil {
+		s.logger.WarnContext(ctx, "Autopay: charge failed", "contract_id", rule.ContractID, "attempt", rule.Attempts+1, "error", err)
+		rule.LastError = err.Error()
+		if rule.Attempts < len(retryBackoff) {
+			wait := retryBackoff[rule.Attempts]
+			rule.Attempts++
+			rule.RetryPeriod = period
+			rule.NextAttemptAt = now.Add(wait)
+			s.save(ctx, rule)
+			s.notify(ctx, rule.ContractID, "autopay.retry", amount, pluralHours(wait))
+			return
+		}
+		// Out of retries: give up on this period and wait for the next one.
+		rule.LastPeriod = period
+		rule.RetryPeriod = ""
+		rule.Attempts = 0
+		rule.NextAttemptAt = time.Time{}
+		s.save(ctx, rule)
+		s.notify(ctx, rule.ContractID, "autopay.failed", amount)
+		return
+	}
+
+	rule.LastPeriod = period
+	rule.RetryPeriod = ""
+	rule.Attempts = 0
+	rule.NextAttemptAt = time.Time{}
+	rule.LastChargeAt = &now
+	rule.LastError = ""
+	s.save(ctx, rule)
+	if intent.Status == domain.PaymentIntentSucceeded {
+		s.notify(ctx, rule.ContractID, "autopay.charged", amount)
+	} else {
+		// The provider took the payment but the balance is credited later,
+		// by its webhook.
+		s.notify(ctx, rule.ContractID, "autopay.pending", amount)
+	}
+}
+
+// due decides whether the rule should charge now. period identifies the
+// billing period or day being paid so it is charged only once.
+func (s *Scheduler) due(ctx context.Context, rule domain.AutopayRule, profile domain.Profile) (amount domain.Money, period string, due bool) {
+	now := s.now()
+	if profile.Balance == nil {
+		s.logger.WarnContext(ctx, "Autopay: balance is unknown, skipping", "contract_id", rule.ContractID)
+		return domain.Money{}, "", false
+	}
+	switch rule.Mode {
+	case domain.AutopayBeforeDue:
+		if profile.ToPay == nil {
+			s.logger.WarnContext(ctx, "Autopay: amount due is unknown, skipping", "contract_id", rule.ContractID)
+			return domain.Money{}, "", false
+		}
+		if profile.NextPayDate == "" || profile.ToPay.Minor <= 0 || profile.Balance.Minor >= profile.ToPay.Minor {
+			return domain.Money{}, "", false
+		}
+		nextPay, err := time.ParseInLocation(time.DateOnly, profile.NextPayDate, time.Local)
+		if err != nil {
+			s.logger.WarnContext(ctx, "Autopay: unexpected next pay date", "next_pay_date", profile.NextPayDate, "error", err)
+			return domain.Money{}, "", false
+		}
+		if now.Before(nextPay.AddDate(0, 0, -rule.DaysBefore)) {
+			return domain.Money{}, "", false
+		}
+		return *profile.ToPay, profile.NextPayDate, true
+	case domain.AutopayThreshold:
+		if profile.Balance.Minor >= rule.Threshold.Minor {
+			return domain.Money{}, "", false
+		}
+		// The billing API may take a while to reflect the top-up, so
+		// threshold rules charge at most once a day.
+		return *rule.Amount, now.Format(time.DateOnly), true
+	default:
+		return domain.Money{}, "", false
+	}
+}
+
+// pause stops a rule whose session can't be used. Without a live session
+// the balance can't be checked, so it waits for the user to come back and
+// resume.
+func (s *Scheduler) pause(ctx context.Context, rule domain.AutopayRule, err error) {
+	rule.Status = domain.AutopayPaused
+	rule.LastError = err.Error()
+	s.save(ctx, rule)
+	s.notify(ctx, rule.ContractID, "autopay.session_expired")
+}
+
+func (s *Scheduler) save(ctx context.Context, rule domain.AutopayRule) {
+	if err := s.rules.Save(ctx, rule); err != nil {
+		s.logger.ErrorContext(ctx, "Autopay: failed to save rule", "contract_id", rule.ContractID, "error", err)
+	}
+}
+
+func (s *Scheduler) notify(ctx context.Context, contractID, key string, args ...any) {
+	if err := s.notifier.Notify(ctx, contractID, notificationType, key, args...); err != nil {
+		s.logger.ErrorContext(ctx, "Autopay: failed to notify", "contract_id", contractID, "error", err)
+	}
+}
+
+func pluralHours(d time.Duration) notification.Plural {
+	return notification.Plural{Key: "common.hours", N: int(d.Hours())}
+}
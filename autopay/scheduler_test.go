@@ -0,0 +1,208 @@
+package autopay
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+type stubProfileRepo struct {
+	profile domain.Profile
+	err     error
+}
+
+func (s *stubProfileRepo) Profile(context.Context, string) (domain.Profile, error) {
+	return s.profile, s.err
+}
+
+type stubCharger struct {
+	charges []string
+	status  domain.PaymentIntentStatus
+	err     error
+}
+
+func (s *stubCharger) SavePaymentMethod(context.Context, string) (domain.PaymentMethod, error) {
+	return domain.PaymentMethod{ID: "pm_1"}, nil
+}
+
+func (s *stubCharger) Charge(_ context.Context, _, _ string, _ domain.Money, key string) (domain.PaymentIntent, error) {
+	s.charges = append(s.charges, key)
+	return domain.PaymentIntent{Status: s.status}, s.err
+}
+
+type stubNotifier struct {
+	keys []string
+}
+
+func (s *stubNotifier) Notify(_ context.Context, _, _, key string, _ ...any) error {
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func newTestScheduler(profile *stubProfileRepo, charger *stubCharger, notifier *stubNotifier, rule domain.AutopayRule, now time.Time) (*Scheduler, RuleStore) {
+	rules := SealTokens(memory.New().AutopayRules(), "secret")
+	rule.Principal = domain.Principal{Token: "token", ContractID: rule.ContractID}
+	rules.Save(context.Background(), rule)
+	s := NewScheduler(rules, profile, charger, notifier, time.Hour, logging.Discard())
+	s.now = func() time.Time { return now }
+	return s, rules
+}
+
+func TestSchedulerBeforeDue(t *testing.T) {
+	now := time.Date(2024, 5, 28, 12, 0, 0, 0, time.Local)
+	rule := domain.AutopayRule{ContractID: "1", Mode: domain.AutopayBeforeDue, DaysBefore: 3, Status: domain.AutopayActive}
+
+	tests := []struct {
+		name        string
+		profile     domain.Profile
+		wantCharges int
+	}{
+		{"Due within window", domain.Profile{ToPay: rub(65000), Balance: rub(1000), NextPayDate: "2024-05-30"}, 1},
+		{"Too early", domain.Profile{ToPay: rub(65000), Balance: rub(1000), NextPayDate: "2024-06-05"}, 0},
+		{"Already covered by balance", domain.Profile{ToPay: rub(65000), Balance: rub(70000), NextPayDate: "2024-05-30"}, 0},
+		{"Unknown next pay date", domain.Profile{ToPay: rub(65000), Balance: rub(1000)}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			charger := &stubCharger{}
+			s, _ := newTestScheduler(&stubProfileRepo{profile: tt.profile}, charger, &stubNotifier{}, rule, now)
+
+			// A second run in the same period must not charge again.
+			s.RunOnce(context.Background())
+			s.RunOnce(context.Background())
+
+			if len(charger.charges) != tt.wantCharges {
+				t.Errorf("charged %d times, want %d", len(charger.charges), tt.wantCharges)
+			}
+		})
+	}
+}
+
+func TestSchedulerRetries(t *testing.T) {
+	now := time.Date(2024, 5, 28, 12, 0, 0, 0, time.Local)
+	rule := domain.AutopayRule{ContractID: "1", Mode: domain.AutopayThreshold, Threshold: rub(10000), Amount: rub(50000), Status: domain.AutopayActive}
+	profile := &stubProfileRepo{profile: domain.Profile{Balance: rub(5000)}}
+	charger := &stubCharger{err: domain.ErrPaymentDeclined}
+	notifier := &stubNotifier{}
+	s, rules := newTestScheduler(profile, charger, notifier, rule, now)
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	s.RunOnce(ctx)
+	// The retry is not due before the backoff has passed.
+	s.RunOnce(ctx)
+	if len(charger.charges) != 1 {
+		t.Fatalf("charged %d times before backoff, want 1", len(charger.charges))
+	}
+
+	for _, wait := range retryBackoff {
+		now = now.Add(wait)
+		s.RunOnce(ctx)
+	}
+	if len(charger.charges) != len(retryBackoff)+1 {
+		t.Errorf("charged %d times, want %d", len(charger.charges), len(retryBackoff)+1)
+	}
+	for _, key := range charger.charges {
+		if key != "autopay:1:2024-05-28" {
+			t.Errorf("idempotency key = %s, want the same key for every retry", key)
+		}
+	}
+	if last := notifier.keys[len(notifier.keys)-1]; last != "autopay.failed" {
+		t.Errorf("last notification = %s, want autopay.failed", last)
+	}
+	saved, _ := rules.Get(ctx, "1")
+	if saved.Attempts != 0 || saved.LastPeriod == "" {
+		t.Errorf("rule after giving up = %+v, want attempts reset and period recorded", saved)
+	}
+}
+
+func TestSchedulerNotifiesCredit(t *testing.T) {
+	rule := domain.AutopayRule{ContractID: "1", Mode: domain.AutopayThreshold, Threshold: rub(10000), Amount: rub(50000), Status: domain.AutopayActive}
+	tests := []struct {
+		status domain.PaymentIntentStatus
+		want   string
+	}{
+		{domain.PaymentIntentSucceeded, "autopay.charged"},
+		// Accepted by the provider but not yet credited.
+		{domain.PaymentIntentPending, "autopay.pending"},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			notifier := &stubNotifier{}
+			s, _ := newTestScheduler(&stubProfileRepo{profile: domain.Profile{Balance: rub(0)}}, &stubCharger{status: tt.status}, notifier, rule, time.Now())
+			s.RunOnce(context.Background())
+			if len(notifier.keys) != 1 || notifier.keys[0] != tt.want {
+				t.Errorf("notifications = %v, want %s", notifier.keys, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerSessionExpired(t *testing.T) {
+	rule := domain.AutopayRule{ContractID: "1", Mode: domain.AutopayThreshold, Threshold: rub(10000), Amount: rub(50000), Status: domain.AutopayActive}
+	profile := &stubProfileRepo{err: domain.ErrSessionExpired}
+	s, rules := newTestScheduler(profile, &stubCharger{}, &stubNotifier{}, rule, time.Now())
+
+	s.RunOnce(context.Background())
+
+	saved, _ := rules.Get(context.Background(), "1")
+	if saved.Status != domain.AutopayPaused {
+		t.Errorf("status = %s, want %s", saved.Status, domain.AutopayPaused)
+	}
+}
+
+func TestSealTokens(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.New().AutopayRules()
+	rules := SealTokens(backend, "secret")
+	rule := domain.AutopayRule{ContractID: "1", Principal: domain.Principal{Token: "token", ContractID: "1"}, Status: domain.AutopayActive}
+	if err := rules.Save(ctx, rule); err != nil {
+		t.Fatal(err)
+	}
+
+	stored, _ := backend.Get(ctx, "1")
+	if stored.Principal.Token == "" || stored.Principal.Token == "token" {
+		t.Errorf("stored token = %q, want it encrypted", stored.Principal.Token)
+	}
+	if got, _ := rules.Get(ctx, "1"); got.Principal.Token != "token" {
+		t.Errorf("Get token = %q, want token", got.Principal.Token)
+	}
+	if list, _ := rules.List(ctx); len(list) != 1 || list[0].Principal.Token != "token" {
+		t.Errorf("List = %+v, want the decrypted token", list)
+	}
+	// Another secret can't read the token back.
+	if got, _ := SealTokens(backend, "other").Get(ctx, "1"); got.Principal.Token != "" {
+		t.Errorf("token with another secret = %q, want empty", got.Principal.Token)
+	}
+}
+
+func TestSchedulerUnreadableSession(t *testing.T) {
+	ctx := context.Background()
+	backend := memory.New().AutopayRules()
+	// Saved before the encryption key was changed.
+	backend.Save(ctx, domain.AutopayRule{
+		ContractID: "1", Principal: domain.Principal{Token: "sealed-with-another-key"},
+		Mode: domain.AutopayThreshold, Threshold: rub(10000), Amount: rub(50000), Status: domain.AutopayActive,
+	})
+	charger, notifier := &stubCharger{}, &stubNotifier{}
+	s := NewScheduler(SealTokens(backend, "secret"), &stubProfileRepo{profile: domain.Profile{Balance: rub(0)}}, charger, notifier, time.Hour, logging.Discard())
+
+	s.RunOnce(ctx)
+
+	saved, _ := backend.Get(ctx, "1")
+	if saved.Status != domain.AutopayPaused || len(charger.charges) != 0 {
+		t.Errorf("status = %s after %d charges, want paused without charging", saved.Status, len(charger.charges))
+	}
+	if len(notifier.keys) != 1 || notifier.keys[0] != "autopay.session_expired" {
+		t.Errorf("notifications = %v, want autopay.session_expired", notifier.keys)
+	}
+}
+
+func rub(minor int64) *domain.Money {
+	m := domain.RUB(minor)
+	return &m
+}
@@ -0,0 +1,213 @@
+package autopay
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+)
+
+const (
+	defaultDaysBefore = 3
+	maxDaysBefore     = 10
+)
+
+// ProfileRepository reads the balance and amount due of a contract.
+type ProfileRepository interface {
+	Profile(ctx context.Context, token string) (domain.Profile, error)
+}
+
+// Charger saves payment methods and charges them through the payment provider.
+type Charger interface {
+	SavePaymentMethod(ctx context.Context, paymentToken string) (domain.PaymentMethod, error)
+	Charge(ctx context.Context, contractID, methodID string, amount domain.Money, idempotencyKey string) (domain.PaymentIntent, error)
+}
+
+// Notifier tells the user how an autopay charge went.
+type Notifier interface {
+	Notify(ctx context.Context, contractID, notificationType, key string, args ...any) error
+}
+
+// RuleStore keeps one autopay rule per contract.
+type RuleStore interface {
+	// Get returns domain.ErrNotFound when the contract has no rule.
+	Get(ctx context.Context, contractID string) (domain.AutopayRule, error)
+	Save(ctx context.Context, rule domain.AutopayRule) error
+	Delete(ctx context.Context, contractID string) error
+	List(ctx context.Context) ([]domain.AutopayRule, error)
+}
+
+type Service struct {
+	profileRepo ProfileRepository
+	charger     Charger
+	rules       RuleStore
+	logger      *slog.Logger
+}
+
+// NewService creates a new Service instance.
+func NewService(p ProfileRepository, c Charger, r RuleStore, logger *slog.Logger) *Service {
+	return &Service{
+		profileRepo: p,
+		charger:     c,
+		rules:       r,
+		logger:      logger,
+	}
+}
+
+// Rule returns the autopay rule of the session's contract.
+func (s *Service) Rule(ctx context.Context, token string) (domain.AutopayRule, error) {
+	ctx, span := tracing.Start(ctx, "autopay.Rule")
+	defer span.End()
+
+	_, rule, err := s.load(ctx, token)
+	return rule, err
+}
+
+// SetRule creates or replaces the autopay rule. The payment token is only
+// needed the first time or to switch to another card.
+func (s *Service) SetRule(ctx context.Context, token string, setup domain.AutopaySetup) (domain.AutopayRule, error) {
+	ctx, span := tracing.Start(ctx, "autopay.SetRule")
+	defer span.End()
+
+	if err := validateSetup(&setup); err != nil {
+		return domain.AutopayRule{}, err
+	}
+
+	profile, rule, err := s.load(ctx, token)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return domain.AutopayRule{}, err
+	}
+
+	if setup.PaymentToken != "" {
+		method, err := s.charger.SavePaymentMethod(ctx, setup.PaymentToken)
+		if err != nil {
+			return domain.AutopayRule{}, err
+		}
+		rule.Method = method
+	}
+	if rule.Method.ID == "" {
+		// Nothing to charge without a saved payment method.
+		return domain.AutopayRule{}, domain.ErrBadParamInput
+	}
+
+	rule.ContractID = profile.ID
+	rule.Principal = principal(ctx, token)
+	rule.Mode = setup.Mode
+	rule.DaysBefore = setup.DaysBefore
+	rule.Threshold, rule.Amount = nil, nil
+	if setup.Mode == domain.AutopayThreshold {
+		rule.Threshold, rule.Amount = &setup.Threshold, &setup.Amount
+	}
+	rule.Status = domain.AutopayActive
+	rule.RetryPeriod = ""
+	rule.Attempts = 0
+	rule.LastError = ""
+
+	if err := s.rules.Save(ctx, rule); err != nil {
+		return domain.AutopayRule{}, err
+	}
+	s.logger.InfoContext(ctx, "Autopay rule saved", "contract_id", rule.ContractID)
+	return rule, nil
+}
+
+// Pause stops charging until Resume is called.
+func (s *Service) Pause(ctx context.Context, token string) (domain.AutopayRule, error) {
+	ctx, span := tracing.Start(ctx, "autopay.Pause")
+	defer span.End()
+
+	return s.setStatus(ctx, token, domain.AutopayPaused)
+}
+
+// Resume re-enables a paused rule.
+func (s *Service) Resume(ctx context.Context, token string) (domain.AutopayRule, error) {
+	ctx, span := tracing.Start(ctx, "autopay.Resume")
+	defer span.End()
+
+	return s.setStatus(ctx, token, domain.AutopayActive)
+}
+
+// Cancel removes the rule and forgets the saved payment method.
+func (s *Service) Cancel(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "autopay.Cancel")
+	defer span.End()
+
+	profile, _, err := s.load(ctx, token)
+	if err != nil {
+		return err
+	}
+	s.logger.InfoContext(ctx, "Autopay cancelled", "contract_id", profile.ID)
+	return s.rules.Delete(ctx, profile.ID)
+}
+
+func (s *Service) setStatus(ctx context.Context, token string, status domain.AutopayStatus) (domain.AutopayRule, error) {
+	_, rule, err := s.load(ctx, token)
+	if err != nil {
+		return domain.AutopayRule{}, err
+	}
+	rule.Status = status
+	// A fresh session lets the scheduler read the profile again.
+	rule.Principal = principal(ctx, token)
+	rule.Attempts = 0
+	rule.LastError = ""
+	if err := s.rules.Save(ctx, rule); err != nil {
+		return domain.AutopayRule{}, err
+	}
+	s.logger.InfoContext(ctx, "Autopay status changed", "contract_id", rule.ContractID, "status", status)
+	return rule, nil
+}
+
+// load resolves the session's contract and its rule. The profile is
+// returned even when the contract has no rule yet.
+func (s *Service) load(ctx context.Context, token string) (domain.Profile, domain.AutopayRule, error) {
+	if token == "" || middleware.ContainsForbiddenChars(token) {
+		return domain.Profile{}, domain.AutopayRule{}, domain.ErrInvalidToken
+	}
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		return domain.Profile{}, domain.AutopayRule{}, err
+	}
+	rule, err := s.rules.Get(ctx, profile.ID)
+	return profile, rule, err
+}
+
+// principal returns the caller's principal so the scheduler later works
+// with the same contract the rule was set up for.
+func principal(ctx context.Context, token string) domain.Principal {
+	if p, ok := domain.PrincipalFromContext(ctx); ok {
+		return p
+	}
+	return domain.Principal{Token: token}
+}
+
+func validateSetup(setup *domain.AutopaySetup) error {
+	if middleware.ContainsForbiddenChars(setup.PaymentToken) {
+		return domain.ErrBadParamInput
+	}
+	switch setup.Mode {
+	case domain.AutopayBeforeDue:
+		if setup.DaysBefore == 0 {
+			setup.DaysBefore = defaultDaysBefore
+		}
+		if setup.DaysBefore < 0 || setup.DaysBefore > maxDaysBefore {
+			return domain.ErrBadParamInput
+		}
+		setup.Threshold, setup.Amount = domain.Money{}, domain.Money{}
+	case domain.AutopayThreshold:
+		if setup.Threshold.Minor < 0 || setup.Amount.Minor <= 0 {
+			return domain.ErrBadParamInput
+		}
+		if setup.Threshold.Minor == 0 {
+			// An omitted threshold tops up once the balance goes negative.
+			setup.Threshold = domain.RUB(0)
+		}
+		if setup.Threshold.Currency != domain.CurrencyRUB || setup.Amount.Currency != domain.CurrencyRUB {
+			return domain.ErrBadParamInput
+		}
+		setup.DaysBefore = 0
+	default:
+		return domain.ErrBadParamInput
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package autopay
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/encryption"
+)
+
+// sealedRules is a RuleStore that encrypts the session token of every rule
+// before it reaches storage, so a copy of the database holds no billing
+// session anyone could use.
+type sealedRules struct {
+	rules  RuleStore
+	secret string
+}
+
+// SealTokens wraps rules so the session tokens they keep are encrypted
+// with secret. A token that can't be decrypted, e.g. after the secret was
+// changed, reads back empty and the scheduler pauses the rule until the
+// user signs in again.
+func SealTokens(rules RuleStore, secret string) RuleStore {
+	return sealedRules{rules: rules, secret: secret}
+}
+
+func (s sealedRules) Get(ctx context.Context, contractID string) (domain.AutopayRule, error) {
+	rule, err := s.rules.Get(ctx, contractID)
+	if err != nil {
+		return domain.AutopayRule{}, err
+	}
+	return s.open(rule), nil
+}
+
+func (s sealedRules) Save(ctx context.Context, rule domain.AutopayRule) error {
+	if rule.Principal.Token != "" {
+		sealed, err := encryption.Seal(rule.Principal.Token, s.secret)
+		if err != nil {
+			return err
+		}
+		rule.Principal.Token = sealed
+	}
+	return s.rules.Save(ctx, rule)
+}
+
+func (s sealedRules) Delete(ctx context.Context, contractID string) error {
+	return s.rules.Delete(ctx, contractID)
+}
+
+func (s sealedRules) List(ctx context.Context) ([]domain.AutopayRule, error) {
+	rules, err := s.rules.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range rules {
+		rules[i] = s.open(rules[i])
+	}
+	return rules, nil
+}
+
+func (s sealedRules) open(rule domain.AutopayRule) domain.AutopayRule {
+	if rule.Principal.Token == "" {
+		return rule
+	}
+	token, err := encryption.Open(rule.Principal.Token, s.secret)
+	if err != nil {
+		token = ""
+	}
+	rule.Principal.Token = token
+	return rule
+}
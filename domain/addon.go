@@ -0,0 +1,34 @@
+package domain
+
+// Addon is an optional service on top of the tariff, such as a static IP,
+// TV, antivirus or equipment rental.
+type Addon struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Category     string `json:"category"`
+	MonthlyPrice Money  `json:"monthly_price"`
+	Connected    bool   `json:"connected"`
+	// ToPayEffect is how much the monthly payment changes if the add-on is
+	// toggled: positive for available add-ons, negative for connected ones.
+	ToPayEffect Money `json:"to_pay_effect"`
+}
+
+// AddonList is the user's add-ons together with what they add to the payment.
+// ToPay is nil when the billing reported an amount due that couldn't be read.
+type AddonList struct {
+	Items       []Addon `json:"items"`
+	AddonsTotal Money   `json:"addons_total"`
+	ToPay       *Money  `json:"to_pay"`
+}
+
+// AddonChange describes enabling or disabling an add-on. It is returned
+// unconfirmed as a preview, and confirmed once the change is made. The
+// amounts due are nil when the current one is unknown.
+type AddonChange struct {
+	Addon       Addon  `json:"addon"`
+	Enable      bool   `json:"enable"`
+	ToPayBefore *Money `json:"to_pay_before"`
+	ToPayAfter  *Money `json:"to_pay_after"`
+	Confirmed   bool   `json:"confirmed"`
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// APIKeyScope is what an API key may be used for.
+type APIKeyScope string
+
+const (
+	// ScopeSwagger opens the API documentation.
+	ScopeSwagger APIKeyScope = "swagger"
+	// ScopeAdmin opens every key-protected endpoint.
+	ScopeAdmin APIKeyScope = "admin"
+	// ScopePartner gives partner integrations read-only access.
+	ScopePartner APIKeyScope = "partner"
+)
+
+// ParseAPIKeyScope returns the scope named s.
+func ParseAPIKeyScope(s string) (APIKeyScope, error) {
+	switch scope := APIKeyScope(s); scope {
+	case ScopeSwagger, ScopeAdmin, ScopePartner:
+		return scope, nil
+	default:
+		return "", fmt.Errorf("unknown API key scope %q, use swagger, admin or partner", s)
+	}
+}
+
+// APIKey describes an issued API key. Its secret is only known when the key
+// is created.
+type APIKey struct {
+	ID         string
+	Name       string
+	Scopes     []APIKeyScope
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	UseCount   int64
+}
+
+// Active reports whether the key can be used at now.
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Permits reports whether the key may make a method request to an endpoint
+// open to scope. Admin keys may do anything; partner keys may only read.
+func (k APIKey) Permits(scope APIKeyScope, method string) bool {
+	if slices.Contains(k.Scopes, ScopeAdmin) {
+		return true
+	}
+	if !slices.Contains(k.Scopes, scope) {
+		return false
+	}
+	if scope == ScopePartner {
+		return method == http.MethodGet || method == http.MethodHead
+	}
+	return true
+}
+
+// IssuedAPIKey is a key that was just created, with the secret the client
+// has to send. The secret can't be recovered later.
+type IssuedAPIKey struct {
+	APIKey
+	Secret string
+}
+
+type apiKeyKey struct{}
+
+// ContextWithAPIKey returns a copy of ctx carrying the key the request was
+// authenticated with. Whether the key grants access to the endpoint is
+// checked separately.
+func ContextWithAPIKey(ctx context.Context, k APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, k)
+}
+
+// APIKeyFromContext returns the API key stored in ctx, if any.
+func APIKeyFromContext(ctx context.Context) (APIKey, bool) {
+	k, ok := ctx.Value(apiKeyKey{}).(APIKey)
+	return k, ok
+}
@@ -1,7 +1,51 @@
 package domain
 
+import "context"
+
 type Auth struct {
 	Login    string `json:"login"`
 	Password string `json:"passwd"`
 	Token    string `json:"token"`
 }
+
+// Contract is a contract linked to a login.
+type Contract struct {
+	ID      string `json:"id"`
+	Number  string `json:"number"`
+	Address string `json:"address"`
+	Tariff  string `json:"tariff"`
+	// Current marks the contract the session is working with.
+	Current bool `json:"current"`
+}
+
+// Session is the result of signing in or switching the contract.
+type Session struct {
+	Token      string     `json:"token"`
+	ContractID string     `json:"contract_id"`
+	Contracts  []Contract `json:"contracts"`
+}
+
+// ContractSwitch is the request to switch the session to another contract.
+type ContractSwitch struct {
+	ContractID string `json:"contract_id"`
+}
+
+// Principal is the authenticated caller: the billing session and the
+// contract every call is scoped to.
+type Principal struct {
+	Token      string
+	ContractID string
+}
+
+type principalKey struct{}
+
+// ContextWithPrincipal returns a copy of ctx carrying p.
+func ContextWithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal stored in ctx, if any.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// PaymentMethod is a tokenised payment method saved at the payment provider.
+type PaymentMethod struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+}
+
+// AutopayMode selects when autopay charges the saved payment method.
+type AutopayMode string
+
+const (
+	// AutopayBeforeDue pays Profile.ToPay ahead of Profile.NextPayDate.
+	AutopayBeforeDue AutopayMode = "before_due"
+	// AutopayThreshold tops the balance up when it drops below a threshold.
+	AutopayThreshold AutopayMode = "threshold"
+)
+
+// AutopayStatus is the state of an autopay rule.
+type AutopayStatus string
+
+const (
+	AutopayActive AutopayStatus = "active"
+	AutopayPaused AutopayStatus = "paused"
+)
+
+// AutopayRule is a contract's recurring payment setup.
+type AutopayRule struct {
+	ContractID string `json:"-"`
+	// Principal is the session and contract scope the scheduler charges in.
+	Principal Principal     `json:"-"`
+	Method    PaymentMethod `json:"method"`
+	Mode      AutopayMode   `json:"mode"`
+	Status    AutopayStatus `json:"status"`
+	// DaysBefore is how many days before NextPayDate a before_due rule pays.
+	DaysBefore int `json:"days_before,omitempty"`
+	// Threshold and Amount configure a threshold rule; they are nil for
+	// other modes.
+	Threshold *Money `json:"threshold,omitempty"`
+	Amount    *Money `json:"amount,omitempty"`
+
+	LastPeriod string `json:"-"`
+	// RetryPeriod is the period a failed charge is being retried for, so
+	// retries keep its idempotency key even once the day has changed.
+	RetryPeriod   string     `json:"-"`
+	Attempts      int        `json:"-"`
+	NextAttemptAt time.Time  `json:"-"`
+	LastChargeAt  *time.Time `json:"last_charge_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// AutopaySetup is the request to create or change the autopay rule. The
+// payment token is only required when no method is saved yet.
+type AutopaySetup struct {
+	PaymentToken string      `json:"payment_token"`
+	Mode         AutopayMode `json:"mode"`
+	DaysBefore   int         `json:"days_before"`
+	Threshold    Money       `json:"threshold"`
+	Amount       Money       `json:"amount"`
+}
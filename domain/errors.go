@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrInternalServerError will throw if any internal server error occurs
@@ -30,6 +33,18 @@ var (
 	// ErrInsufficientFunds will throw if the user does not have enough funds
 	ErrInsufficientFunds = errors.New("insufficient funds")
 
+	// ErrPaymentDeclined will throw if the payment provider declines a charge
+	ErrPaymentDeclined = errors.New("payment was declined")
+
+	// ErrNotEligible will throw if the account does not qualify for the requested action
+	ErrNotEligible = errors.New("action is not available for this account")
+
+	// ErrBalanceUnknown will throw if the billing reported a balance that can't be read
+	ErrBalanceUnknown = errors.New("balance is unknown")
+
+	// ErrPreconditionFailed will throw if the resource changed since the client read it
+	ErrPreconditionFailed = errors.New("resource has been modified")
+
 	// ErrInvalidToken will throw if the provided token is invalid
 	ErrInvalidToken = errors.New("invalid token")
 
@@ -39,3 +54,24 @@ var (
 	// ErrTooManyRequests will throw if the rate limit is exceeded
 	ErrTooManyRequests = errors.New("too many requests, please try again later")
 )
+
+// RateLimitError is ErrTooManyRequests telling when the client may retry.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string { return ErrTooManyRequests.Error() }
+
+func (e *RateLimitError) Unwrap() error { return ErrTooManyRequests }
+
+// NotEligibleError is ErrNotEligible telling the client why.
+type NotEligibleError struct {
+	// Reason is a machine-readable code, e.g. "cooldown".
+	Reason string
+	// Message explains the reason in the language of the request.
+	Message string
+}
+
+func (e *NotEligibleError) Error() string { return ErrNotEligible.Error() + ": " + e.Reason }
+
+func (e *NotEligibleError) Unwrap() error { return ErrNotEligible }
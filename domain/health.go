@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// HealthStatus is the state of the service or of one of its dependencies.
+type HealthStatus string
+
+const (
+	HealthReady   HealthStatus = "ready"
+	HealthUnready HealthStatus = "unready"
+	HealthOK      HealthStatus = "ok"
+	HealthFailed  HealthStatus = "failed"
+)
+
+// HealthReport is the outcome of the readiness checks.
+type HealthReport struct {
+	Status    HealthStatus  `json:"status"`
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []HealthCheck `json:"checks"`
+}
+
+// Ready reports whether the service can take traffic.
+func (r HealthReport) Ready() bool {
+	return r.Status == HealthReady
+}
+
+// HealthCheck is the outcome of checking one dependency.
+type HealthCheck struct {
+	Name       string       `json:"name"`
+	Status     HealthStatus `json:"status"`
+	DurationMS int64        `json:"duration_ms"`
+	Error      string       `json:"error,omitempty"`
+}
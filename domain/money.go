@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CurrencyRUB is the currency the billing keeps accounts in.
+const CurrencyRUB = "RUB"
+
+// Money is an exact amount in minor units (kopecks) of a currency.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// RUB returns an amount of minor units in roubles.
+func RUB(minor int64) Money {
+	return Money{Minor: minor, Currency: CurrencyRUB}
+}
+
+// ParseMoney parses a plain decimal amount such as "650", "-120.5" or "1234.56".
+// More than two fractional digits is an error rather than being rounded.
+func ParseMoney(s, currency string) (Money, error) {
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" || len(frac) > 2 || !isDigits(whole) || !isDigits(frac) {
+		return Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	frac += strings.Repeat("0", 2-len(frac))
+
+	minor, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	if negative {
+		minor = -minor
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// String formats the amount as a decimal with two fractional digits.
+func (m Money) String() string {
+	sign := ""
+	minor := m.Minor
+	if minor < 0 {
+		sign = "-"
+		minor = -minor
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, minor/100, minor%100)
+}
+
+type moneyJSON struct {
+	Amount     string `json:"amount"`
+	MinorUnits int64  `json:"minor_units"`
+	Currency   string `json:"currency"`
+}
+
+// MarshalJSON encodes the amount both as an exact decimal string and in minor units.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(moneyJSON{Amount: m.String(), MinorUnits: m.Minor, Currency: m.Currency})
+}
+
+// UnmarshalJSON decodes the form written by MarshalJSON, in roubles when the
+// currency is left out. The object needs minor_units or amount, and both
+// must agree when both are sent. Requests may also send a plain decimal, as
+// a number or a string such as 650.5 or "650.50", which is read in roubles.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 0 && data[0] != '{' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			s = string(data)
+		}
+		money, err := ParseMoney(s, CurrencyRUB)
+		if err != nil {
+			return err
+		}
+		*m = money
+		return nil
+	}
+
+	var v struct {
+		Amount     *string `json:"amount"`
+		MinorUnits *int64  `json:"minor_units"`
+		Currency   string  `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Currency == "" {
+		v.Currency = CurrencyRUB
+	}
+	var money Money
+	switch {
+	case v.MinorUnits != nil:
+		money = Money{Minor: *v.MinorUnits, Currency: v.Currency}
+		if v.Amount != nil {
+			if amount, err := ParseMoney(*v.Amount, v.Currency); err != nil || amount != money {
+				return fmt.Errorf("amount %q does not match minor_units %d", *v.Amount, *v.MinorUnits)
+			}
+		}
+	case v.Amount != nil:
+		amount, err := ParseMoney(*v.Amount, v.Currency)
+		if err != nil {
+			return err
+		}
+		money = amount
+	default:
+		return errors.New("amount needs minor_units or amount")
+	}
+	*m = money
+	return nil
+}
+
+func isDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package domain
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"650", 65000, false},
+		{"-120.5", -12050, false},
+		{"1234.56", 123456, false},
+		{"+0.01", 1, false},
+		{"12.345", 0, true},
+		{"1 234,56", 0, true},
+		{".5", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseMoney(tt.in, CurrencyRUB)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMoney() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Minor != tt.want {
+				t.Errorf("ParseMoney() = %d, want %d", got.Minor, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(RUB(-12050))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"amount":"-120.50","minor_units":-12050,"currency":"RUB"}`; string(data) != want {
+		t.Errorf("Marshal() = %s, want %s", data, want)
+	}
+
+	var m Money
+	if err := json.Unmarshal(data, &m); err != nil || m != RUB(-12050) {
+		t.Errorf("Unmarshal() = %v, %v", m, err)
+	}
+}
+
+func TestMoneyUnmarshalRequest(t *testing.T) {
+	tests := []struct {
+		data    string
+		want    Money
+		wantErr bool
+	}{
+		{`650.5`, RUB(65050), false},
+		{`"650.50"`, RUB(65050), false},
+		{`{"minor_units":65050}`, RUB(65050), false},
+		{`{"minor_units":100,"currency":"USD"}`, Money{Minor: 100, Currency: "USD"}, false},
+		{`{"amount":"650"}`, RUB(65000), false},
+		{`{"amount":"650.50","minor_units":65050}`, RUB(65050), false},
+		{`null`, Money{}, false},
+		{`{}`, Money{}, true},
+		{`{"currency":"RUB"}`, Money{}, true},
+		{`{"amount":"650","minor_units":650}`, Money{}, true},
+		{`{"amount":"6.5.0"}`, Money{}, true},
+		{`650.505`, Money{}, true},
+		{`"six hundred"`, Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.data, func(t *testing.T) {
+			var m Money
+			err := json.Unmarshal([]byte(tt.data), &m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if m != tt.want {
+				t.Errorf("Unmarshal() = %+v, want %+v", m, tt.want)
+			}
+		})
+	}
+}
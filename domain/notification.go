@@ -1,6 +1,7 @@
 package domain
 
 type Notification struct {
-	Body string `json:"body"`
-	Type string `json:"type"`
+	Title string `json:"title"`
+	Body  string `json:"body"`
+	Type  string `json:"type"`
 }
@@ -0,0 +1,64 @@
+package domain
+
+import "time"
+
+// Payment is a single entry of the account's payment history.
+type Payment struct {
+	Amount  Money     `json:"amount"`
+	Date    time.Time `json:"date"`
+	Method  string    `json:"method"`
+	Balance Money     `json:"balance"`
+}
+
+// PaymentFilter narrows the payment history to a date range and page.
+type PaymentFilter struct {
+	From    time.Time
+	To      time.Time
+	Page    int
+	PerPage int
+}
+
+// PaymentPage is one page of the payment history.
+type PaymentPage struct {
+	Items   []Payment `json:"items"`
+	Page    int       `json:"page"`
+	PerPage int       `json:"per_page"`
+	Total   int       `json:"total"`
+}
+
+// PaymentIntentStatus is the lifecycle state of an online top-up.
+type PaymentIntentStatus string
+
+const (
+	PaymentIntentPending    PaymentIntentStatus = "pending"
+	PaymentIntentProcessing PaymentIntentStatus = "processing"
+	PaymentIntentSucceeded  PaymentIntentStatus = "succeeded"
+	PaymentIntentCanceled   PaymentIntentStatus = "canceled"
+)
+
+// PaymentIntent is an online top-up created through a payment provider.
+type PaymentIntent struct {
+	ID              string              `json:"id"`
+	ContractID      string              `json:"-"`
+	Amount          Money               `json:"amount"`
+	Status          PaymentIntentStatus `json:"status"`
+	ConfirmationURL string              `json:"confirmation_url"`
+	ProviderID      string              `json:"-"`
+	IdempotencyKey  string              `json:"-"`
+	CreatedAt       time.Time           `json:"created_at"`
+}
+
+// TopUp is the request to create a payment intent. A zero amount means
+// "pay what is due" (Profile.ToPay).
+type TopUp struct {
+	Amount    Money  `json:"amount"`
+	ReturnURL string `json:"return_url"`
+}
+
+// ProviderEvent is a verified webhook notification from a payment provider.
+type ProviderEvent struct {
+	ProviderID string
+	IntentID   string
+	Status     PaymentIntentStatus
+	Amount     Money
+}
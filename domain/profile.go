@@ -1,17 +1,124 @@
 package domain
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
 type Profile struct {
-	ID             string  `json:"ID"`
-	FirstName      string  `json:"firstName"`
-	MiddleName     string  `json:"middle_name"`
-	LastName       string  `json:"last_name"`
-	FullName       string  `json:"full_name"`
-	Balance        float64 `json:"balance"`
-	ToPay          float64 `json:"to_pay"`
-	Tariff         string  `json:"tariff"`
-	Email          string  `json:"email"`
-	Phone          string  `json:"phone"`
-	Password       string  `json:"password"`
-	InternetStatus bool    `json:"internet_status"`
-	NextPayDate    string  `json:"next_pay_date"`
+	// ID is the contract number; it identifies the contract throughout the API.
+	ID             string `json:"ID"`
+	ContractNumber string `json:"contract_number"`
+	// AccountNumber is the personal account payments are made to.
+	AccountNumber string `json:"account_number"`
+	// ContractDate is the day the contract was signed, YYYY-MM-DD.
+	ContractDate string `json:"contract_date"`
+	Address      string `json:"address"`
+	FirstName    string `json:"firstName"`
+	MiddleName   string `json:"middle_name"`
+	LastName     string `json:"last_name"`
+	FullName     string `json:"full_name"`
+	// Balance is nil, and null in JSON, when the billing reported a balance
+	// that couldn't be read.
+	Balance *Money `json:"balance"`
+	// ToPay is what is due for the next period; nil, like Balance, when the
+	// billing reported an amount that couldn't be read.
+	ToPay *Money `json:"to_pay"`
+	// Tariff is the tariff name for display; TariffID identifies it.
+	Tariff   string `json:"tariff"`
+	TariffID string `json:"tariff_id"`
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	// Password is never filled: the billing API doesn't return it.
+	Password       string `json:"password,omitempty"`
+	InternetStatus bool   `json:"internet_status"`
+	// NextPayDate is the day the next monthly charge is due, YYYY-MM-DD,
+	// or empty when the billing doesn't report it.
+	NextPayDate string `json:"next_pay_date"`
+	// PromisedPayment is the active trust credit and its deadline, if any.
+	PromisedPayment *PromisedPayment `json:"promised_payment,omitempty"`
+	// Suspension is the voluntary suspension the internet is currently off for, if any.
+	Suspension *Suspension `json:"suspension,omitempty"`
+}
+
+// ETag identifies the current representation of the profile, for
+// If-None-Match. It starts with the EditVersion, which is what If-Match is
+// checked against, so a balance that moved since the client read the
+// profile doesn't fail an update of the contact details.
+func (p Profile) ETag() string {
+	return `"` + p.EditVersion() + "-" + hash(p) + `"`
+}
+
+// EditVersion identifies the fields a client can change. Clients send it
+// back, as part of the ETag, so an update made from another device isn't
+// lost.
+func (p Profile) EditVersion() string {
+	return hash(struct{ ID, Email, Phone string }{p.ID, p.Email, p.Phone})
+}
+
+// EditVersionOf returns the EditVersion an ETag was made with.
+func EditVersionOf(etag string) string {
+	etag = strings.Trim(strings.TrimPrefix(etag, "W/"), `"`)
+	version, _, _ := strings.Cut(etag, "-")
+	return version
+}
+
+func hash(v any) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ProfileUpdate is a JSON Merge Patch (RFC 7396) of the editable profile
+// fields. A nil field is left as it is; a null email removes it.
+type ProfileUpdate struct {
+	Email *string `json:"email,omitempty"`
+	Phone *string `json:"phone,omitempty"`
+}
+
+// UnmarshalJSON decodes a merge patch. Fields that can't be changed and
+// removing the phone, which the billing requires, are rejected.
+func (u *ProfileUpdate) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if fields == nil {
+		return errors.New("merge patch must be an object")
+	}
+
+	*u = ProfileUpdate{}
+	for name, value := range fields {
+		var target **string
+		switch name {
+		case "email":
+			target = &u.Email
+		case "phone":
+			target = &u.Phone
+		default:
+			return fmt.Errorf("field %q can't be changed", name)
+		}
+
+		var v *string
+		if err := json.Unmarshal(value, &v); err != nil {
+			return fmt.Errorf("field %q: %w", name, err)
+		}
+		if v == nil {
+			if name == "phone" {
+				return errors.New(`field "phone" can't be removed`)
+			}
+			v = new(string)
+		}
+		*target = v
+	}
+	return nil
+}
+
+// Empty reports whether the patch changes nothing.
+func (u ProfileUpdate) Empty() bool {
+	return u.Email == nil && u.Phone == nil
 }
@@ -0,0 +1,31 @@
+package domain
+
+import "time"
+
+// PromisedPayment is a trust credit that keeps the internet on until the deadline.
+type PromisedPayment struct {
+	Amount    Money     `json:"amount"`
+	CreatedAt time.Time `json:"created_at"`
+	Deadline  time.Time `json:"deadline"`
+}
+
+// PromisedPaymentHistory is the current promised payment, if any, and the
+// ones taken before it.
+type PromisedPaymentHistory struct {
+	Current *PromisedPayment
+	Past    []PromisedPayment
+}
+
+// PromisedPaymentOffer tells the user whether a promised payment is
+// available and for how much.
+type PromisedPaymentOffer struct {
+	Eligible bool `json:"eligible"`
+	// Reason is a machine-readable code explaining why the offer is unavailable.
+	Reason        string           `json:"reason,omitempty"`
+	Message       string           `json:"message,omitempty"`
+	MinAmount     Money            `json:"min_amount"`
+	MaxAmount     Money            `json:"max_amount"`
+	Days          int              `json:"days"`
+	AvailableFrom *time.Time       `json:"available_from,omitempty"`
+	Current       *PromisedPayment `json:"current,omitempty"`
+}
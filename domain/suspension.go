@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// SuspensionStatus is the state of a voluntary service suspension.
+type SuspensionStatus string
+
+const (
+	SuspensionScheduled SuspensionStatus = "scheduled"
+	SuspensionActive    SuspensionStatus = "active"
+	SuspensionFinished  SuspensionStatus = "finished"
+	SuspensionCancelled SuspensionStatus = "cancelled"
+)
+
+// Suspension pauses the internet and its charges between two dates, both inclusive.
+type Suspension struct {
+	ID        string           `json:"id"`
+	StartDate time.Time        `json:"start_date"`
+	EndDate   time.Time        `json:"end_date"`
+	Status    SuspensionStatus `json:"status"`
+	Days      int              `json:"days"`
+}
+
+// SuspensionRequest is the request to schedule a suspension. Dates are YYYY-MM-DD.
+type SuspensionRequest struct {
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+}
+
+// SuspensionSummary lists the suspensions with the yearly allowance.
+type SuspensionSummary struct {
+	Items         []Suspension `json:"items"`
+	AllowedDays   int          `json:"allowed_days"`
+	UsedDays      int          `json:"used_days"`
+	RemainingDays int          `json:"remaining_days"`
+}
@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// Tariff is an internet plan available at the user's address.
+type Tariff struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	SpeedMbps   int    `json:"speed_mbps"`
+	Price       Money  `json:"price"`
+	Current     bool   `json:"current"`
+}
+
+// TariffChangeWhen selects when a tariff change takes effect.
+type TariffChangeWhen string
+
+const (
+	TariffChangeNow        TariffChangeWhen = "now"
+	TariffChangeNextPeriod TariffChangeWhen = "next_period"
+)
+
+// TariffChange is the request to switch to another tariff.
+type TariffChange struct {
+	TariffID string           `json:"tariff_id"`
+	When     TariffChangeWhen `json:"when"`
+}
+
+// TariffChangePreview shows what a tariff change costs before it is made.
+type TariffChangePreview struct {
+	Tariff        Tariff    `json:"tariff"`
+	EffectiveFrom time.Time `json:"effective_from"`
+	// Charge is what is written off the balance when the change is made.
+	Charge       Money `json:"charge"`
+	BalanceAfter Money `json:"balance_after"`
+	Sufficient   bool  `json:"sufficient"`
+}
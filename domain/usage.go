@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// UsageBucket is the aggregation step of traffic statistics.
+type UsageBucket string
+
+const (
+	UsageBucketDay   UsageBucket = "day"
+	UsageBucketMonth UsageBucket = "month"
+)
+
+// ConnectionType is the access technology of a connection session.
+type ConnectionType string
+
+const (
+	ConnectionPPPoE ConnectionType = "pppoe"
+	ConnectionIPoE  ConnectionType = "ipoe"
+)
+
+// TrafficPoint is the traffic of one bucket. Period is the first day of the bucket.
+type TrafficPoint struct {
+	Period   time.Time `json:"period"`
+	BytesIn  int64     `json:"bytes_in"`
+	BytesOut int64     `json:"bytes_out"`
+}
+
+// ConnectionSession is a single PPPoE or IPoE session. End is nil while
+// the session is still open.
+type ConnectionSession struct {
+	ID       string         `json:"id"`
+	Type     ConnectionType `json:"type"`
+	Start    time.Time      `json:"start"`
+	End      *time.Time     `json:"end,omitempty"`
+	IP       string         `json:"ip"`
+	BytesIn  int64          `json:"bytes_in"`
+	BytesOut int64          `json:"bytes_out"`
+}
+
+// UsageQuery selects the statistics to return. Dates are YYYY-MM-DD and inclusive.
+type UsageQuery struct {
+	From   string      `query:"from"`
+	To     string      `query:"to"`
+	Bucket UsageBucket `query:"bucket"`
+}
+
+// Usage is the traffic and the connection sessions over a date range.
+type Usage struct {
+	From     time.Time           `json:"from"`
+	To       time.Time           `json:"to"`
+	Bucket   UsageBucket         `json:"bucket"`
+	Traffic  []TrafficPoint      `json:"traffic"`
+	BytesIn  int64               `json:"bytes_in"`
+	BytesOut int64               `json:"bytes_out"`
+	Sessions []ConnectionSession `json:"sessions"`
+}
@@ -0,0 +1,142 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"golang.org/x/sync/singleflight"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// checkTimeout bounds each check so a hung dependency reads as failed
+	// before the probe of the orchestrator times out.
+	checkTimeout = 2 * time.Second
+	// reportTTL is how long a report is reused, so frequent probes don't
+	// turn into a stream of billing calls.
+	reportTTL = 5 * time.Second
+)
+
+// errDraining is reported once the service has started shutting down.
+var errDraining = errors.New("shutting down")
+
+// Check is a dependency the service needs to serve traffic.
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type Service struct {
+	checks  []Check
+	timeout time.Duration
+	now     func() time.Time
+	logger  *slog.Logger
+	group   singleflight.Group
+
+	draining atomic.Bool
+
+	mu   sync.Mutex
+	last domain.HealthReport
+}
+
+// NewService creates a Service running checks to decide readiness.
+func NewService(checks []Check, logger *slog.Logger) *Service {
+	return &Service{
+		checks:  checks,
+		timeout: checkTimeout,
+		now:     time.Now,
+		logger:  logger,
+	}
+}
+
+// Drain makes the service report unready from now on, so the orchestrator
+// stops routing traffic to it before the server stops.
+func (s *Service) Drain() {
+	if !s.draining.Swap(true) {
+		s.logger.Info("Readiness switched to unready for shutdown")
+	}
+}
+
+// Ready runs the checks, or reuses a report younger than reportTTL, and
+// reports whether every dependency is usable.
+func (s *Service) Ready(ctx context.Context) domain.HealthReport {
+	if s.draining.Load() {
+		return domain.HealthReport{
+			Status:    domain.HealthUnready,
+			CheckedAt: s.now(),
+			Checks: []domain.HealthCheck{{
+				Name:   "shutdown",
+				Status: domain.HealthFailed,
+				Error:  errDraining.Error(),
+			}},
+		}
+	}
+
+	s.mu.Lock()
+	last := s.last
+	s.mu.Unlock()
+	if !last.CheckedAt.IsZero() && s.now().Sub(last.CheckedAt) < reportTTL {
+		return last
+	}
+
+	// Concurrent probes share one run. It must not be cut short when the
+	// probe that started it goes away.
+	v, _, _ := s.group.Do("ready", func() (interface{}, error) {
+		report := s.run(context.WithoutCancel(ctx))
+		s.mu.Lock()
+		s.last = report
+		s.mu.Unlock()
+		return report, nil
+	})
+	return v.(domain.HealthReport)
+}
+
+func (s *Service) run(ctx context.Context) domain.HealthReport {
+	ctx, span := tracing.Start(ctx, "health.Ready")
+	defer span.End()
+
+	report := domain.HealthReport{
+		Status:    domain.HealthReady,
+		CheckedAt: s.now(),
+		Checks:    make([]domain.HealthCheck, len(s.checks)),
+	}
+	var wg sync.WaitGroup
+	for i, check := range s.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = s.runCheck(ctx, check)
+		}()
+	}
+	wg.Wait()
+
+	for _, check := range report.Checks {
+		if check.Status != domain.HealthOK {
+			report.Status = domain.HealthUnready
+		}
+	}
+	return report
+}
+
+func (s *Service) runCheck(ctx context.Context, check Check) domain.HealthCheck {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check.Run(ctx)
+	result := domain.HealthCheck{
+		Name:       check.Name,
+		Status:     domain.HealthOK,
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		s.logger.WarnContext(ctx, "Readiness check failed", "check", check.Name, "error", err)
+		result.Status = domain.HealthFailed
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"testing"
+	"time"
+)
+
+func TestReady(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	down := func(context.Context) error { return errors.New("connection refused") }
+	hung := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name       string
+		checks     []Check
+		want       domain.HealthStatus
+		wantFailed []string
+	}{
+		{"no checks", nil, domain.HealthReady, nil},
+		{"all ok", []Check{{"billing", ok}, {"storage", ok}}, domain.HealthReady, nil},
+		{"one down", []Check{{"billing", down}, {"storage", ok}}, domain.HealthUnready, []string{"billing"}},
+		{"timeout", []Check{{"billing", hung}}, domain.HealthUnready, []string{"billing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(tt.checks, logging.Discard())
+			svc.timeout = 10 * time.Millisecond
+			report := svc.Ready(context.Background())
+			if report.Status != tt.want {
+				t.Errorf("status = %s, want %s", report.Status, tt.want)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Fatalf("got %d checks, want %d", len(report.Checks), len(tt.checks))
+			}
+			var failed []string
+			for i, check := range report.Checks {
+				if check.Name != tt.checks[i].Name {
+					t.Errorf("check %d = %s, want %s", i, check.Name, tt.checks[i].Name)
+				}
+				if check.Status == domain.HealthFailed {
+					if check.Error == "" {
+						t.Errorf("check %s failed without an error", check.Name)
+					}
+					failed = append(failed, check.Name)
+				}
+			}
+			if len(failed) != len(tt.wantFailed) || (len(failed) > 0 && failed[0] != tt.wantFailed[0]) {
+				t.Errorf("failed = %v, want %v", failed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestReadyReusesReport(t *testing.T) {
+	calls := 0
+	svc := NewService([]Check{{"billing", func(context.Context) error {
+		calls++
+		return nil
+	}}}, logging.Discard())
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	svc.now = func() time.Time { return now }
+
+	svc.Ready(context.Background())
+	svc.Ready(context.Background())
+	if calls != 1 {
+		t.Errorf("checks ran %d times within the TTL, want 1", calls)
+	}
+
+	now = now.Add(reportTTL)
+	svc.Ready(context.Background())
+	if calls != 2 {
+		t.Errorf("checks ran %d times after the TTL, want 2", calls)
+	}
+}
+
+func TestDrain(t *testing.T) {
+	svc := NewService([]Check{{"billing", func(context.Context) error { return nil }}}, logging.Discard())
+	if !svc.Ready(context.Background()).Ready() {
+		t.Fatal("not ready before draining")
+	}
+	svc.Drain()
+	if report := svc.Ready(context.Background()); report.Ready() {
+		t.Errorf("ready while draining: %+v", report)
+	}
+}
@@ -0,0 +1,29 @@
+package certs
+
+import (
+	"fmt"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+	"os"
+)
+
+// NewACMEManager returns a manager that obtains and renews certificates
+// for domains, keeping them in cacheDir. directoryURL selects the CA and
+// defaults to Let's Encrypt. Certificates are requested on the first TLS
+// handshake for a domain; challenges are answered through the manager's
+// TLS config on port 443, or its HTTP handler on port 80.
+func NewACMEManager(domains []string, email, cacheDir, directoryURL string) (*autocert.Manager, error) {
+	if err := os.MkdirAll(cacheDir, 0o700); err != nil {
+		return nil, fmt.Errorf("acme cache: %w", err)
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(domains...),
+		Email:      email,
+	}
+	if directoryURL != "" {
+		manager.Client = &acme.Client{DirectoryURL: directoryURL}
+	}
+	return manager, nil
+}
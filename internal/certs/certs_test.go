@@ -0,0 +1,137 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCert writes a self-signed certificate for name and returns its serial.
+func writeCert(t *testing.T, certFile, keyFile, name string, modTime time.Time) *big.Int {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][]byte{
+		certFile: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyFile:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+	for file, data := range files {
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return serial
+}
+
+func serialOf(t *testing.T, r *Reloader) *big.Int {
+	t.Helper()
+	cert, err := r.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.SerialNumber
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	start := time.Now().Add(-time.Hour)
+
+	first := writeCert(t, certFile, keyFile, "a.example.com", start)
+	r, err := NewReloader(certFile, keyFile, logging.Discard())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := serialOf(t, r); got.Cmp(first) != 0 {
+		t.Fatalf("serial = %v, want %v", got, first)
+	}
+
+	if reloaded, err := r.Reload(); err != nil || reloaded {
+		t.Errorf("Reload() of unchanged files = %v, %v", reloaded, err)
+	}
+
+	second := writeCert(t, certFile, keyFile, "a.example.com", start.Add(time.Minute))
+	if reloaded, err := r.Reload(); err != nil || !reloaded {
+		t.Fatalf("Reload() of new files = %v, %v", reloaded, err)
+	}
+	if got := serialOf(t, r); got.Cmp(second) != 0 {
+		t.Errorf("serial = %v, want %v", got, second)
+	}
+
+	// A half-written pair is rejected and the last good certificate kept.
+	if err := os.WriteFile(keyFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reload(); err == nil {
+		t.Error("Reload() of a broken key succeeded")
+	}
+	if got := serialOf(t, r); got.Cmp(second) != 0 {
+		t.Errorf("serial after failed reload = %v, want %v", got, second)
+	}
+}
+
+func TestNewReloaderFailsOnMissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), logging.Discard()); err == nil {
+		t.Error("NewReloader() succeeded without files")
+	}
+}
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		port   string
+		target string
+		want   string
+	}{
+		{"443", "http://api.example.com/api/v1/profile?x=1", "https://api.example.com/api/v1/profile?x=1"},
+		{"", "http://api.example.com:80/", "https://api.example.com/"},
+		{"8443", "http://api.example.com:8080/healthz", "https://api.example.com:8443/healthz"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		RedirectHandler(tt.port).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.target, nil))
+		if rec.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: status = %d", tt.target, rec.Code)
+		}
+		if got := rec.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s: Location = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package certs
+
+import (
+	"net"
+	"net/http"
+)
+
+// RedirectHandler sends plain HTTP requests to the same URL over HTTPS on
+// httpsPort, which is left out of the URL when it is 443 or empty.
+func RedirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+		// 308 keeps the method and body of API calls.
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
@@ -0,0 +1,101 @@
+// Package certs provides the certificates the server presents over HTTPS,
+// either from files on disk or obtained automatically over ACME.
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves the certificate in a pair of PEM files and picks up new
+// files, e.g. renewed by certbot, without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	logger   *slog.Logger
+
+	mu    sync.RWMutex
+	cert  *tls.Certificate
+	stamp [2]fileStamp
+}
+
+// fileStamp tells whether a file has been replaced since it was loaded.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewReloader loads the certificate in certFile and keyFile. It fails when
+// they can't be loaded, so a broken setup is noticed at startup.
+func NewReloader(certFile, keyFile string, logger *slog.Logger) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, logger: logger}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// GetCertificate returns the current certificate; it fits tls.Config.
+func (r *Reloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// Reload loads the files again if they changed since the last load and
+// reports whether it did. On error the current certificate stays in use.
+func (r *Reloader) Reload() (bool, error) {
+	var stamp [2]fileStamp
+	for i, file := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false, fmt.Errorf("tls certificate: %w", err)
+		}
+		stamp[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	r.mu.RLock()
+	unchanged := r.cert != nil && stamp == r.stamp
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return false, fmt.Errorf("tls certificate: %w", err)
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.stamp = stamp
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Watch checks the files every interval until ctx is done.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := r.Reload()
+		switch {
+		case err != nil:
+			// Certbot may be halfway through writing the pair; the next
+			// check will see the finished files.
+			r.logger.Error("Failed to reload TLS certificate, keeping the current one", "error", err)
+		case reloaded:
+			r.logger.Info("Reloaded TLS certificate", "cert_file", r.certFile)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Package config loads the settings of the service. Values come from, in
+// increasing order of precedence: the defaults below, an optional YAML or
+// TOML file, an optional .env file and the environment.
+package config
+
+import "time"
+
+// Config is the complete configuration of the service.
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	TLS       TLS       `yaml:"tls" toml:"tls"`
+	Upstream  Upstream  `yaml:"upstream" toml:"upstream"`
+	CORS      CORS      `yaml:"cors" toml:"cors"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Storage   Storage   `yaml:"storage" toml:"storage"`
+	Log       Log       `yaml:"log" toml:"log"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+	Security  Security  `yaml:"security" toml:"security"`
+	Payment   Payment   `yaml:"payment" toml:"payment"`
+	Cache     Cache     `yaml:"cache" toml:"cache"`
+	Autopay   Autopay   `yaml:"autopay" toml:"autopay"`
+	Features  Features  `yaml:"features" toml:"features"`
+	// I18NDir holds message catalogues replacing the embedded ones.
+	I18NDir string `yaml:"i18n_dir" toml:"i18n_dir" env:"I18N_DIR"`
+}
+
+// Server configures the HTTP server and its lifecycle.
+type Server struct {
+	Address string `yaml:"address" toml:"address" env:"SERVER_ADDRESS"`
+	// RequestTimeout bounds the context of every request.
+	RequestTimeout    time.Duration `yaml:"request_timeout" toml:"request_timeout" env:"CONTEXT_TIMEOUT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	// WriteTimeout defaults to RequestTimeout plus time to write the response.
+	WriteTimeout time.Duration `yaml:"write_timeout" toml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" toml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// DrainDelay is how long readiness reports unready before the server
+	// stops taking requests.
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay" env:"READINESS_DRAIN_DELAY"`
+	// ShutdownTimeout bounds the wait for in-flight requests on shutdown.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// TrustedProxies lists the networks, besides private and loopback
+	// ones, whose X-Forwarded-For header names the client IP.
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies" env:"SERVER_TRUSTED_PROXIES"`
+}
+
+// TLS modes.
+const (
+	TLSDisabled = "disabled"
+	// TLSStatic serves the certificate in CertFile and KeyFile and reloads
+	// it when the files change.
+	TLSStatic = "static"
+	// TLSACME obtains and renews certificates for ACME.Domains from an
+	// ACME CA such as Let's Encrypt.
+	TLSACME = "acme"
+)
+
+// TLS configures HTTPS.
+type TLS struct {
+	Mode     string `yaml:"mode" toml:"mode" env:"TLS_MODE"`
+	CertFile string `yaml:"cert_file" toml:"cert_file" env:"TLS_CERT_FILE"`
+	KeyFile  string `yaml:"key_file" toml:"key_file" env:"TLS_KEY_FILE"`
+	// ReloadInterval is how often static certificate files are checked
+	// for changes.
+	ReloadInterval time.Duration `yaml:"reload_interval" toml:"reload_interval" env:"TLS_RELOAD_INTERVAL"`
+	ACME           ACME          `yaml:"acme" toml:"acme"`
+	// RedirectAddress, when set, is where plain HTTP is served, redirecting
+	// to HTTPS and answering ACME HTTP-01 challenges, e.g. ":80".
+	RedirectAddress string `yaml:"redirect_address" toml:"redirect_address" env:"TLS_REDIRECT_ADDRESS"`
+	// HSTSMaxAge, when positive, makes browsers use HTTPS only for that long.
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age" toml:"hsts_max_age" env:"TLS_HSTS_MAX_AGE"`
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains" toml:"hsts_include_subdomains" env:"TLS_HSTS_INCLUDE_SUBDOMAINS"`
+}
+
+// Enabled reports whether the server should serve HTTPS.
+func (t TLS) Enabled() bool {
+	return t.Mode != TLSDisabled
+}
+
+// ACME configures automatic certificates.
+type ACME struct {
+	Domains []string `yaml:"domains" toml:"domains" env:"TLS_ACME_DOMAINS"`
+	// Email is given to the CA for expiry notices.
+	Email string `yaml:"email" toml:"email" env:"TLS_ACME_EMAIL"`
+	// CacheDir keeps account keys and certificates across restarts.
+	CacheDir string `yaml:"cache_dir" toml:"cache_dir" env:"TLS_ACME_CACHE_DIR"`
+	// DirectoryURL selects the CA; empty means Let's Encrypt production.
+	DirectoryURL string `yaml:"directory_url" toml:"directory_url" env:"TLS_ACME_DIRECTORY_URL"`
+}
+
+// Upstream configures calls to the billing API.
+type Upstream struct {
+	BaseURL string        `yaml:"base_url" toml:"base_url" env:"BASE_URL"`
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" env:"UPSTREAM_TIMEOUT"`
+	Retry   Retry         `yaml:"retry" toml:"retry"`
+}
+
+// Retry is the policy for billing calls that could not reach the API.
+type Retry struct {
+	// MaxAttempts counts the first attempt; 1 disables retries.
+	MaxAttempts int `yaml:"max_attempts" toml:"max_attempts" env:"UPSTREAM_RETRY_ATTEMPTS"`
+	// Backoff is the wait before the first retry and doubles after each.
+	Backoff time.Duration `yaml:"backoff" toml:"backoff" env:"UPSTREAM_RETRY_BACKOFF"`
+}
+
+// CORS configures cross-origin access from browsers. Groups override the
+// policy for requests under a path prefix.
+type CORS struct {
+	CORSPolicy `yaml:",inline"`
+	Groups     []CORSGroup `yaml:"groups" toml:"groups"`
+}
+
+// CORSPolicy decides which browser origins may call the API and how.
+type CORSPolicy struct {
+	// AllowOrigins lists the origins allowed to call the API, such as
+	// https://app.example.com. https://*.example.com allows every subdomain
+	// and "*" allows any origin.
+	AllowOrigins     []string `yaml:"allow_origins" toml:"allow_origins" env:"CORS_ALLOW_ORIGINS"`
+	AllowCredentials bool     `yaml:"allow_credentials" toml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	// AllowHeaders lists the request headers scripts may send.
+	AllowHeaders []string `yaml:"allow_headers" toml:"allow_headers" env:"CORS_ALLOW_HEADERS"`
+	// ExposeHeaders lists the response headers scripts may read.
+	ExposeHeaders []string `yaml:"expose_headers" toml:"expose_headers" env:"CORS_EXPOSE_HEADERS"`
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration `yaml:"max_age" toml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// CORSGroup replaces the CORS policy for requests under Prefix. Fields it
+// leaves out take their defaults, not the values of the main policy.
+type CORSGroup struct {
+	Prefix     string `yaml:"prefix" toml:"prefix"`
+	CORSPolicy `yaml:",inline"`
+}
+
+// RateLimit configures request throttling per client: the API key, else
+// the session, else the IP address. The quota applies to requests no group
+// matches.
+type RateLimit struct {
+	// Enabled is on by default; turn it off only when something in front
+	// of the service already limits clients.
+	Enabled bool `yaml:"enabled" toml:"enabled" env:"RATE_LIMIT_ENABLED"`
+	Quota   `yaml:",inline"`
+	Groups  []RateLimitGroup `yaml:"groups" toml:"groups"`
+}
+
+// Quota is a token bucket: Burst requests at once, refilled at Rate.
+type Quota struct {
+	// Rate is the number of requests per second a client may sustain.
+	Rate float64 `yaml:"rate" toml:"rate" env:"RATE_LIMIT_RATE"`
+	// Burst is the number of requests a client may make at once.
+	Burst int `yaml:"burst" toml:"burst" env:"RATE_LIMIT_BURST"`
+}
+
+// RateLimitGroup gives requests under Prefix their own quota, counted
+// apart from the others. Methods, when set, narrows the group to those
+// methods. A zero rate lifts the limit.
+type RateLimitGroup struct {
+	Prefix  string   `yaml:"prefix" toml:"prefix"`
+	Methods []string `yaml:"methods" toml:"methods"`
+	Quota   `yaml:",inline"`
+}
+
+// Storage selects where local state is kept.
+type Storage struct {
+	// DSN is "memory" or "sqlite:<path>".
+	DSN string `yaml:"dsn" toml:"dsn" env:"STORAGE_DSN"`
+}
+
+// Log configures the structured logger.
+type Log struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+}
+
+// Tracing selects where spans are exported.
+type Tracing struct {
+	Exporter string `yaml:"exporter" toml:"exporter" env:"TRACE_EXPORTER"`
+}
+
+// Security holds the secrets protecting the API.
+type Security struct {
+	// APIKey is accepted as an admin API key next to the keys managed
+	// with the apikey command. Leave it empty to use managed keys only.
+	APIKey string `yaml:"api_key" toml:"api_key" env:"API_KEY" secret:"true"`
+	// SessionSecret signs bearer tokens. Without it a random key is used
+	// and tokens don't survive a restart.
+	SessionSecret string `yaml:"session_secret" toml:"session_secret" env:"SESSION_SECRET" secret:"true"`
+	// EncryptionKey encrypts the billing sessions autopay rules keep in
+	// storage. Use a long random string; changing it pauses every rule
+	// until its user signs in again.
+	EncryptionKey string `yaml:"encryption_key" toml:"encryption_key" env:"ENCRYPTION_KEY" secret:"true"`
+}
+
+// Payment configures the payment provider used for top-ups and autopay.
+type Payment struct {
+	// Provider is empty when no provider is set up, which turns top-ups
+	// and autopay off.
+	Provider string `yaml:"provider" toml:"provider" env:"PAYMENT_PROVIDER"`
+	// AllowFake permits the "fake" provider, which confirms payments
+	// without taking any money. Only for development.
+	AllowFake     bool   `yaml:"allow_fake" toml:"allow_fake" env:"PAYMENT_ALLOW_FAKE"`
+	WebhookSecret string `yaml:"webhook_secret" toml:"webhook_secret" env:"PAYMENT_WEBHOOK_SECRET" secret:"true"`
+	ConfirmURL    string `yaml:"confirm_url" toml:"confirm_url" env:"PAYMENT_CONFIRM_URL"`
+}
+
+// Cache configures the profile cache.
+type Cache struct {
+	// ProfileTTL is how long a profile is served from the cache; 0 disables it.
+	ProfileTTL time.Duration `yaml:"profile_ttl" toml:"profile_ttl" env:"PROFILE_CACHE_TTL"`
+}
+
+// Autopay configures the recurring payment scheduler.
+type Autopay struct {
+	Interval time.Duration `yaml:"interval" toml:"interval" env:"AUTOPAY_INTERVAL"`
+}
+
+// Features switches optional parts of the service on or off.
+type Features struct {
+	// Autopay needs a payment provider.
+	Autopay bool `yaml:"autopay" toml:"autopay" env:"FEATURE_AUTOPAY"`
+	Swagger bool `yaml:"swagger" toml:"swagger" env:"FEATURE_SWAGGER"`
+	Metrics bool `yaml:"metrics" toml:"metrics" env:"FEATURE_METRICS"`
+}
+
+// Default returns the configuration used for anything not set explicitly.
+func Default() Config {
+	return Config{
+		Server: Server{
+			Address:           ":9090",
+			RequestTimeout:    30 * time.Second,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			DrainDelay:        5 * time.Second,
+			ShutdownTimeout:   15 * time.Second,
+		},
+		TLS: TLS{
+			Mode:           TLSDisabled,
+			ReloadInterval: time.Minute,
+			ACME: ACME{
+				CacheDir: "autocert",
+			},
+		},
+		Upstream: Upstream{
+			Timeout: 20 * time.Second,
+			Retry: Retry{
+				MaxAttempts: 3,
+				Backoff:     200 * time.Millisecond,
+			},
+		},
+		CORS: CORS{
+			CORSPolicy: DefaultCORSPolicy(),
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+			Quota:   Quota{Rate: 10, Burst: 20},
+			Groups: []RateLimitGroup{
+				// Sign-in and password resets are what credential
+				// stuffing goes for.
+				{Prefix: "/api/v1/auth", Quota: Quota{Rate: 0.1, Burst: 5}},
+				// The app polls the profile.
+				{Prefix: "/api/v1/profile", Methods: []string{"GET"}, Quota: Quota{Rate: 20, Burst: 40}},
+			},
+		},
+		Storage: Storage{
+			DSN: "memory",
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: Tracing{
+			Exporter: "none",
+		},
+		Cache: Cache{
+			ProfileTTL: 30 * time.Second,
+		},
+		Autopay: Autopay{
+			Interval: time.Hour,
+		},
+		Features: Features{
+			Swagger: true,
+			Metrics: true,
+		},
+	}
+}
+
+// DefaultCORSPolicy is the policy of the API and the base of group policies.
+func DefaultCORSPolicy() CORSPolicy {
+	return CORSPolicy{
+		AllowOrigins:  []string{"*"},
+		AllowHeaders:  []string{"Accept-Language", "Authorization", "Content-Type", "If-Match", "If-None-Match", "X-Request-ID"},
+		ExposeHeaders: []string{"ETag", "Retry-After", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-RateLimit-Reset", "X-Request-ID"},
+		MaxAge:        10 * time.Minute,
+	}
+}
+
+// writeMargin is the time allowed to write a response once the request
+// context has expired.
+const writeMargin = 10 * time.Second
+
+// resolve fills in settings that default to others.
+func (c *Config) resolve() {
+	if c.Server.WriteTimeout == 0 {
+		c.Server.WriteTimeout = c.Server.RequestTimeout + writeMargin
+	}
+	defaults := DefaultCORSPolicy()
+	for i := range c.CORS.Groups {
+		group := &c.CORS.Groups[i].CORSPolicy
+		if group.AllowOrigins == nil {
+			group.AllowOrigins = defaults.AllowOrigins
+		}
+		if group.AllowHeaders == nil {
+			group.AllowHeaders = defaults.AllowHeaders
+		}
+		if group.ExposeHeaders == nil {
+			group.ExposeHeaders = defaults.ExposeHeaders
+		}
+		if group.MaxAge == 0 {
+			group.MaxAge = defaults.MaxAge
+		}
+	}
+}
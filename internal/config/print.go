@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// masked replaces secrets in printed configuration.
+const masked = "********"
+
+// Print writes the configuration to w, one "key = value" line per setting
+// with the names used in configuration files. Secrets are masked and so is
+// any password in the upstream URL.
+func (c Config) Print(w io.Writer) error {
+	var b strings.Builder
+	printStruct(&b, "", reflect.ValueOf(c))
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func printStruct(b *strings.Builder, prefix string, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, value := t.Field(i), v.Field(i)
+		key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		name := prefix + key
+		switch {
+		case field.Anonymous && key == "":
+			// Inlined into the parent.
+			printStruct(b, prefix, value)
+			continue
+		case field.Type.Kind() == reflect.Struct && field.Type != durationType:
+			printStruct(b, name+".", value)
+			continue
+		case field.Type.Kind() == reflect.Slice && field.Type.Elem().Kind() == reflect.Struct:
+			for j := 0; j < value.Len(); j++ {
+				printStruct(b, fmt.Sprintf("%s[%d].", name, j), value.Index(j))
+			}
+			continue
+		}
+
+		var out string
+		switch {
+		case field.Tag.Get("secret") == "true":
+			if !value.IsZero() {
+				out = masked
+			}
+		case value.Kind() == reflect.String:
+			out = maskURL(value.String())
+		case value.Kind() == reflect.Slice:
+			out = strings.Join(value.Interface().([]string), ",")
+		default:
+			out = fmt.Sprint(value.Interface())
+		}
+		fmt.Fprintf(b, "%s = %s\n", name, out)
+	}
+}
+
+// maskURL hides the password of a URL with credentials and leaves any
+// other string as it is.
+func maskURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	return u.Redacted()
+}
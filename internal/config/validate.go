@@ -0,0 +1,247 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Validate reports every setting that keeps the service from starting or
+// doing its job, not just the first.
+func (c Config) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.Server.Address == "" {
+		fail("server.address is required")
+	}
+	for _, setting := range []struct {
+		name string
+		d    time.Duration
+	}{
+		{"server.request_timeout", c.Server.RequestTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"upstream.timeout", c.Upstream.Timeout},
+		{"autopay.interval", c.Autopay.Interval},
+	} {
+		if setting.d <= 0 {
+			fail("%s must be positive", setting.name)
+		}
+	}
+	if c.Server.DrainDelay < 0 {
+		fail("server.drain_delay must not be negative")
+	}
+
+	errs = append(errs, c.TLS.validate(c.Server.Address)...)
+
+	if err := validateBaseURL(c.Upstream.BaseURL); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Upstream.Retry.MaxAttempts < 1 {
+		fail("upstream.retry.max_attempts must be at least 1")
+	}
+	if c.Upstream.Retry.Backoff < 0 {
+		fail("upstream.retry.backoff must not be negative")
+	}
+
+	errs = append(errs, c.CORS.CORSPolicy.validate("cors")...)
+	prefixes := make(map[string]bool, len(c.CORS.Groups))
+	for i, group := range c.CORS.Groups {
+		name := fmt.Sprintf("cors.groups[%d]", i)
+		if !strings.HasPrefix(group.Prefix, "/") {
+			fail("%s.prefix must be a path such as /api/v1/payments", name)
+		}
+		if prefixes[group.Prefix] {
+			fail("%s.prefix: %s has another group", name, group.Prefix)
+		}
+		prefixes[group.Prefix] = true
+		errs = append(errs, group.CORSPolicy.validate(name)...)
+	}
+
+	if c.RateLimit.Enabled {
+		if c.RateLimit.Rate <= 0 {
+			fail("rate_limit.rate must be positive")
+		}
+		if c.RateLimit.Burst < 1 {
+			fail("rate_limit.burst must be at least 1")
+		}
+		groups := make(map[string]bool, len(c.RateLimit.Groups))
+		for i, group := range c.RateLimit.Groups {
+			name := fmt.Sprintf("rate_limit.groups[%d]", i)
+			if !strings.HasPrefix(group.Prefix, "/") {
+				fail("%s.prefix must be a path such as /api/v1/auth", name)
+			}
+			for _, method := range group.Methods {
+				if !oneOf(method, http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete) {
+					fail("%s.methods: unknown method %q", name, method)
+				}
+			}
+			key := group.Prefix + " " + strings.Join(group.Methods, ",")
+			if groups[key] {
+				fail("%s: another group has the same prefix and methods", name)
+			}
+			groups[key] = true
+			if group.Rate < 0 {
+				fail("%s.rate must not be negative", name)
+			}
+			if group.Rate > 0 && group.Burst < 1 {
+				fail("%s.burst must be at least 1", name)
+			}
+		}
+	}
+	for _, cidr := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			fail("server.trusted_proxies: %q is not a network such as 203.0.113.0/24", cidr)
+		}
+	}
+
+	if scheme, path, _ := strings.Cut(c.Storage.DSN, ":"); scheme == "sqlite" && path == "" {
+		fail("storage.dsn: sqlite needs a path, e.g. sqlite:/var/lib/spektr/state.db")
+	} else if scheme != "memory" && scheme != "sqlite" {
+		fail("storage.dsn: unknown storage %q, use memory or sqlite:<path>", scheme)
+	}
+
+	if !oneOf(strings.ToLower(c.Log.Level), "debug", "info", "warn", "error") {
+		fail("log.level: unknown level %q", c.Log.Level)
+	}
+	if !oneOf(strings.ToLower(c.Log.Format), "text", "json") {
+		fail("log.format: unknown format %q", c.Log.Format)
+	}
+	if !oneOf(strings.ToLower(c.Tracing.Exporter), "none", "stdout", "otlp") {
+		fail("tracing.exporter: unknown exporter %q", c.Tracing.Exporter)
+	}
+
+	switch c.Payment.Provider {
+	case "":
+		if c.Features.Autopay {
+			fail("features.autopay needs payment.provider")
+		}
+	case "fake":
+		if !c.Payment.AllowFake {
+			fail("payment.provider: fake confirms payments without charging anyone; set payment.allow_fake to use it in development")
+		}
+		if c.Payment.WebhookSecret == "" {
+			fail("payment.webhook_secret is required")
+		}
+	default:
+		fail("payment.provider: unknown provider %q", c.Payment.Provider)
+	}
+	if c.Features.Autopay && c.Security.EncryptionKey == "" {
+		fail("features.autopay needs security.encryption_key to protect the sessions it keeps")
+	}
+	if c.Cache.ProfileTTL < 0 {
+		fail("cache.profile_ttl must not be negative")
+	}
+	return errors.Join(errs...)
+}
+
+// validate reports a TLS setup that can't work, so the server doesn't fall
+// back to something the operator didn't ask for. address is where HTTPS is
+// served.
+func (t TLS) validate(address string) []error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch t.Mode {
+	case TLSDisabled:
+		if t.CertFile != "" || t.KeyFile != "" {
+			fail("tls.cert_file and tls.key_file are set but tls.mode is disabled; set it to static")
+		}
+		if t.RedirectAddress != "" {
+			fail("tls.redirect_address needs tls.mode static or acme")
+		}
+		if t.HSTSMaxAge > 0 {
+			fail("tls.hsts_max_age needs tls.mode static or acme")
+		}
+	case TLSStatic:
+		if t.CertFile == "" || t.KeyFile == "" {
+			fail("tls.mode static needs tls.cert_file and tls.key_file")
+		}
+		for _, file := range []string{t.CertFile, t.KeyFile} {
+			if file == "" {
+				continue
+			}
+			if _, err := os.Stat(file); err != nil {
+				fail("tls: %v", err)
+			}
+		}
+		if t.ReloadInterval <= 0 {
+			fail("tls.reload_interval must be positive")
+		}
+	case TLSACME:
+		if len(t.ACME.Domains) == 0 {
+			fail("tls.mode acme needs tls.acme.domains")
+		}
+		if t.ACME.CacheDir == "" {
+			fail("tls.mode acme needs tls.acme.cache_dir")
+		}
+		// The CA checks the domains on port 443 (TLS-ALPN-01) or 80
+		// (HTTP-01); on any other port issuance fails at the first
+		// handshake.
+		if _, port, _ := net.SplitHostPort(address); port != "443" && t.RedirectAddress == "" {
+			fail("tls.mode acme needs server.address on port 443 or tls.redirect_address, e.g. :80, to answer the CA's challenges")
+		}
+	default:
+		fail("tls.mode: unknown mode %q, use disabled, static or acme", t.Mode)
+	}
+	if t.HSTSMaxAge < 0 {
+		fail("tls.hsts_max_age must not be negative")
+	}
+	return errs
+}
+
+// validate reports origins browsers would never send and a policy that
+// grants credentials to every site. name prefixes the errors.
+func (p CORSPolicy) validate(name string) []error {
+	var errs []error
+	for _, origin := range p.AllowOrigins {
+		if origin == "*" {
+			if p.AllowCredentials {
+				errs = append(errs, fmt.Errorf("%s: allow_credentials can't be combined with allow_origins *, which would let any site act as the user", name))
+			}
+			continue
+		}
+		// A leading "*." in the host allows its subdomains.
+		u, err := url.Parse(strings.Replace(origin, "://*.", "://wildcard.", 1))
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Path != "" || u.RawQuery != "" {
+			errs = append(errs, fmt.Errorf("%s.allow_origins: %q is not an origin such as https://example.com or https://*.example.com", name, origin))
+		}
+	}
+	if p.MaxAge < 0 {
+		errs = append(errs, fmt.Errorf("%s.max_age must not be negative", name))
+	}
+	return errs
+}
+
+func validateBaseURL(raw string) error {
+	if raw == "" {
+		return errors.New("upstream.base_url is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("upstream.base_url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+func oneOf(s string, values ...string) bool {
+	for _, v := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
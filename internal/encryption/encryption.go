@@ -1,6 +1,11 @@
 package encryption
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
 	"strings"
 	"unicode"
@@ -79,3 +84,51 @@ func isAlphaNumeric(input string) bool {
 	}
 	return true
 }
+
+// Seal encrypts and authenticates plaintext with AES-256-GCM under a key
+// derived from secret, which may be any non-empty string. The result is
+// base64 and carries its own nonce, so sealing the same value twice gives
+// different output.
+func Seal(plaintext, secret string) (string, error) {
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value produced by Seal with the same secret. It fails if
+// the value was sealed with another secret or has been tampered with.
+func Open(sealed, secret string) (string, error) {
+	aead, err := newAEAD(secret)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errors.New("sealed value is malformed")
+	}
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("sealed value can't be decrypted with this secret")
+	}
+	return string(plaintext), nil
+}
+
+// newAEAD derives the AES-256 key from secret.
+func newAEAD(secret string) (cipher.AEAD, error) {
+	if secret == "" {
+		return nil, errors.New("secret must not be empty")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
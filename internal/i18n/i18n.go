@@ -0,0 +1,244 @@
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is used when the client does not ask for a supported language.
+const DefaultLanguage = "ru"
+
+//go:embed locales/*.json
+var embedded embed.FS
+
+// message is a single catalogue entry. Plain entries only have Other set,
+// plural entries carry one form per CLDR category.
+type message struct {
+	One   string `json:"one"`
+	Few   string `json:"few"`
+	Many  string `json:"many"`
+	Other string `json:"other"`
+}
+
+func (m *message) UnmarshalJSON(data []byte) error {
+	var plain string
+	if err := json.Unmarshal(data, &plain); err == nil {
+		m.Other = plain
+		return nil
+	}
+	type forms message
+	var f forms
+	if err := json.Unmarshal(data, &f); err != nil {
+		return err
+	}
+	*m = message(f)
+	return nil
+}
+
+// Bundle holds message catalogues for every supported language.
+type Bundle struct {
+	fallback string
+	catalogs map[string]map[string]message
+}
+
+// Default returns a bundle with the catalogues shipped with the binary.
+func Default() *Bundle {
+	b, err := Load(embedded, "locales")
+	if err != nil {
+		panic(fmt.Sprintf("i18n: broken embedded catalogues: %v", err))
+	}
+	return b
+}
+
+// Load reads every <lang>.json catalogue from dir in fsys.
+func Load(fsys fs.FS, dir string) (*Bundle, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read catalogue dir: %w", err)
+	}
+
+	b := &Bundle{
+		fallback: DefaultLanguage,
+		catalogs: make(map[string]map[string]message),
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read catalogue %s: %w", entry.Name(), err)
+		}
+		catalog := make(map[string]message)
+		if err := json.Unmarshal(data, &catalog); err != nil {
+			return nil, fmt.Errorf("failed to parse catalogue %s: %w", entry.Name(), err)
+		}
+		b.catalogs[strings.TrimSuffix(entry.Name(), ".json")] = catalog
+	}
+	if _, ok := b.catalogs[b.fallback]; !ok {
+		return nil, fmt.Errorf("catalogue for default language %q is missing", b.fallback)
+	}
+	return b, nil
+}
+
+// Languages returns the languages the bundle has catalogues for.
+func (b *Bundle) Languages() []string {
+	langs := make([]string, 0, len(b.catalogs))
+	for lang := range b.catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Negotiate picks the best supported language for an Accept-Language header value.
+func (b *Bundle) Negotiate(acceptLanguage string) string {
+	best, bestQ := b.fallback, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, q := parseLanguageRange(part)
+		if tag == "" || q <= bestQ {
+			continue
+		}
+		// Only the primary subtag matters: ru-RU and ru share a catalogue.
+		primary, _, _ := strings.Cut(tag, "-")
+		if _, ok := b.catalogs[primary]; ok {
+			best, bestQ = primary, q
+		}
+	}
+	return best
+}
+
+func parseLanguageRange(part string) (string, float64) {
+	tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	q := 1.0
+	if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return "", 0
+		}
+		q = parsed
+	}
+	return tag, q
+}
+
+// Localizer returns a localizer for lang, falling back to the default language.
+func (b *Bundle) Localizer(lang string) *Localizer {
+	if _, ok := b.catalogs[lang]; !ok {
+		lang = b.fallback
+	}
+	return &Localizer{bundle: b, lang: lang}
+}
+
+// Localizer translates message keys for one negotiated language.
+type Localizer struct {
+	bundle *Bundle
+	lang   string
+}
+
+// Language returns the language the localizer translates into.
+func (l *Localizer) Language() string {
+	return l.lang
+}
+
+// T returns the message for key formatted with args. Unknown keys are
+// returned as-is so a missing translation never breaks a response.
+func (l *Localizer) T(key string, args ...any) string {
+	m, ok := l.lookup(key)
+	if !ok {
+		return key
+	}
+	return format(m.Other, args)
+}
+
+// Plural returns the plural form of key matching n. The count is passed
+// to the format string as the first argument, followed by args.
+func (l *Localizer) Plural(key string, n int, args ...any) string {
+	m, ok := l.lookup(key)
+	if !ok {
+		return key
+	}
+	var form string
+	switch pluralCategory(l.lang, n) {
+	case "one":
+		form = m.One
+	case "few":
+		form = m.Few
+	case "many":
+		form = m.Many
+	}
+	if form == "" {
+		form = m.Other
+	}
+	return format(form, append([]any{n}, args...))
+}
+
+// Has reports whether key has a translation in this or the default language.
+func (l *Localizer) Has(key string) bool {
+	_, ok := l.lookup(key)
+	return ok
+}
+
+func (l *Localizer) lookup(key string) (message, bool) {
+	if m, ok := l.bundle.catalogs[l.lang][key]; ok {
+		return m, true
+	}
+	m, ok := l.bundle.catalogs[l.bundle.fallback][key]
+	return m, ok
+}
+
+func format(s string, args []any) string {
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
+
+// pluralCategory implements the CLDR cardinal rules for integers.
+func pluralCategory(lang string, n int) string {
+	if n < 0 {
+		n = -n
+	}
+	switch lang {
+	case "ru", "uk", "be":
+		mod10, mod100 := n%10, n%100
+		switch {
+		case mod10 == 1 && mod100 != 11:
+			return "one"
+		case mod10 >= 2 && mod10 <= 4 && (mod100 < 12 || mod100 > 14):
+			return "few"
+		default:
+			return "many"
+		}
+	default:
+		if n == 1 {
+			return "one"
+		}
+		return "other"
+	}
+}
+
+type contextKey struct{}
+
+// WithLocalizer stores the localizer in the context.
+func WithLocalizer(ctx context.Context, l *Localizer) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the localizer stored in ctx, or a default-language
+// localizer when the request did not go through the language middleware.
+func FromContext(ctx context.Context) *Localizer {
+	if l, ok := ctx.Value(contextKey{}).(*Localizer); ok {
+		return l
+	}
+	return defaultBundle.Localizer(DefaultLanguage)
+}
+
+var defaultBundle = Default()
@@ -0,0 +1,57 @@
+package i18n
+
+import "testing"
+
+func TestPlural(t *testing.T) {
+	ru := Default().Localizer("ru")
+	en := Default().Localizer("en")
+
+	tests := []struct {
+		name string
+		l    *Localizer
+		n    int
+		want string
+	}{
+		{"ru one", ru, 1, "1 день"},
+		{"ru few", ru, 2, "2 дня"},
+		{"ru many", ru, 5, "5 дней"},
+		{"ru eleven", ru, 11, "11 дней"},
+		{"ru twenty one", ru, 21, "21 день"},
+		{"ru twenty two", ru, 22, "22 дня"},
+		{"ru one hundred twelve", ru, 112, "112 дней"},
+		{"ru zero", ru, 0, "0 дней"},
+		{"en one", en, 1, "1 day"},
+		{"en other", en, 5, "5 days"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.l.Plural("common.days", tt.n); got != tt.want {
+				t.Errorf("Plural(common.days, %d) = %q, want %q", tt.n, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"Empty header", "", "ru"},
+		{"Exact match", "en", "en"},
+		{"Region subtag", "en-US,en;q=0.9", "en"},
+		{"Quality ordering", "en;q=0.5, ru;q=0.8", "ru"},
+		{"Unsupported language", "de-DE", "ru"},
+		{"Unsupported preferred", "de, en;q=0.7", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Default().Negotiate(tt.header); got != tt.want {
+				t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedactString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Fetching profile", "Fetching profile"},
+		{"email ivan.petrov@example.com changed", "email i***@example.com changed"},
+		{"phone +79991234567", "phone ***67"},
+		{"phone 8 (999) 123-45-67", "phone ***67"},
+		{"call +7 999 123 45 67 now", "call ***67 now"},
+		{`arg1={"suid":"abc123","psw1":"hunter2"}`, `arg1={"suid":"[REDACTED]","psw1":"[REDACTED]"}`},
+		{"GET /api?token=abc&x=1", "GET /api?token=[REDACTED]&x=1"},
+		{"Authorization: Bearer eyJhbGciOi.sig", "Authorization: Bearer [REDACTED]"},
+		{"password = secret", "password = [REDACTED]"},
+		// Amounts and contract numbers are not phones.
+		{"charged 1500.00 to contract 12345", "charged 1500.00 to contract 12345"},
+	}
+	for _, tt := range tests {
+		if got := RedactString(tt.in); got != tt.want {
+			t.Errorf("RedactString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "debug", "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	logger.With("token", "abc").InfoContext(ctx, "Updating user@example.com",
+		"phone", "+79991234567",
+		"email", "user@example.com",
+		"error", errors.New(`API error for {"suid":"abc"}`),
+		slog.Group("user", "password", "hunter2", "contract_id", "42"),
+	)
+
+	var line map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	want := map[string]any{
+		"msg":        "Updating u***@example.com",
+		"token":      "[REDACTED]",
+		"phone":      "***67",
+		"email":      "u***@example.com",
+		"error":      `API error for {"suid":"[REDACTED]"}`,
+		"request_id": "req-1",
+	}
+	for key, value := range want {
+		if line[key] != value {
+			t.Errorf("%s = %v, want %v", key, line[key], value)
+		}
+	}
+	user, _ := line["user"].(map[string]any)
+	if user["password"] != "[REDACTED]" || user["contract_id"] != "42" {
+		t.Errorf("user = %v", user)
+	}
+	if strings.Contains(buf.String(), "hunter2") || strings.Contains(buf.String(), "abc\"") {
+		t.Errorf("secret leaked: %s", buf.String())
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		level, format string
+		wantErr       bool
+	}{
+		{"", "", false},
+		{"debug", "text", false},
+		{"WARN", "JSON", false},
+		{"verbose", "text", true},
+		{"info", "xml", true},
+	}
+	for _, tt := range tests {
+		_, err := New(&bytes.Buffer{}, tt.level, tt.format)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("New(%q, %q) error = %v, wantErr %v", tt.level, tt.format, err, tt.wantErr)
+		}
+	}
+
+	var buf bytes.Buffer
+	logger, _ := New(&buf, "warn", "text")
+	logger.Info("hidden")
+	if buf.Len() != 0 {
+		t.Errorf("info logged at warn level: %s", buf.String())
+	}
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// redacted replaces values that must never reach the logs.
+const redacted = "[REDACTED]"
+
+// secretKeys name attributes whose values are dropped entirely.
+var secretKeys = map[string]bool{
+	"token":         true,
+	"suid":          true,
+	"session":       true,
+	"session_id":    true,
+	"password":      true,
+	"psw":           true,
+	"psw1":          true,
+	"psw2":          true,
+	"secret":        true,
+	"authorization": true,
+	"api_key":       true,
+	"otp":           true,
+	"arg1":          true,
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// phonePattern matches international numbers and the Russian formats
+	// written with 8 or +7 and optional separators.
+	phonePattern = regexp.MustCompile(`\+\d{10,15}\b|(?:\+7|\b8)[\s(-]*\d{3}[\s)-]*\d{3}[\s-]*\d{2}[\s-]*\d{2}\b`)
+	// secretPairPattern matches secrets inside JSON, query strings and
+	// key=value text, e.g. "suid":"..." or password=....
+	secretPairPattern = regexp.MustCompile(`(?i)("?(?:suid|token|session_id|password|psw[12]?|secret|api_key)"?\s*[:=]\s*"?)([^"&\s,}]+)`)
+	bearerPattern     = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+)
+
+// RedactString masks emails, phone numbers and secret key/value pairs in s.
+func RedactString(s string) string {
+	s = secretPairPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = bearerPattern.ReplaceAllString(s, "${1}"+redacted)
+	s = emailPattern.ReplaceAllStringFunc(s, MaskEmail)
+	s = phonePattern.ReplaceAllStringFunc(s, MaskPhone)
+	return s
+}
+
+// MaskEmail keeps the first letter and the domain: j***@example.com.
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return redacted
+	}
+	return local[:1] + "***@" + domain
+}
+
+// MaskPhone keeps the last two digits: ***67.
+func MaskPhone(phone string) string {
+	var digits []byte
+	for i := 0; i < len(phone); i++ {
+		if phone[i] >= '0' && phone[i] <= '9' {
+			digits = append(digits, phone[i])
+		}
+	}
+	if len(digits) < 4 {
+		return redacted
+	}
+	return "***" + string(digits[len(digits)-2:])
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	key := strings.ToLower(a.Key)
+	switch {
+	case secretKeys[key]:
+		return slog.String(a.Key, redacted)
+	case key == "email":
+		return slog.String(a.Key, MaskEmail(a.Value.String()))
+	case key == "phone":
+		return slog.String(a.Key, MaskPhone(a.Value.String()))
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindGroup:
+		attrs := a.Value.Group()
+		out := make([]slog.Attr, len(attrs))
+		for i, attr := range attrs {
+			out[i] = redactAttr(attr)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(out...)}
+	case slog.KindAny:
+		// Errors and other values are logged through their text, which
+		// may carry request details or personal data.
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+		return slog.String(a.Key, RedactString(fmt.Sprint(a.Value.Any())))
+	}
+	return a
+}
@@ -0,0 +1,132 @@
+// Package metrics holds the Prometheus metrics of the service and the
+// handler that exposes them.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "spektr"
+
+// Registry collects every metric below plus the Go runtime and process ones.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time to serve HTTP requests, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	upstreamCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "billing_calls_total",
+		Help:      "Calls to the billing API, by web_cabinet method and outcome.",
+	}, []string{"method", "outcome"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "billing_call_duration_seconds",
+		Help:      "Latency of billing API calls, by web_cabinet method and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "outcome"})
+
+	sessionsExpired = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "billing_sessions_expired_total",
+		Help:      "Billing responses reporting that the session has expired.",
+	})
+
+	loginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_failures_total",
+		Help:      "Failed sign-ins, by reason.",
+	}, []string{"reason"})
+
+	cacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Cache lookups, by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected by the rate limiter, by quota and client kind (ip, session or api_key).",
+	}, []string{"quota", "client"})
+)
+
+// Outcomes of a billing call.
+const (
+	OutcomeOK      = "ok"
+	OutcomeTimeout = "timeout"
+	// OutcomeNetworkError is a call that got no HTTP response.
+	OutcomeNetworkError = "network_error"
+	// OutcomeHTTPError is a response with a status other than 200.
+	OutcomeHTTPError = "http_error"
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration,
+		upstreamCalls, upstreamDuration,
+		sessionsExpired, loginFailures, cacheLookups,
+		rateLimited,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveRequest records a served HTTP request.
+func ObserveRequest(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// ObserveBillingCall records a call to a web_cabinet method.
+func ObserveBillingCall(method, outcome string, d time.Duration) {
+	upstreamCalls.WithLabelValues(method, outcome).Inc()
+	upstreamDuration.WithLabelValues(method, outcome).Observe(d.Seconds())
+}
+
+// SessionExpired counts a billing session found expired.
+func SessionExpired() {
+	sessionsExpired.Inc()
+}
+
+// LoginFailed counts a failed sign-in.
+func LoginFailed(reason string) {
+	loginFailures.WithLabelValues(reason).Inc()
+}
+
+// CacheLookup counts a lookup in the named cache.
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// RateLimited counts a request rejected for exceeding quota.
+func RateLimited(quota, client string) {
+	rateLimited.WithLabelValues(quota, client).Inc()
+}
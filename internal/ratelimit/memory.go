@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepThreshold is the number of buckets above which full buckets are
+// dropped on the next request. A full bucket is the same as no bucket.
+const sweepThreshold = 10000
+
+// sweepInterval spaces sweeps out, so a flood of new clients while most
+// buckets are still draining costs one pass over them at most this often
+// rather than one per request.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps buckets in process memory, so every instance limits
+// clients on its own.
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+func (s *MemoryStore) Take(_ context.Context, key string, limit Limit) (Result, error) {
+	if limit.Unlimited() {
+		return Result{Allowed: true}, nil
+	}
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		if len(s.buckets) >= sweepThreshold && now.Sub(s.lastSweep) >= sweepInterval {
+			s.sweep(now)
+		}
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	var res Result
+	b.tokens, res = take(b.tokens, now.Sub(b.last), limit)
+	b.last = now
+	b.limit = limit
+	return res, nil
+}
+
+// sweep drops the buckets that have refilled since their last use.
+func (s *MemoryStore) sweep(now time.Time) {
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Rate: 2, Burst: 3}
+
+	steps := []struct {
+		name          string
+		advance       time.Duration
+		key           string
+		wantAllowed   bool
+		wantRemaining int
+		wantRetry     time.Duration
+		wantReset     time.Duration
+	}{
+		{"first", 0, "a", true, 2, 0, 500 * time.Millisecond},
+		{"second", 0, "a", true, 1, 0, time.Second},
+		{"burst used up", 0, "a", true, 0, 0, 1500 * time.Millisecond},
+		{"denied", 0, "a", false, 0, 500 * time.Millisecond, 1500 * time.Millisecond},
+		{"other client", 0, "b", true, 2, 0, 500 * time.Millisecond},
+		{"half refilled", 250 * time.Millisecond, "a", false, 0, 250 * time.Millisecond, 1250 * time.Millisecond},
+		{"refilled a token", 250 * time.Millisecond, "a", true, 0, 0, 1500 * time.Millisecond},
+		{"refilled fully", time.Hour, "a", true, 2, 0, 500 * time.Millisecond},
+	}
+	for _, step := range steps {
+		now = now.Add(step.advance)
+		res, err := s.Take(ctx, step.key, limit)
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		want := Result{Allowed: step.wantAllowed, Limit: 3, Remaining: step.wantRemaining, RetryAfter: step.wantRetry, Reset: step.wantReset}
+		if res != want {
+			t.Errorf("%s: Take = %+v, want %+v", step.name, res, want)
+		}
+	}
+
+	if res, _ := s.Take(ctx, "a", Limit{}); !res.Allowed {
+		t.Error("unlimited request denied")
+	}
+}
+
+func TestMemoryStoreSweep(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := Limit{Rate: 1, Burst: 1}
+
+	for i := 0; i < sweepThreshold; i++ {
+		s.Take(ctx, fmt.Sprint(i), limit)
+	}
+	now = now.Add(time.Second)
+	// Keep one bucket empty; the others have refilled.
+	s.Take(ctx, "0", limit)
+	s.Take(ctx, "new", limit)
+	if len(s.buckets) != 2 {
+		t.Errorf("%d buckets after sweep, want 2", len(s.buckets))
+	}
+	if res, _ := s.Take(ctx, "0", limit); res.Allowed {
+		t.Error("sweep dropped a bucket in use")
+	}
+
+	// While clients keep their buckets drained, new ones don't trigger a
+	// sweep on every request.
+	for i := 0; i < sweepThreshold; i++ {
+		s.Take(ctx, fmt.Sprint("busy", i), limit)
+	}
+	now = now.Add(time.Second)
+	s.Take(ctx, "late", limit)
+	if len(s.buckets) != sweepThreshold+3 {
+		t.Errorf("%d buckets, want no sweep within %v of the last", len(s.buckets), sweepInterval)
+	}
+	now = now.Add(sweepInterval)
+	s.Take(ctx, "later", limit)
+	// Everything else has refilled by then.
+	if len(s.buckets) != 1 {
+		t.Errorf("%d buckets after the next sweep, want 1", len(s.buckets))
+	}
+}
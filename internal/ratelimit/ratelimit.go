@@ -0,0 +1,65 @@
+// Package ratelimit throttles clients with token buckets. A bucket holds up
+// to Burst tokens and refills at Rate tokens per second; every request takes
+// one. Buckets live in a Store, in process memory or shared between
+// instances.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Limit is the quota of one bucket. A zero Rate means no limit.
+type Limit struct {
+	// Rate is the number of requests per second a client may sustain.
+	Rate float64
+	// Burst is the number of requests a client may make at once.
+	Burst int
+}
+
+// Unlimited reports whether the limit lets every request through.
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// Result is the state of a bucket after a request tried to take a token.
+type Result struct {
+	Allowed bool
+	Limit   int
+	// Remaining is the number of whole tokens left.
+	Remaining int
+	// RetryAfter is how long until the next token, when not allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps the buckets. An implementation shared between instances,
+// e.g. on Redis, must take tokens atomically.
+type Store interface {
+	// Take takes a token from the bucket key, which refills at limit.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// take is the token bucket arithmetic: it refills a bucket holding tokens
+// for elapsed and takes one token if there is one.
+func take(tokens float64, elapsed time.Duration, limit Limit) (float64, Result) {
+	burst := float64(limit.Burst)
+	tokens = math.Min(burst, tokens+elapsed.Seconds()*limit.Rate)
+
+	res := Result{Limit: limit.Burst}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	res.Remaining = int(tokens)
+	res.Reset = seconds((burst - tokens) / limit.Rate)
+	return tokens, res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"log/slog"
+	"net/http"
+)
+
+// AddonRepository manages add-on services through the billing API.
+type AddonRepository struct {
+	client  *http.Client
+	baseURL string
+	logger  *slog.Logger
+}
+
+// NewAddonRepository creates a new AddonRepository instance.
+func NewAddonRepository(client *http.Client, baseURL string, logger *slog.Logger) *AddonRepository {
+	return &AddonRepository{
+		client:  client,
+		baseURL: baseURL,
+		logger:  logger,
+	}
+}
+
+// apiAddon is a service as returned by web_cabinet.get_services.
+type apiAddon struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Type        string `json:"type"`
+	Price       string `json:"price"`
+}
+
+// Addons fetches the connected and available add-on services.
+func (a *AddonRepository) Addons(ctx context.Context, suid string) ([]domain.Addon, error) {
+	a.logger.DebugContext(ctx, "Fetching add-ons")
+
+	arg1 := struct {
+		SUID string `json:"suid"`
+	}{SUID: suid}
+
+	body, err := sendRequest(ctx, a.logger, a.client, a.baseURL, "web_cabinet.get_services", arg1)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResponse struct {
+		Error     string     `json:"error"`
+		Connected []apiAddon `json:"connected"`
+		Available []apiAddon `json:"available"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return nil, err
+	}
+
+	addons := make([]domain.Addon, 0, len(apiResponse.Connected)+len(apiResponse.Available))
+	for _, group := range []struct {
+		items     []apiAddon
+		connected bool
+	}{{apiResponse.Connected, true}, {apiResponse.Available, false}} {
+		for _, item := range group.items {
+			price, err := parseBillingAmount(item.Price)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse add-on price: %w", err)
+			}
+			addons = append(addons, domain.Addon{
+				ID:           item.ID,
+				Name:         item.Name,
+				Description:  item.Description,
+				Category:     item.Type,
+				MonthlyPrice: price,
+				Connected:    group.connected,
+			})
+		}
+	}
+	return addons, nil
+}
+
+// SetAddon connects or disconnects an add-on service.
+func (a *AddonRepository) SetAddon(ctx context.Context, suid, addonID string, enable bool) error {
+	a.logger.InfoContext(ctx, "Setting add-on", "addon_id", addonID, "enable", enable)
+
+	state := "0"
+	if enable {
+		state = "1"
+	}
+	arg1 := struct {
+		SUID      string `json:"suid"`
+		ServiceID string `json:"service_id"`
+		State     string `json:"state"`
+	}{SUID: suid, ServiceID: addonID, State: state}
+
+	body, err := sendRequest(ctx, a.logger, a.client, a.baseURL, "web_cabinet.set_service", arg1)
+	if err != nil {
+		return err
+	}
+
+	var apiResponse struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return apiError(apiResponse.Error)
+}
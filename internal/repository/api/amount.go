@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"strings"
+)
+
+// amountLabels are the captions the billing puts in front of an amount.
+var amountLabels = []string{"баланс", "остаток", "balance"}
+
+// amountCurrencies are the currency marks the billing puts after an amount.
+var amountCurrencies = []string{"руб.", "руб", "р.", "₽", "rub"}
+
+// billingAmount is an amount the billing sends either as a JSON number or
+// as a string. It is kept as text for parseBillingAmount, so a malformed
+// amount doesn't fail decoding the whole response.
+type billingAmount string
+
+func (a *billingAmount) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*a = billingAmount(s)
+		return nil
+	}
+	*a = billingAmount(data)
+	return nil
+}
+
+// parseBillingAmount parses a monetary string as the billing formats it,
+// e.g. "Баланс: -1 234,56 руб.". It accepts a known caption, a plus or minus
+// sign, spaces between thousands, a dot or a comma before at most two
+// kopeck digits and a rouble mark. Anything it can't read unambiguously,
+// such as "1,234" or "1.234,5", is an error rather than a guess.
+func parseBillingAmount(s string) (domain.Money, error) {
+	rest := strings.TrimSpace(s)
+
+	if label, value, ok := strings.Cut(rest, ":"); ok {
+		if !isAmountLabel(label) {
+			return domain.Money{}, fmt.Errorf("unknown amount caption in %q", s)
+		}
+		rest = strings.TrimSpace(value)
+	}
+
+	lower := strings.ToLower(rest)
+	for _, currency := range amountCurrencies {
+		if strings.HasSuffix(lower, currency) {
+			rest = strings.TrimSpace(rest[:len(rest)-len(currency)])
+			break
+		}
+	}
+
+	sign := ""
+	switch {
+	case strings.HasPrefix(rest, "-"):
+		sign, rest = "-", rest[1:]
+	case strings.HasPrefix(rest, "−"): // U+2212 minus sign
+		sign, rest = "-", rest[len("−"):]
+	case strings.HasPrefix(rest, "+"):
+		rest = rest[1:]
+	}
+
+	whole, frac, err := splitAmount(rest)
+	if err != nil {
+		return domain.Money{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	money, err := domain.ParseMoney(sign+whole+"."+frac, domain.CurrencyRUB)
+	if err != nil {
+		return domain.Money{}, fmt.Errorf("invalid amount %q: %w", s, err)
+	}
+	return money, nil
+}
+
+// splitAmount splits an unsigned amount into its whole digits without group
+// separators and up to two fractional digits.
+func splitAmount(s string) (whole, frac string, err error) {
+	if s == "" {
+		return "", "", fmt.Errorf("no digits")
+	}
+	if strings.Contains(s, ".") && strings.Contains(s, ",") {
+		return "", "", fmt.Errorf("both dot and comma")
+	}
+
+	intPart, fracPart, hasFrac := strings.Cut(strings.ReplaceAll(s, ",", "."), ".")
+	if hasFrac {
+		if len(fracPart) == 0 || len(fracPart) > 2 || !allDigits(fracPart) {
+			// Three digits after the separator would be a thousands group,
+			// not kopecks; refuse rather than guess.
+			return "", "", fmt.Errorf("bad fractional part %q", fracPart)
+		}
+	}
+
+	groups := strings.FieldsFunc(intPart, isGroupSeparator)
+	if len(groups) == 0 || strings.TrimFunc(intPart, isGroupSeparator) != intPart {
+		return "", "", fmt.Errorf("bad whole part %q", intPart)
+	}
+	for i, group := range groups {
+		if !allDigits(group) {
+			return "", "", fmt.Errorf("bad whole part %q", intPart)
+		}
+		// Only the leading group may be shorter than three digits.
+		if i > 0 && len(group) != 3 || i == 0 && len(groups) > 1 && len(group) > 3 {
+			return "", "", fmt.Errorf("bad digit grouping in %q", intPart)
+		}
+	}
+	if len(groups) > 1 && !separatedOnce(intPart) {
+		return "", "", fmt.Errorf("bad digit grouping in %q", intPart)
+	}
+	return strings.Join(groups, ""), fracPart, nil
+}
+
+// separatedOnce reports whether groups are separated by exactly one separator.
+func separatedOnce(s string) bool {
+	prev := false
+	for _, r := range s {
+		sep := isGroupSeparator(r)
+		if sep && prev {
+			return false
+		}
+		prev = sep
+	}
+	return true
+}
+
+func isAmountLabel(label string) bool {
+	label = strings.ToLower(strings.TrimSpace(label))
+	for _, known := range amountLabels {
+		if label == known {
+			return true
+		}
+	}
+	return false
+}
+
+// isGroupSeparator matches the spaces used between thousands, including
+// the no-break and narrow no-break spaces.
+func isGroupSeparator(r rune) bool {
+	return r == ' ' || r == ' ' || r == ' '
+}
+
+func allDigits(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestParseBillingAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{"Баланс: 245.50 руб.", 24550, false},
+		{"Баланс: -120.50 руб.", -12050, false},
+		{"Баланс: 1 234,56 руб.", 123456, false},
+		{"Баланс: -1 234,56 ₽", -123456, false},
+		{"Баланс: −15 руб.", -1500, false},
+		{"Остаток: 12 руб", 1200, false},
+		{"Баланс:0.5", 50, false},
+		{"650", 65000, false},
+		{"499.90", 49990, false},
+		{"+10,1", 1010, false},
+		{"12 345 678,00", 1234567800, false},
+
+		{"", 0, true},
+		{"Баланс: ", 0, true},
+		{"Баланс: не определён", 0, true},
+		{"Долг: 150 руб.", 0, true},
+		{"1,234", 0, true},
+		{"1.234,56", 0, true},
+		{"12.345", 0, true},
+		{"1 23,00", 0, true},
+		{"1234 567", 0, true},
+		{"1  234", 0, true},
+		{" 12 руб. 50 коп.", 0, true},
+		{"--5", 0, true},
+		{"99999999999999999999", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseBillingAmount(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseBillingAmount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Minor != tt.want {
+				t.Errorf("parseBillingAmount() = %d, want %d", got.Minor, tt.want)
+			}
+		})
+	}
+}
+
+// FuzzParseBillingAmount checks that the parser never panics and that every
+// amount it accepts survives a round trip through its canonical form. The
+// seed corpus in testdata/fuzz holds strings seen from the billing.
+func FuzzParseBillingAmount(f *testing.F) {
+	f.Add("Баланс: 245.50 руб.")
+	f.Add("1 234,56")
+	f.Fuzz(func(t *testing.T, s string) {
+		money, err := parseBillingAmount(s)
+		if err != nil {
+			return
+		}
+		again, err := parseBillingAmount(money.String())
+		if err != nil {
+			t.Fatalf("canonical form %q of %q is rejected: %v", money.String(), s, err)
+		}
+		if again != money {
+			t.Fatalf("round trip of %q: %v != %v", s, again, money)
+		}
+	})
+}
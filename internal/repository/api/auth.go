@@ -6,55 +6,22 @@ import (
 	"errors"
 	"fmt"
 	"github.com/llchhh/spektr-account-api/domain"
-	"io/ioutil"
+	"log/slog"
 	"net/http"
-	"net/url"
 )
 
 type AuthRepository struct {
 	client  *http.Client
 	baseURL string
+	logger  *slog.Logger
 }
 
 func (a *AuthRepository) RequestPasswordResetToken(ctx context.Context, login string) error {
 	arg1 := fmt.Sprintf(`{"login":"%s", "base_url":"null"}`, login)
 
-	// Construct the query parameters in the exact order required
-	params := url.Values{}
-	params.Add("format", "json")
-	params.Add("context", "web")
-	params.Add("model", "users")
-	params.Add("method1", "web_cabinet.reset_password")
-	params.Add("arg1", arg1) // Add the JSON string for arg1
-
-	// Construct the full URL with the query parameters
-	requestURL := fmt.Sprintf("%s?%s", a.baseURL, params.Encode())
-
-	// Create a new HTTP GET request (even though you're sending data in the query, it's a GET request)
-	req, err := http.NewRequest("GET", requestURL, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set the context for the request
-	req = req.WithContext(ctx)
-
-	// Send the request using http.Client
-	resp, err := a.client.Do(req)
+	body, err := doRequest(ctx, a.logger, a.client, a.baseURL, "web_cabinet.reset_password", arg1)
 	if err != nil {
-		return fmt.Errorf("request error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check if the response status is 200 (OK)
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to authenticate, status code: %d", resp.StatusCode)
-	}
-
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return err
 	}
 
 	// Parse the response body into a predefined struct
@@ -73,42 +40,9 @@ func (a *AuthRepository) UpdatePassword(ctx context.Context, token, password str
 	// Create the payload with the token, user ID, and the new password
 	arg1 := fmt.Sprintf(`{"token":"%s", "uid":"324", "psw1":"%s", "psw2":"%s"}`, token, password, password)
 
-	// Construct the query parameters
-	params := url.Values{}
-	params.Add("format", "json")
-	params.Add("context", "web")
-	params.Add("model", "users")
-	params.Add("method1", "web_cabinet.submit_password")
-	params.Add("arg1", arg1) // Add the JSON string for arg1
-
-	// Construct the full URL with the query parameters
-	requestURL := fmt.Sprintf("%s?%s", a.baseURL, params.Encode())
-
-	// Create a new HTTP GET request (even though you're sending data in the query, it's a GET request)
-	req, err := http.NewRequest("GET", requestURL, nil)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set the context for the request
-	req = req.WithContext(ctx)
-
-	// Send the request using http.Client
-	resp, err := a.client.Do(req)
+	body, err := doRequest(ctx, a.logger, a.client, a.baseURL, "web_cabinet.submit_password", arg1)
 	if err != nil {
-		return fmt.Errorf("request error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check if the response status is 200 (OK)
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update password, status code: %d", resp.StatusCode)
-	}
-
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read response body: %w", err)
+		return err
 	}
 
 	// Parse the response body into a predefined struct
@@ -136,45 +70,11 @@ func (a *AuthRepository) Login(ctx context.Context, user domain.Auth) (string, e
 		return "", fmt.Errorf("failed to marshal user data: %w", err)
 	}
 
-	// Convert the JSON object into a string to use in the query parameter
-	arg1 := string(arg1JSON)
-
-	// Construct the query parameters in the exact order required
-	params := url.Values{}
-	params.Add("format", "json")
-	params.Add("context", "web")
-	params.Add("model", "users")
-	params.Add("method1", "web_cabinet.login")
-	params.Add("arg1", arg1) // Add the JSON string for arg1
-
-	// Construct the full URL with the query parameters
-	requestURL := fmt.Sprintf("%s?%s", a.baseURL, params.Encode())
-
-	// Create a new HTTP GET request (even though you're sending data in the query, it's a GET request)
-	req, err := http.NewRequest("GET", requestURL, nil)
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set the context for the request
-	req = req.WithContext(ctx)
-
-	// Send the request using http.Client
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("request error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check if the response status is 200 (OK)
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("failed to authenticate, status code: %d", resp.StatusCode)
-	}
-
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
+	// Signing in picks the login's default contract, so the call is not
+	// scoped to one.
+	body, err := doRequest(ctx, a.logger, a.client, a.baseURL, "web_cabinet.login", string(arg1JSON))
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %w", err)
+		return "", err
 	}
 
 	// Parse the response body into a predefined struct
@@ -192,9 +92,51 @@ func (a *AuthRepository) Login(ctx context.Context, user domain.Auth) (string, e
 	return result.SessionID, nil
 }
 
-func NewAuthRepository(baseURL string) *AuthRepository {
+func NewAuthRepository(client *http.Client, baseURL string, logger *slog.Logger) *AuthRepository {
 	return &AuthRepository{
-		client:  &http.Client{},
+		client:  client,
 		baseURL: baseURL,
+		logger:  logger,
+	}
+}
+
+// Contracts lists the contracts linked to the login of the session.
+func (a *AuthRepository) Contracts(ctx context.Context, suid string) ([]domain.Contract, error) {
+	arg1 := struct {
+		SUID string `json:"suid"`
+	}{SUID: suid}
+
+	body, err := sendRequest(ctx, a.logger, a.client, a.baseURL, "web_cabinet.get_contracts", arg1)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResponse struct {
+		Error     string `json:"error"`
+		Contracts []struct {
+			ID      string `json:"id"`
+			Number  string `json:"contract_number"`
+			Address string `json:"address"`
+			Tariff  string `json:"__tarif"`
+			Current string `json:"current"`
+		} `json:"contracts"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return nil, err
+	}
+
+	contracts := make([]domain.Contract, 0, len(apiResponse.Contracts))
+	for _, item := range apiResponse.Contracts {
+		contracts = append(contracts, domain.Contract{
+			ID:      item.ID,
+			Number:  item.Number,
+			Address: item.Address,
+			Tariff:  item.Tariff,
+			Current: item.Current == "1",
+		})
 	}
+	return contracts, nil
 }
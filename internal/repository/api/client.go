@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errNotAuthorized is the message the billing API returns for dead sessions.
+const errNotAuthorized = "Необходимо авторизоваться"
+
+// sendRequest sends a web_cabinet call to the billing API, scoped to the
+// contract of the principal, and returns the raw response body.
+func sendRequest(ctx context.Context, logger *slog.Logger, client *http.Client, baseURL, method string, arg1 interface{}) ([]byte, error) {
+	// Serialize arg1 into JSON
+	jsonData, err := json.Marshal(arg1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize arg1 to JSON: %w", err)
+	}
+	if jsonData, err = scopeToContract(ctx, jsonData); err != nil {
+		return nil, err
+	}
+	return doRequest(ctx, logger, client, baseURL, method, string(jsonData))
+}
+
+// doRequest sends a web_cabinet call with arg1 already encoded. Calls made
+// before there is a session to scope, such as signing in, use it directly.
+//
+// The request URL carries the session and sometimes a password in arg1, so
+// only the method is logged. The request ID and trace context are passed on
+// so the billing side of a call can be found, and the call is counted in
+// the billing metrics by method and outcome.
+func doRequest(ctx context.Context, logger *slog.Logger, client *http.Client, baseURL, method, arg1 string) ([]byte, error) {
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "billing "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("billing.method", method)),
+	)
+	defer span.End()
+
+	// Construct query parameters
+	params := url.Values{}
+	params.Add("format", "json")
+	params.Add("context", "web")
+	params.Add("model", "users")
+	params.Add("method1", method)
+	params.Add("arg1", arg1)
+
+	// Build the request URL
+	requestURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
+	logger.DebugContext(ctx, "Calling billing API", "method", method)
+
+	// Create the HTTP GET request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if id := logging.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(logging.RequestIDHeader, id)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	// Send the request
+	resp, err := client.Do(req)
+	if err != nil {
+		// url.Error quotes the full URL; keep only the cause.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		logger.WarnContext(ctx, "Billing API call failed", "method", method, "error", err)
+		tracing.Fail(span, err)
+		outcome := metrics.OutcomeNetworkError
+		if errors.Is(err, context.DeadlineExceeded) {
+			outcome = metrics.OutcomeTimeout
+		}
+		metrics.ObserveBillingCall(method, outcome, time.Since(start))
+		return nil, fmt.Errorf("request error: %w", err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	// Check response status code
+	if resp.StatusCode != http.StatusOK {
+		logger.WarnContext(ctx, "Billing API call failed", "method", method, "status", resp.StatusCode)
+		err := fmt.Errorf("failed request, status code: %d", resp.StatusCode)
+		tracing.Fail(span, err)
+		metrics.ObserveBillingCall(method, metrics.OutcomeHTTPError, time.Since(start))
+		return nil, err
+	}
+
+	// Read the response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		tracing.Fail(span, err)
+		metrics.ObserveBillingCall(method, metrics.OutcomeNetworkError, time.Since(start))
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	metrics.ObserveBillingCall(method, metrics.OutcomeOK, time.Since(start))
+
+	return body, nil
+}
+
+// scopeToContract adds the contract selected by the authenticated principal
+// to arg1, so every call works with that contract rather than the default
+// one of the login.
+func scopeToContract(ctx context.Context, arg1 []byte) ([]byte, error) {
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok || principal.ContractID == "" {
+		return arg1, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(arg1, &fields); err != nil {
+		return nil, fmt.Errorf("failed to scope arg1 to contract: %w", err)
+	}
+	contractID, err := json.Marshal(principal.ContractID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scope arg1 to contract: %w", err)
+	}
+	fields["contract_id"] = contractID
+	return json.Marshal(fields)
+}
+
+// apiError converts the error field of a billing response into an error.
+func apiError(message string) error {
+	switch message {
+	case "":
+		return nil
+	case errNotAuthorized:
+		metrics.SessionExpired()
+		return domain.ErrSessionExpired
+	default:
+		return errors.New("API error: " + message)
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSendRequestScopesToContract(t *testing.T) {
+	var got map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = nil
+		if err := json.Unmarshal([]byte(r.URL.Query().Get("arg1")), &got); err != nil {
+			t.Errorf("arg1 is not JSON: %v", err)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	arg1 := struct {
+		SUID string `json:"suid"`
+	}{SUID: "abc"}
+
+	if _, err := sendRequest(context.Background(), logging.Discard(), server.Client(), server.URL, "web_cabinet.get_user", arg1); err != nil {
+		t.Fatalf("sendRequest() error = %v", err)
+	}
+	if _, ok := got["contract_id"]; ok {
+		t.Errorf("arg1 = %v, want no contract without a principal", got)
+	}
+
+	ctx := domain.ContextWithPrincipal(context.Background(), domain.Principal{Token: "abc", ContractID: "42"})
+	if _, err := sendRequest(ctx, logging.Discard(), server.Client(), server.URL, "web_cabinet.get_user", arg1); err != nil {
+		t.Fatalf("sendRequest() error = %v", err)
+	}
+	if got["suid"] != "abc" || got["contract_id"] != "42" {
+		t.Errorf("arg1 = %v, want suid and contract_id 42", got)
+	}
+}
+
+func TestSendRequestForwardsRequestID(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	ctx = logging.ContextWithRequestID(ctx, "req-1")
+
+	if _, err := sendRequest(ctx, logging.Discard(), server.Client(), server.URL, "web_cabinet.get_user", struct{}{}); err != nil {
+		t.Fatalf("sendRequest() error = %v", err)
+	}
+	if got := header.Get("X-Request-ID"); got != "req-1" {
+		t.Errorf("X-Request-ID = %q, want req-1", got)
+	}
+	if got := header.Get("Traceparent"); !strings.Contains(got, traceID.String()) {
+		t.Errorf("traceparent = %q, want trace %s", got, traceID)
+	}
+}
+
+func TestDoRequestRecordsMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("method1") == "web_cabinet.metrics_fail" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	doRequest(ctx, logging.Discard(), server.Client(), server.URL, "web_cabinet.metrics_ok", "{}")
+	doRequest(ctx, logging.Discard(), server.Client(), server.URL, "web_cabinet.metrics_fail", "{}")
+	server.Close()
+	doRequest(ctx, logging.Discard(), server.Client(), server.URL, "web_cabinet.metrics_down", "{}")
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`spektr_billing_calls_total{method="web_cabinet.metrics_ok",outcome="ok"} 1`,
+		`spektr_billing_calls_total{method="web_cabinet.metrics_fail",outcome="http_error"} 1`,
+		`spektr_billing_calls_total{method="web_cabinet.metrics_down",outcome="network_error"} 1`,
+		`spektr_billing_call_duration_seconds_count{method="web_cabinet.metrics_ok",outcome="ok"} 1`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// pingMethod is called to check that the billing API answers. Without a
+// session it is refused straight away, which proves the API is up without
+// touching any account.
+const pingMethod = "web_cabinet.get_user"
+
+// HealthRepository checks that the billing API is reachable.
+type HealthRepository struct {
+	client  *http.Client
+	baseURL string
+	logger  *slog.Logger
+}
+
+// NewHealthRepository creates a HealthRepository for the billing API at baseURL.
+func NewHealthRepository(client *http.Client, baseURL string, logger *slog.Logger) *HealthRepository {
+	return &HealthRepository{
+		client:  client,
+		baseURL: baseURL,
+		logger:  logger,
+	}
+}
+
+// Ping makes a call the billing API refuses without a session and checks
+// that the answer is a billing response.
+func (h *HealthRepository) Ping(ctx context.Context) error {
+	body, err := doRequest(ctx, h.logger, h.client, h.baseURL, pingMethod, "{}")
+	if err != nil {
+		return err
+	}
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("unexpected billing API response: %w", err)
+	}
+	return nil
+}
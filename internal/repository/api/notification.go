@@ -6,10 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/llchhh/spektr-account-api/domain"
-	"io"
-	"log"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
+	"log/slog"
 	"net/http"
-	"net/url"
 )
 
 // Notification represents the API notification structure.
@@ -28,67 +27,24 @@ type APIResponse struct {
 type NotificationRepository struct {
 	client  *http.Client
 	baseURL string
+	logger  *slog.Logger
 }
 
 // GetNotifications fetches the notifications for the specified user.
 func (n *NotificationRepository) GetNotifications(ctx context.Context, suid string) ([]domain.Notification, error) {
-	log.Printf("Fetching notifications for user with suid: %s", suid)
+	n.logger.DebugContext(ctx, "Fetching notifications")
 
-	// Construct the payload as JSON
-	payload := map[string]string{
+	arg1 := map[string]string{
 		"suid": suid,
 	}
-	jsonPayload, err := json.Marshal(payload)
+	body, err := sendRequest(ctx, n.logger, n.client, n.baseURL, "web_cabinet.get_notifications_for_user", arg1)
 	if err != nil {
-		log.Printf("Failed to marshal payload: %v", err)
-		return nil, fmt.Errorf("failed to prepare request payload: %w", err)
-	}
-
-	// Construct query parameters
-	params := url.Values{
-		"format":  {"json"},
-		"context": {"web"},
-		"model":   {"users"},
-		"method1": {"web_cabinet.get_notifications_for_user"},
-		"arg1":    {string(jsonPayload)},
-	}
-
-	// Build the request URL
-	requestURL := fmt.Sprintf("%s?%s", n.baseURL, params.Encode())
-	log.Printf("Request URL: %s", requestURL)
-
-	// Create the HTTP GET request
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
-	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Execute the HTTP request
-	resp, err := n.client.Do(req)
-	if err != nil {
-		log.Printf("Request error: %v", err)
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Validate response status
-	if resp.StatusCode != http.StatusOK {
-		log.Printf("Non-OK HTTP status: %d", resp.StatusCode)
-		return nil, fmt.Errorf("unexpected response status: %d", resp.StatusCode)
-	}
-
-	// Parse the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Printf("Failed to read response body: %v", err)
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
 	// Use json.RawMessage to handle dynamic response
 	var rawMessage json.RawMessage
 	if err := json.Unmarshal(body, &rawMessage); err != nil {
-		log.Printf("Failed to parse raw response: %v", err)
 		return nil, fmt.Errorf("failed to parse API response: %w", err)
 	}
 
@@ -97,13 +53,11 @@ func (n *NotificationRepository) GetNotifications(ctx context.Context, suid stri
 	if err := json.Unmarshal(rawMessage, &apiResponse); err == nil {
 		// Handle error field
 		if apiResponse.Error != "" {
-			log.Printf("API returned an error: %s", apiResponse.Error)
-			switch apiResponse.Error {
-			case "Необходимо авторизоваться":
+			if apiResponse.Error == errNotAuthorized {
+				metrics.SessionExpired()
 				return nil, domain.ErrSessionExpired
-			default:
-				return nil, errors.New(apiResponse.Error)
 			}
+			return nil, errors.New(apiResponse.Error)
 		}
 		// Convert API notifications to domain notifications
 		notifications := make([]domain.Notification, len(apiResponse.Notifications))
@@ -113,7 +67,7 @@ func (n *NotificationRepository) GetNotifications(ctx context.Context, suid stri
 				Type: apiNotification.Type,
 			}
 		}
-		log.Printf("Fetched %d notifications successfully", len(notifications))
+		n.logger.DebugContext(ctx, "Fetched notifications", "count", len(notifications))
 		return notifications, nil
 	}
 
@@ -128,19 +82,19 @@ func (n *NotificationRepository) GetNotifications(ctx context.Context, suid stri
 				Type: apiNotification.Type,
 			}
 		}
-		log.Printf("Fetched %d notifications successfully from array response", len(notifications))
+		n.logger.DebugContext(ctx, "Fetched notifications from array response", "count", len(notifications))
 		return notifications, nil
 	}
 
 	// If all attempts fail, return an error
-	log.Printf("Failed to parse API response: %v", err)
 	return nil, fmt.Errorf("failed to parse API response: %w", err)
 }
 
 // NewNotificationRepository creates a new instance of NotificationRepository.
-func NewNotificationRepository(baseURL string) *NotificationRepository {
+func NewNotificationRepository(client *http.Client, baseURL string, logger *slog.Logger) *NotificationRepository {
 	return &NotificationRepository{
-		client:  &http.Client{},
+		client:  client,
 		baseURL: baseURL,
+		logger:  logger,
 	}
 }
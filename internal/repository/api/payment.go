@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// billingDateLayout is the timestamp format used by the billing API.
+const billingDateLayout = "2006-01-02 15:04:05"
+
+// PaymentRepository fetches the payment history from the billing API.
+type PaymentRepository struct {
+	client  *http.Client
+	baseURL string
+	logger  *slog.Logger
+}
+
+// NewPaymentRepository creates a new PaymentRepository instance.
+func NewPaymentRepository(client *http.Client, baseURL string, logger *slog.Logger) *PaymentRepository {
+	return &PaymentRepository{
+		client:  client,
+		baseURL: baseURL,
+		logger:  logger,
+	}
+}
+
+// apiPayment is a single operation as returned by web_cabinet.get_payments.
+type apiPayment struct {
+	Sum          string `json:"sum"`
+	Date         string `json:"date"`
+	PayType      string `json:"pay_type"`
+	BalanceAfter string `json:"balance_after"`
+}
+
+// Payments fetches the payments made between from and to, newest first.
+func (p *PaymentRepository) Payments(ctx context.Context, suid string, from, to time.Time) ([]domain.Payment, error) {
+	p.logger.DebugContext(ctx, "Fetching payments", "from", from, "to", to)
+
+	arg1 := struct {
+		SUID     string `json:"suid"`
+		DateFrom string `json:"date_from"`
+		DateTo   string `json:"date_to"`
+	}{
+		SUID:     suid,
+		DateFrom: from.Format(time.DateOnly),
+		DateTo:   to.Format(time.DateOnly),
+	}
+
+	body, err := sendRequest(ctx, p.logger, p.client, p.baseURL, "web_cabinet.get_payments", arg1)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResponse struct {
+		Error    string       `json:"error"`
+		Payments []apiPayment `json:"payments"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return nil, err
+	}
+
+	payments := make([]domain.Payment, 0, len(apiResponse.Payments))
+	for _, item := range apiResponse.Payments {
+		payment, err := mapPayment(item)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+
+	p.logger.DebugContext(ctx, "Fetched payments", "count", len(payments))
+	return payments, nil
+}
+
+func mapPayment(item apiPayment) (domain.Payment, error) {
+	date, err := time.ParseInLocation(billingDateLayout, item.Date, time.Local)
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("failed to parse payment date %q: %w", item.Date, err)
+	}
+	amount, err := parseBillingAmount(item.Sum)
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("failed to parse payment sum: %w", err)
+	}
+	balance, err := parseBillingAmount(item.BalanceAfter)
+	if err != nil {
+		return domain.Payment{}, fmt.Errorf("failed to parse balance: %w", err)
+	}
+	return domain.Payment{
+		Amount:  amount,
+		Date:    date,
+		Method:  item.PayType,
+		Balance: balance,
+	}, nil
+}
+
+// CreditPayment records an online payment on the contract. The transaction
+// ID lets the billing API reject a duplicate credit for the same payment.
+func (p *PaymentRepository) CreditPayment(ctx context.Context, contractID string, amount domain.Money, transactionID string) error {
+	p.logger.InfoContext(ctx, "Crediting payment", "transaction_id", transactionID, "contract_id", contractID)
+
+	arg1 := struct {
+		ContractNumber string `json:"contract_number"`
+		Sum            string `json:"sum"`
+		TransactionID  string `json:"transaction_id"`
+	}{
+		ContractNumber: contractID,
+		Sum:            amount.String(),
+		TransactionID:  transactionID,
+	}
+
+	body, err := sendRequest(ctx, p.logger, p.client, p.baseURL, "web_cabinet.add_payment", arg1)
+	if err != nil {
+		return err
+	}
+
+	var apiResponse struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return apiError(apiResponse.Error)
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"github.com/llchhh/spektr-account-api/domain"
+	"testing"
+)
+
+func TestMapPayment(t *testing.T) {
+	tests := []struct {
+		name        string
+		item        apiPayment
+		wantAmount  domain.Money
+		wantBalance domain.Money
+		wantErr     bool
+	}{
+		{"plain", apiPayment{Sum: "650.00", BalanceAfter: "895.50", Date: "2024-05-01 10:00:00"}, domain.RUB(65000), domain.RUB(89550), false},
+		{"localised", apiPayment{Sum: "1 234,56", BalanceAfter: "-15,00 руб.", Date: "2024-05-01 10:00:00"}, domain.RUB(123456), domain.RUB(-1500), false},
+		{"ambiguous sum", apiPayment{Sum: "1,234", BalanceAfter: "0", Date: "2024-05-01 10:00:00"}, domain.Money{}, domain.Money{}, true},
+		{"bad date", apiPayment{Sum: "650", BalanceAfter: "0", Date: "01.05.2024"}, domain.Money{}, domain.Money{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mapPayment(tt.item)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("mapPayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got.Amount != tt.wantAmount || got.Balance != tt.wantBalance {
+				t.Errorf("mapPayment() = %+v, want amount %v and balance %v", got, tt.wantAmount, tt.wantBalance)
+			}
+		})
+	}
+}
@@ -5,79 +5,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/llchhh/spektr-account-api/domain"
-	"io/ioutil"
-	"log"
+	"log/slog"
 	"net/http"
-	"net/url"
-	"regexp"
-	"strconv"
 	"strings"
+	"time"
 )
 
 // ProfileRepository provides methods for profile management.
 type ProfileRepository struct {
 	client  *http.Client
 	baseURL string
+	logger  *slog.Logger
 }
 
 // NewProfileRepository creates a new ProfileRepository instance.
-func NewProfileRepository(baseURL string) *ProfileRepository {
+func NewProfileRepository(client *http.Client, baseURL string, logger *slog.Logger) *ProfileRepository {
 	return &ProfileRepository{
-		client:  &http.Client{},
+		client:  client,
 		baseURL: baseURL,
+		logger:  logger,
 	}
 }
 
 // sendRequest sends a GET request to the API with the provided parameters and decodes the response.
 func (p *ProfileRepository) sendRequest(ctx context.Context, method string, arg1 interface{}) ([]byte, error) {
-	// Serialize arg1 into JSON
-	jsonData, err := json.Marshal(arg1)
-	if err != nil {
-		return nil, fmt.Errorf("failed to serialize arg1 to JSON: %w", err)
-	}
-
-	// Construct query parameters
-	params := url.Values{}
-	params.Add("format", "json")
-	params.Add("context", "web")
-	params.Add("model", "users")
-	params.Add("method1", method)
-	params.Add("arg1", string(jsonData))
-
-	// Build the request URL
-	requestURL := fmt.Sprintf("%s?%s", p.baseURL, params.Encode())
-	log.Printf("Request URL: %s", requestURL)
-
-	// Create the HTTP GET request
-	req, err := http.NewRequestWithContext(ctx, "GET", requestURL, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Send the request
-	resp, err := p.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request error: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Check response status code
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed request, status code: %d", resp.StatusCode)
-	}
-
-	// Read the response body
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
-	return body, nil
+	return sendRequest(ctx, p.logger, p.client, p.baseURL, method, arg1)
 }
 
 // Profile fetches the profile data for a user.
 func (p *ProfileRepository) Profile(ctx context.Context, suid string) (domain.Profile, error) {
-	log.Printf("Fetching profile for user with suid: %s", suid)
+	p.logger.DebugContext(ctx, "Fetching profile")
 
 	arg1 := struct {
 		SUID string `json:"suid"`
@@ -88,20 +45,10 @@ func (p *ProfileRepository) Profile(ctx context.Context, suid string) (domain.Pr
 		return domain.Profile{}, err
 	}
 
-	// Define the API response structure
 	var apiResponse struct {
 		Error string `json:"error"`
 		User  struct {
-			Abonent struct {
-				Name          string      `json:"name"`
-				Tariff        string      `json:"__tarif"`
-				Balance       string      `json:"__account"`
-				MinimalPaySum json.Number `json:"minimal_pay_sum"`
-				Email         string      `json:"email"`
-				Phone         string      `json:"sms"`
-				AllowInternet string      `json:"allow_internet"`
-				ID            string      `json:"contract_number"`
-			} `json:"abonent"`
+			Abonent apiAbonent `json:"abonent"`
 		} `json:"user"`
 	}
 
@@ -110,32 +57,85 @@ func (p *ProfileRepository) Profile(ctx context.Context, suid string) (domain.Pr
 	}
 
 	// Handle errors in the response
-	if apiResponse.Error != "" {
-		return domain.Profile{}, fmt.Errorf("API error: %s", apiResponse.Error)
+	if err := apiError(apiResponse.Error); err != nil {
+		return domain.Profile{}, err
+	}
 
+	abonent := apiResponse.User.Abonent
+	profile, err := mapProfile(abonent)
+	if err != nil {
+		return domain.Profile{}, err
+	}
+	if profile.Balance == nil {
+		p.logger.WarnContext(ctx, "Unrecognised balance from billing", "balance", abonent.Balance)
+	}
+	if profile.ToPay == nil {
+		p.logger.WarnContext(ctx, "Unrecognised minimal pay sum from billing", "minimal_pay_sum", abonent.MinimalPaySum)
+	}
+	return profile, nil
+}
+
+// apiAbonent is the subscriber as returned by web_cabinet.get_user.
+type apiAbonent struct {
+	Name           string        `json:"name"`
+	Tariff         string        `json:"__tarif"`
+	TariffID       string        `json:"tarif_id"`
+	Balance        string        `json:"__account"`
+	MinimalPaySum  billingAmount `json:"minimal_pay_sum"`
+	Email          string        `json:"email"`
+	Phone          string        `json:"sms"`
+	AllowInternet  string        `json:"allow_internet"`
+	ContractNumber string        `json:"contract_number"`
+	AccountNumber  string        `json:"personal_account"`
+	ContractDate   string        `json:"contract_date"`
+	NextPayDate    string        `json:"next_pay_date"`
+	Address        string        `json:"address"`
+}
+
+// mapProfile maps a billing subscriber to domain.Profile.
+func mapProfile(abonent apiAbonent) (domain.Profile, error) {
+	// A subscriber without a minimal pay sum owes nothing.
+	toPay := &domain.Money{Currency: domain.CurrencyRUB}
+	if abonent.MinimalPaySum != "" {
+		toPay = parseBalance(string(abonent.MinimalPaySum))
+	}
+
+	contractDate, err := parseBillingDay(abonent.ContractDate)
+	if err != nil {
+		return domain.Profile{}, fmt.Errorf("failed to parse contract date %q: %w", abonent.ContractDate, err)
+	}
+	// Installations that don't report the next charge leave it unknown.
+	nextPayDate, err := parseBillingDay(abonent.NextPayDate)
+	if err != nil {
+		return domain.Profile{}, fmt.Errorf("failed to parse next pay date %q: %w", abonent.NextPayDate, err)
 	}
 
-	// Map API response to domain.Profile
-	minimalPaySum, _ := apiResponse.User.Abonent.MinimalPaySum.Float64()
-	firstName, middleName, lastName := parseFullName(apiResponse.User.Abonent.Name)
+	lastName, firstName, middleName := parseFullName(abonent.Name)
 
 	return domain.Profile{
-		FirstName:      middleName,
-		MiddleName:     lastName,
-		LastName:       firstName,
-		Tariff:         apiResponse.User.Abonent.Tariff,
-		Balance:        parseBalance(apiResponse.User.Abonent.Balance),
-		ToPay:          minimalPaySum,
-		Email:          apiResponse.User.Abonent.Email,
-		Phone:          apiResponse.User.Abonent.Phone,
-		InternetStatus: parseInternetStatus(apiResponse.User.Abonent.AllowInternet),
-		ID:             apiResponse.User.Abonent.ID,
+		ID:             abonent.ContractNumber,
+		ContractNumber: abonent.ContractNumber,
+		AccountNumber:  abonent.AccountNumber,
+		ContractDate:   contractDate,
+		Address:        strings.TrimSpace(abonent.Address),
+		FirstName:      firstName,
+		MiddleName:     middleName,
+		LastName:       lastName,
+		FullName:       strings.Join(strings.Fields(abonent.Name), " "),
+		Tariff:         abonent.Tariff,
+		TariffID:       abonent.TariffID,
+		Balance:        parseBalance(abonent.Balance),
+		ToPay:          toPay,
+		Email:          abonent.Email,
+		Phone:          abonent.Phone,
+		InternetStatus: parseInternetStatus(abonent.AllowInternet),
+		NextPayDate:    nextPayDate,
 	}, nil
 }
 
 // ChangePassword changes the password for a user.
 func (p *ProfileRepository) ChangePassword(ctx context.Context, suid, newPassword string) error {
-	log.Printf("Changing password for user with suid: %s", suid)
+	p.logger.InfoContext(ctx, "Changing password")
 
 	arg1 := struct {
 		SUID         string `json:"suid"`
@@ -153,13 +153,11 @@ func (p *ProfileRepository) ChangePassword(ctx context.Context, suid, newPasswor
 
 	// If the response body is empty, we can handle it as a special case
 	if len(body) == 0 {
-		log.Printf("Empty response body received")
 		return fmt.Errorf("received empty response body")
 	}
 
 	// Parse the API response
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		log.Printf("Failed to parse API response: %v", err)
 		return fmt.Errorf("failed to parse API response: %w", err)
 	}
 
@@ -168,113 +166,86 @@ func (p *ProfileRepository) ChangePassword(ctx context.Context, suid, newPasswor
 		return fmt.Errorf("API error: %s", apiResponse.Error)
 	}
 
-	log.Printf("Password change successful for user with suid: %s", suid)
+	p.logger.InfoContext(ctx, "Password changed")
 	return nil
 }
 
-// ChangePhone changes the phone number for a user.
-func (p *ProfileRepository) ChangePhone(ctx context.Context, suid, newPhone string) error {
-	log.Printf("Changing phone number for user with suid: %s", suid)
+// UpdateUserInfo changes the contact details present in update with a
+// single set_user_info call.
+func (p *ProfileRepository) UpdateUserInfo(ctx context.Context, suid string, update domain.ProfileUpdate) error {
+	p.logger.InfoContext(ctx, "Updating user info", "email", update.Email != nil, "phone", update.Phone != nil)
 
 	arg1 := struct {
-		SUID string `json:"suid"`
-		SMS  string `json:"sms"`
-	}{SUID: suid, SMS: newPhone}
+		SUID  string  `json:"suid"`
+		Email *string `json:"email,omitempty"`
+		SMS   *string `json:"sms,omitempty"`
+	}{SUID: suid, Email: update.Email, SMS: update.Phone}
 
 	body, err := p.sendRequest(ctx, "web_cabinet.set_user_info", arg1)
 	if err != nil {
 		return err
 	}
-	// Define the API response structure
-	var apiResponse struct {
-		Error string `json:"error"`
-	}
-
-	// If the response body is empty, we can handle it as a special case
 	if len(body) == 0 {
-		log.Printf("Empty response body received")
 		return fmt.Errorf("received empty response body")
 	}
 
-	// Parse the API response
-	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		log.Printf("Failed to parse API response: %v", err)
-		return fmt.Errorf("failed to parse API response: %w", err)
-	}
-
-	// Handle errors in the response
-	if apiResponse.Error != "" {
-		return fmt.Errorf("API error: %s", apiResponse.Error)
-	}
-
-	log.Printf("Phone change successful for user with suid: %s", suid)
-	return nil
-}
-
-// ChangeEmail changes the email address for a user.
-func (p *ProfileRepository) ChangeEmail(ctx context.Context, suid, newEmail string) error {
-	log.Printf("Changing email for user with suid: %s", suid)
-
-	arg1 := struct {
-		SUID  string `json:"suid"`
-		Email string `json:"email"`
-	}{SUID: suid, Email: newEmail}
-
-	body, err := p.sendRequest(ctx, "web_cabinet.set_user_info", arg1)
-	if err != nil {
-		return err
-	}
-	// Define the API response structure
 	var apiResponse struct {
 		Error string `json:"error"`
 	}
-
-	// If the response body is empty, we can handle it as a special case
-	if len(body) == 0 {
-		log.Printf("Empty response body received")
-		return fmt.Errorf("received empty response body")
-	}
-
-	// Parse the API response
 	if err := json.Unmarshal(body, &apiResponse); err != nil {
-		log.Printf("Failed to parse API response: %v", err)
 		return fmt.Errorf("failed to parse API response: %w", err)
 	}
-
-	// Handle errors in the response
-	if apiResponse.Error != "" {
-		return fmt.Errorf("API error: %s", apiResponse.Error)
+	if err := apiError(apiResponse.Error); err != nil {
+		return err
 	}
 
-	log.Printf("Email change successful for user with suid: %s", suid)
+	p.logger.InfoContext(ctx, "User info updated")
 	return nil
 }
 
 // Helper functions
-func parseBalance(balanceStr string) float64 {
-	re := regexp.MustCompile(`Баланс:\s*([\d.]+)`)
-	matches := re.FindStringSubmatch(balanceStr)
-	if len(matches) > 1 {
-		balance, _ := strconv.ParseFloat(matches[1], 64)
-		return balance
+
+// parseBalance reads an amount of the subscriber, such as the balance
+// caption. An amount that can't be read is reported as unknown (nil) instead
+// of a made-up zero.
+func parseBalance(balanceStr string) *domain.Money {
+	balance, err := parseBillingAmount(balanceStr)
+	if err != nil {
+		return nil
 	}
-	return 0.0
+	return &balance
 }
 
 func parseInternetStatus(statusStr string) bool {
 	return statusStr == "1"
 }
 
-func parseFullName(fullName string) (string, string, string) {
+// parseFullName splits a name the billing stores in Russian order:
+// last name, first name, then the patronymic.
+func parseFullName(fullName string) (lastName, firstName, middleName string) {
 	nameParts := strings.Fields(fullName)
 	switch len(nameParts) {
+	case 0:
+		return "", "", ""
 	case 1:
 		return nameParts[0], "", ""
 	case 2:
-		return nameParts[0], "", nameParts[1]
-	case 3:
-		return nameParts[0], nameParts[1], nameParts[2]
+		return nameParts[0], nameParts[1], ""
 	default:
-		return nameParts[0], strings.Join(nameParts[1:len(nameParts)-1], " "), nameParts[len(nameParts)-1]
+		return nameParts[0], nameParts[1], strings.Join(nameParts[2:], " ")
+	}
+}
+
+// parseBillingDay reads a date of the billing as YYYY-MM-DD. Some
+// installations append a time to the date. An empty date stays empty.
+func parseBillingDay(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	day, _, _ := strings.Cut(value, " ")
+	date, err := time.Parse(time.DateOnly, day)
+	if err != nil {
+		return "", err
 	}
+	return date.Format(time.DateOnly), nil
 }
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/llchhh/spektr-account-api/domain"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMapProfile(t *testing.T) {
+	tests := []struct {
+		file string
+		want domain.Profile
+	}{
+		{
+			file: "get_user.json",
+			want: domain.Profile{
+				ID:             "S540100440",
+				ContractNumber: "S540100440",
+				AccountNumber:  "100440",
+				ContractDate:   "2019-03-15",
+				Address:        "г. Новосибирск, ул. Ленина, д. 10, кв. 25",
+				FirstName:      "Иван",
+				MiddleName:     "Сергеевич",
+				LastName:       "Петров",
+				FullName:       "Петров Иван Сергеевич",
+				Balance:        rub(24550),
+				ToPay:          rub(65000),
+				Tariff:         "Домашний 100",
+				TariffID:       "14",
+				Email:          "llchh@yahoo.com",
+				Phone:          "+79134773649",
+				InternetStatus: true,
+				NextPayDate:    "2024-06-01",
+			},
+		},
+		{
+			file: "get_user_debt.json",
+			want: domain.Profile{
+				ID:             "S540100440",
+				ContractNumber: "S540100440",
+				AccountNumber:  "100440",
+				ContractDate:   "2019-03-15",
+				Address:        "г. Новосибирск, ул. Ленина, д. 10, кв. 25",
+				FirstName:      "Иван",
+				MiddleName:     "Сергеевич",
+				LastName:       "Петров",
+				FullName:       "Петров Иван Сергеевич",
+				Balance:        rub(-123456),
+				ToPay:          rub(65000),
+				Tariff:         "Домашний 100",
+				TariffID:       "14",
+				Email:          "llchh@yahoo.com",
+				Phone:          "+79134773649",
+				InternetStatus: true,
+				NextPayDate:    "2024-06-01",
+			},
+		},
+		{
+			file: "get_user_unknown_balance.json",
+			want: domain.Profile{
+				ID:             "S540100440",
+				ContractNumber: "S540100440",
+				AccountNumber:  "100440",
+				ContractDate:   "2019-03-15",
+				Address:        "г. Новосибирск, ул. Ленина, д. 10, кв. 25",
+				FirstName:      "Иван",
+				MiddleName:     "Сергеевич",
+				LastName:       "Петров",
+				FullName:       "Петров Иван Сергеевич",
+				ToPay:          rub(65000),
+				Tariff:         "Домашний 100",
+				TariffID:       "14",
+				Email:          "llchh@yahoo.com",
+				Phone:          "+79134773649",
+				InternetStatus: true,
+				NextPayDate:    "2024-06-01",
+			},
+		},
+		{
+			file: "get_user_unknown_to_pay.json",
+			want: domain.Profile{
+				ID:             "S540100440",
+				ContractNumber: "S540100440",
+				AccountNumber:  "100440",
+				ContractDate:   "2019-03-15",
+				Address:        "г. Новосибирск, ул. Ленина, д. 10, кв. 25",
+				FirstName:      "Иван",
+				MiddleName:     "Сергеевич",
+				LastName:       "Петров",
+				FullName:       "Петров Иван Сергеевич",
+				Balance:        rub(24550),
+				Tariff:         "Домашний 100",
+				TariffID:       "14",
+				Email:          "llchh@yahoo.com",
+				Phone:          "+79134773649",
+				InternetStatus: true,
+				NextPayDate:    "2024-06-01",
+			},
+		},
+		{
+			file: "get_user_two_names.json",
+			want: domain.Profile{
+				ID:             "S540100512",
+				ContractNumber: "S540100512",
+				AccountNumber:  "100512",
+				ContractDate:   "2023-11-01",
+				Address:        "г. Бердск, ул. Ленина, д. 3",
+				FirstName:      "Анна",
+				LastName:       "Сидорова",
+				FullName:       "Сидорова Анна",
+				Balance:        rub(0),
+				ToPay:          rub(49990),
+				Tariff:         "Базовый",
+				TariffID:       "3",
+				Phone:          "+79130000000",
+				NextPayDate:    "2024-06-01",
+			},
+		},
+		{
+			file: "get_user_minimal.json",
+			want: domain.Profile{
+				ID:             "S540100777",
+				ContractNumber: "S540100777",
+				LastName:       "Козлов",
+				FullName:       "Козлов",
+				Balance:        rub(1200),
+				ToPay:          rub(0),
+				Tariff:         "Базовый",
+				TariffID:       "3",
+				InternetStatus: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var response struct {
+				User struct {
+					Abonent apiAbonent `json:"abonent"`
+				} `json:"user"`
+			}
+			if err := json.Unmarshal(data, &response); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := mapProfile(response.User.Abonent)
+			if err != nil {
+				t.Fatalf("mapProfile() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mapProfile() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func rub(minor int64) *domain.Money {
+	m := domain.RUB(minor)
+	return &m
+}
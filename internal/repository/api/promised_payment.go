@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"time"
+)
+
+// apiPromisedPayment is a promised payment as returned by the billing API.
+type apiPromisedPayment struct {
+	Sum      string `json:"sum"`
+	Date     string `json:"date"`
+	Deadline string `json:"deadline"`
+}
+
+// PromisedPayments fetches the active promised payment and the history of past ones.
+func (p *ProfileRepository) PromisedPayments(ctx context.Context, suid string) (domain.PromisedPaymentHistory, error) {
+	p.logger.DebugContext(ctx, "Fetching promised payments")
+
+	arg1 := struct {
+		SUID string `json:"suid"`
+	}{SUID: suid}
+
+	body, err := p.sendRequest(ctx, "web_cabinet.get_promised_payments", arg1)
+	if err != nil {
+		return domain.PromisedPaymentHistory{}, err
+	}
+
+	var apiResponse struct {
+		Error   string               `json:"error"`
+		Current *apiPromisedPayment  `json:"current"`
+		History []apiPromisedPayment `json:"history"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return domain.PromisedPaymentHistory{}, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return domain.PromisedPaymentHistory{}, err
+	}
+
+	var history domain.PromisedPaymentHistory
+	if apiResponse.Current != nil {
+		current, err := mapPromisedPayment(*apiResponse.Current)
+		if err != nil {
+			return domain.PromisedPaymentHistory{}, err
+		}
+		history.Current = &current
+	}
+	for _, item := range apiResponse.History {
+		past, err := mapPromisedPayment(item)
+		if err != nil {
+			return domain.PromisedPaymentHistory{}, err
+		}
+		history.Past = append(history.Past, past)
+	}
+	return history, nil
+}
+
+// CreatePromisedPayment takes a promised payment of amount for the given number of days.
+func (p *ProfileRepository) CreatePromisedPayment(ctx context.Context, suid string, amount domain.Money, days int) (domain.PromisedPayment, error) {
+	p.logger.InfoContext(ctx, "Creating promised payment", "amount", amount.String(), "days", days)
+
+	arg1 := struct {
+		SUID string `json:"suid"`
+		Sum  string `json:"sum"`
+		Days int    `json:"days"`
+	}{
+		SUID: suid,
+		Sum:  amount.String(),
+		Days: days,
+	}
+
+	body, err := p.sendRequest(ctx, "web_cabinet.set_promised_payment", arg1)
+	if err != nil {
+		return domain.PromisedPayment{}, err
+	}
+
+	var apiResponse struct {
+		Error           string             `json:"error"`
+		PromisedPayment apiPromisedPayment `json:"promised_payment"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return domain.PromisedPayment{}, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return domain.PromisedPayment{}, err
+	}
+
+	p.logger.InfoContext(ctx, "Promised payment created")
+	return mapPromisedPayment(apiResponse.PromisedPayment)
+}
+
+func mapPromisedPayment(item apiPromisedPayment) (domain.PromisedPayment, error) {
+	amount, err := parseBillingAmount(item.Sum)
+	if err != nil {
+		return domain.PromisedPayment{}, fmt.Errorf("failed to parse promised payment sum: %w", err)
+	}
+	created, err := time.ParseInLocation(billingDateLayout, item.Date, time.Local)
+	if err != nil {
+		return domain.PromisedPayment{}, fmt.Errorf("failed to parse promised payment date %q: %w", item.Date, err)
+	}
+	deadline, err := time.ParseInLocation(billingDateLayout, item.Deadline, time.Local)
+	if err != nil {
+		return domain.PromisedPayment{}, fmt.Errorf("failed to parse promised payment deadline %q: %w", item.Deadline, err)
+	}
+	return domain.PromisedPayment{
+		Amount:    amount,
+		CreatedAt: created,
+		Deadline:  deadline,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// SuspensionRepository manages voluntary suspensions through the billing API.
+type SuspensionRepository struct {
+	client  *http.Client
+	baseURL string
+	logger  *slog.Logger
+}
+
+// NewSuspensionRepository creates a new SuspensionRepository instance.
+func NewSuspensionRepository(client *http.Client, baseURL string, logger *slog.Logger) *SuspensionRepository {
+	return &SuspensionRepository{
+		client:  client,
+		baseURL: baseURL,
+		logger:  logger,
+	}
+}
+
+// apiSuspension is a suspension as returned by web_cabinet.get_suspensions.
+type apiSuspension struct {
+	ID        string `json:"id"`
+	DateFrom  string `json:"date_from"`
+	DateTo    string `json:"date_to"`
+	Cancelled string `json:"cancelled"`
+}
+
+// Suspensions fetches the past, current and scheduled suspensions.
+func (s *SuspensionRepository) Suspensions(ctx context.Context, suid string) ([]domain.Suspension, error) {
+	s.logger.DebugContext(ctx, "Fetching suspensions")
+
+	arg1 := struct {
+		SUID string `json:"suid"`
+	}{SUID: suid}
+
+	body, err := sendRequest(ctx, s.logger, s.client, s.baseURL, "web_cabinet.get_suspensions", arg1)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResponse struct {
+		Error       string          `json:"error"`
+		Suspensions []apiSuspension `json:"suspensions"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return nil, err
+	}
+
+	suspensions := make([]domain.Suspension, 0, len(apiResponse.Suspensions))
+	for _, item := range apiResponse.Suspensions {
+		start, err := time.ParseInLocation(time.DateOnly, item.DateFrom, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse suspension start %q: %w", item.DateFrom, err)
+		}
+		end, err := time.ParseInLocation(time.DateOnly, item.DateTo, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse suspension end %q: %w", item.DateTo, err)
+		}
+		suspension := domain.Suspension{ID: item.ID, StartDate: start, EndDate: end}
+		if item.Cancelled == "1" {
+			suspension.Status = domain.SuspensionCancelled
+		}
+		suspensions = append(suspensions, suspension)
+	}
+	return suspensions, nil
+}
+
+// CreateSuspension schedules a suspension and returns its ID.
+func (s *SuspensionRepository) CreateSuspension(ctx context.Context, suid string, start, end time.Time) (string, error) {
+	s.logger.InfoContext(ctx, "Scheduling suspension")
+
+	arg1 := struct {
+		SUID     string `json:"suid"`
+		DateFrom string `json:"date_from"`
+		DateTo   string `json:"date_to"`
+	}{
+		SUID:     suid,
+		DateFrom: start.Format(time.DateOnly),
+		DateTo:   end.Format(time.DateOnly),
+	}
+
+	body, err := sendRequest(ctx, s.logger, s.client, s.baseURL, "web_cabinet.add_suspension", arg1)
+	if err != nil {
+		return "", err
+	}
+
+	var apiResponse struct {
+		Error string `json:"error"`
+		ID    string `json:"id"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return "", fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return "", err
+	}
+	return apiResponse.ID, nil
+}
+
+// CancelSuspension cancels a scheduled suspension or ends an active one today.
+func (s *SuspensionRepository) CancelSuspension(ctx context.Context, suid, id string) error {
+	s.logger.InfoContext(ctx, "Cancelling suspension", "suspension_id", id)
+
+	arg1 := struct {
+		SUID string `json:"suid"`
+		ID   string `json:"id"`
+	}{SUID: suid, ID: id}
+
+	body, err := sendRequest(ctx, s.logger, s.client, s.baseURL, "web_cabinet.cancel_suspension", arg1)
+	if err != nil {
+		return err
+	}
+
+	var apiResponse struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return apiError(apiResponse.Error)
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TariffRepository fetches and changes tariffs through the billing API.
+type TariffRepository struct {
+	client  *http.Client
+	baseURL string
+	logger  *slog.Logger
+}
+
+// NewTariffRepository creates a new TariffRepository instance.
+func NewTariffRepository(client *http.Client, baseURL string, logger *slog.Logger) *TariffRepository {
+	return &TariffRepository{
+		client:  client,
+		baseURL: baseURL,
+		logger:  logger,
+	}
+}
+
+// apiTariff is a tariff as returned by web_cabinet.get_available_tarifs.
+type apiTariff struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Speed       string `json:"speed"`
+	Price       string `json:"price"`
+}
+
+// Tariffs fetches the tariffs available at the user's connection address.
+func (t *TariffRepository) Tariffs(ctx context.Context, suid string) ([]domain.Tariff, error) {
+	t.logger.DebugContext(ctx, "Fetching tariffs")
+
+	arg1 := struct {
+		SUID string `json:"suid"`
+	}{SUID: suid}
+
+	body, err := sendRequest(ctx, t.logger, t.client, t.baseURL, "web_cabinet.get_available_tarifs", arg1)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResponse struct {
+		Error   string      `json:"error"`
+		Tariffs []apiTariff `json:"tarifs"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return nil, err
+	}
+
+	tariffs := make([]domain.Tariff, 0, len(apiResponse.Tariffs))
+	for _, item := range apiResponse.Tariffs {
+		price, err := parseBillingAmount(item.Price)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tariff price: %w", err)
+		}
+		tariffs = append(tariffs, domain.Tariff{
+			ID:          item.ID,
+			Name:        item.Name,
+			Description: item.Description,
+			SpeedMbps:   parseSpeed(item.Speed),
+			Price:       price,
+		})
+	}
+	return tariffs, nil
+}
+
+// speedUnits maps the units the billing writes speeds in to Mbit/s.
+var speedUnits = map[string]float64{
+	"":       1,
+	"кбит/с": 0.001,
+	"мбит/с": 1,
+	"гбит/с": 1000,
+	"kbit/s": 0.001,
+	"mbit/s": 1,
+	"gbit/s": 1000,
+	"kbps":   0.001,
+	"mbps":   1,
+	"gbps":   1000,
+}
+
+// parseSpeed reads a tariff speed such as "100", "100 Мбит/с" or
+// "1 Гбит/с" as Mbit/s. A bare number is in Mbit/s. A speed that can't be
+// read is 0.
+func parseSpeed(s string) int {
+	s = strings.TrimSpace(s)
+	number, unit := s, ""
+	if i := strings.IndexFunc(s, func(r rune) bool { return !strings.ContainsRune("0123456789.,", r) }); i >= 0 {
+		number, unit = s[:i], s[i:]
+	}
+	factor, ok := speedUnits[strings.ToLower(strings.TrimSpace(unit))]
+	if !ok {
+		return 0
+	}
+	value, err := strconv.ParseFloat(strings.Replace(number, ",", ".", 1), 64)
+	if err != nil || value < 0 {
+		return 0
+	}
+	return int(math.Round(value * factor))
+}
+
+// ChangeTariff schedules the switch to tariffID starting from the given date.
+func (t *TariffRepository) ChangeTariff(ctx context.Context, suid, tariffID string, from time.Time) error {
+	t.logger.InfoContext(ctx, "Changing tariff", "tariff_id", tariffID)
+
+	arg1 := struct {
+		SUID     string `json:"suid"`
+		TariffID string `json:"tarif_id"`
+		Date     string `json:"date"`
+	}{
+		SUID:     suid,
+		TariffID: tariffID,
+		Date:     from.Format(time.DateOnly),
+	}
+
+	body, err := sendRequest(ctx, t.logger, t.client, t.baseURL, "web_cabinet.change_tarif", arg1)
+	if err != nil {
+		return err
+	}
+
+	var apiResponse struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return fmt.Errorf("failed to parse API response: %w", err)
+	}
+	return apiError(apiResponse.Error)
+}
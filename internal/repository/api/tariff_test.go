@@ -0,0 +1,27 @@
+package api
+
+import "testing"
+
+func TestParseSpeed(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"100", 100},
+		{"100 Мбит/с", 100},
+		{"100Мбит/с", 100},
+		{"1 Гбит/с", 1000},
+		{"2,5 Гбит/с", 2500},
+		{"512 Кбит/с", 1},
+		{"300 Mbps", 300},
+		{"1 Gbit/s", 1000},
+		{"", 0},
+		{"быстро", 0},
+		{"100 лошадей", 0},
+	}
+	for _, tt := range tests {
+		if got := parseSpeed(tt.in); got != tt.want {
+			t.Errorf("parseSpeed(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
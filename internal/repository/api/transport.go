@@ -0,0 +1,60 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"time"
+)
+
+// NewHTTPClient returns the client the repositories share to call the
+// billing API. Each call, retries included, is bounded by timeout. A call
+// that could not connect is retried up to maxAttempts times in all, waiting
+// backoff before the first retry and twice as long before each next one.
+//
+// Only failures to connect are retried: once a request has been sent, the
+// billing may have acted on it, and calls such as payments must not be
+// repeated.
+func NewHTTPClient(timeout time.Duration, maxAttempts int, backoff time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &retryTransport{
+			next:        http.DefaultTransport,
+			maxAttempts: maxAttempts,
+			backoff:     backoff,
+		},
+	}
+}
+
+type retryTransport struct {
+	next        http.RoundTripper
+	maxAttempts int
+	backoff     time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	wait := t.backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		// Billing calls are bodiless GETs, so the request can be sent again.
+		bodiless := req.Body == nil || req.Body == http.NoBody
+		if err == nil || attempt >= t.maxAttempts || !bodiless || !isDialError(err) {
+			return resp, err
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
+
+// isDialError reports whether err happened before anything was sent.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubTransport struct {
+	errs  []error
+	calls int
+}
+
+func (s *stubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+}
+
+func TestRetryTransport(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	reset := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset by peer")}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		attempts  int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"success", nil, 3, 1, false},
+		{"recovers after refused", []error{refused, refused}, 3, 3, false},
+		{"gives up", []error{refused, refused, refused}, 3, 3, true},
+		{"retries disabled", []error{refused}, 1, 1, true},
+		{"sent requests are not repeated", []error{reset}, 3, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubTransport{errs: tt.errs}
+			transport := &retryTransport{next: stub, maxAttempts: tt.attempts}
+
+			resp, err := transport.RoundTrip(httptest.NewRequest(http.MethodGet, "http://billing.test/api", nil))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("RoundTrip() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if resp != nil {
+				resp.Body.Close()
+			}
+			if stub.calls != tt.wantCalls {
+				t.Errorf("attempts = %d, want %d", stub.calls, tt.wantCalls)
+			}
+		})
+	}
+}
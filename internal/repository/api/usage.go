@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// UsageRepository reads traffic and connection sessions from the billing API.
+type UsageRepository struct {
+	client  *http.Client
+	baseURL string
+	logger  *slog.Logger
+}
+
+// NewUsageRepository creates a new UsageRepository instance.
+func NewUsageRepository(client *http.Client, baseURL string, logger *slog.Logger) *UsageRepository {
+	return &UsageRepository{
+		client:  client,
+		baseURL: baseURL,
+		logger:  logger,
+	}
+}
+
+// usageArgs is the argument of the traffic and session calls.
+type usageArgs struct {
+	SUID     string `json:"suid"`
+	DateFrom string `json:"date_from"`
+	DateTo   string `json:"date_to"`
+}
+
+// DailyTraffic fetches the traffic per day between two dates, both inclusive.
+func (u *UsageRepository) DailyTraffic(ctx context.Context, suid string, from, to time.Time) ([]domain.TrafficPoint, error) {
+	u.logger.DebugContext(ctx, "Fetching traffic", "from", from, "to", to)
+
+	arg1 := usageArgs{SUID: suid, DateFrom: from.Format(time.DateOnly), DateTo: to.Format(time.DateOnly)}
+	body, err := sendRequest(ctx, u.logger, u.client, u.baseURL, "web_cabinet.get_traffic", arg1)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResponse struct {
+		Error   string `json:"error"`
+		Traffic []struct {
+			Date     string      `json:"date"`
+			BytesIn  json.Number `json:"bytes_in"`
+			BytesOut json.Number `json:"bytes_out"`
+		} `json:"traffic"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return nil, err
+	}
+
+	points := make([]domain.TrafficPoint, 0, len(apiResponse.Traffic))
+	for _, item := range apiResponse.Traffic {
+		day, err := time.ParseInLocation(time.DateOnly, item.Date, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse traffic date %q: %w", item.Date, err)
+		}
+		in, err := parseBytes(item.BytesIn)
+		if err != nil {
+			return nil, err
+		}
+		out, err := parseBytes(item.BytesOut)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, domain.TrafficPoint{Period: day, BytesIn: in, BytesOut: out})
+	}
+	return points, nil
+}
+
+// Sessions fetches the connection sessions started between two dates, both inclusive.
+func (u *UsageRepository) Sessions(ctx context.Context, suid string, from, to time.Time) ([]domain.ConnectionSession, error) {
+	u.logger.DebugContext(ctx, "Fetching connection sessions", "from", from, "to", to)
+
+	arg1 := usageArgs{SUID: suid, DateFrom: from.Format(time.DateOnly), DateTo: to.Format(time.DateOnly)}
+	body, err := sendRequest(ctx, u.logger, u.client, u.baseURL, "web_cabinet.get_sessions", arg1)
+	if err != nil {
+		return nil, err
+	}
+
+	var apiResponse struct {
+		Error    string `json:"error"`
+		Sessions []struct {
+			ID       string      `json:"id"`
+			Type     string      `json:"type"`
+			Start    string      `json:"start_time"`
+			End      string      `json:"end_time"`
+			IP       string      `json:"ip"`
+			BytesIn  json.Number `json:"bytes_in"`
+			BytesOut json.Number `json:"bytes_out"`
+		} `json:"sessions"`
+	}
+	if err := json.Unmarshal(body, &apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse API response: %w", err)
+	}
+	if err := apiError(apiResponse.Error); err != nil {
+		return nil, err
+	}
+
+	sessions := make([]domain.ConnectionSession, 0, len(apiResponse.Sessions))
+	for _, item := range apiResponse.Sessions {
+		start, err := time.ParseInLocation(billingDateLayout, item.Start, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse session start %q: %w", item.Start, err)
+		}
+		session := domain.ConnectionSession{
+			ID:    item.ID,
+			Type:  domain.ConnectionType(strings.ToLower(item.Type)),
+			Start: start,
+			IP:    item.IP,
+		}
+		// Open sessions have no end time yet.
+		if item.End != "" && !strings.HasPrefix(item.End, "0000") {
+			end, err := time.ParseInLocation(billingDateLayout, item.End, time.Local)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse session end %q: %w", item.End, err)
+			}
+			session.End = &end
+		}
+		if session.BytesIn, err = parseBytes(item.BytesIn); err != nil {
+			return nil, err
+		}
+		if session.BytesOut, err = parseBytes(item.BytesOut); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, nil
+}
+
+// parseBytes parses a byte counter, treating an empty value as zero.
+func parseBytes(n json.Number) (int64, error) {
+	if n == "" {
+		return 0, nil
+	}
+	v, err := n.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse byte counter %q: %w", n, err)
+	}
+	return v, nil
+}
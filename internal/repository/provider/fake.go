@@ -0,0 +1,131 @@
+package provider
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"net/http"
+	"net/url"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the webhook body.
+const SignatureHeader = "X-Signature"
+
+// fakeEvent is the webhook body the fake provider sends.
+type fakeEvent struct {
+	ID       string                     `json:"id"`
+	IntentID string                     `json:"intent_id"`
+	Status   domain.PaymentIntentStatus `json:"status"`
+	Amount   string                     `json:"amount"`
+}
+
+// FakeProvider is a local payment provider for development and tests. It
+// never moves money: payments are confirmed by sending the webhook built
+// with Webhook.
+type FakeProvider struct {
+	confirmBaseURL string
+	secret         []byte
+}
+
+// NewFakeProvider creates a FakeProvider that signs webhooks with secret.
+func NewFakeProvider(confirmBaseURL, secret string) *FakeProvider {
+	return &FakeProvider{
+		confirmBaseURL: confirmBaseURL,
+		secret:         []byte(secret),
+	}
+}
+
+// CreatePayment returns a random provider ID and a confirmation URL under confirmBaseURL.
+func (f *FakeProvider) CreatePayment(_ context.Context, intent domain.PaymentIntent, returnURL string) (string, string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("failed to generate payment ID: %w", err)
+	}
+	providerID := "fake_" + hex.EncodeToString(b)
+
+	params := url.Values{}
+	params.Add("intent_id", intent.ID)
+	params.Add("amount", intent.Amount.String())
+	if returnURL != "" {
+		params.Add("return_url", returnURL)
+	}
+	return providerID, fmt.Sprintf("%s/%s?%s", f.confirmBaseURL, providerID, params.Encode()), nil
+}
+
+// DeclinedToken is a payment token whose saved method declines every charge.
+const DeclinedToken = "declined"
+
+// SavePaymentMethod saves any token as a test card. Methods saved from
+// DeclinedToken decline every charge.
+func (f *FakeProvider) SavePaymentMethod(_ context.Context, paymentToken string) (domain.PaymentMethod, error) {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write([]byte(paymentToken))
+	id := "pm_" + hex.EncodeToString(mac.Sum(nil))[:16]
+	if paymentToken == DeclinedToken {
+		id = "pm_" + DeclinedToken
+	}
+	return domain.PaymentMethod{
+		ID:    id,
+		Title: "Test card •••• 4242",
+	}, nil
+}
+
+// Charge succeeds immediately unless the method was saved from DeclinedToken.
+func (f *FakeProvider) Charge(ctx context.Context, methodID string, intent domain.PaymentIntent) (string, domain.PaymentIntentStatus, error) {
+	if methodID == "pm_"+DeclinedToken {
+		return "", domain.PaymentIntentCanceled, errors.New("card declined")
+	}
+	providerID, _, err := f.CreatePayment(ctx, intent, "")
+	if err != nil {
+		return "", "", err
+	}
+	return providerID, domain.PaymentIntentSucceeded, nil
+}
+
+// ParseWebhook verifies the X-Signature header and decodes the event.
+func (f *FakeProvider) ParseWebhook(header http.Header, body []byte) (domain.ProviderEvent, error) {
+	signature, err := hex.DecodeString(header.Get(SignatureHeader))
+	if err != nil || !hmac.Equal(signature, f.sign(body)) {
+		return domain.ProviderEvent{}, errors.New("invalid webhook signature")
+	}
+
+	var event fakeEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		return domain.ProviderEvent{}, fmt.Errorf("failed to parse webhook: %w", err)
+	}
+	amount, err := domain.ParseMoney(event.Amount, domain.CurrencyRUB)
+	if err != nil {
+		return domain.ProviderEvent{}, fmt.Errorf("failed to parse webhook: %w", err)
+	}
+	return domain.ProviderEvent{
+		ProviderID: event.ID,
+		IntentID:   event.IntentID,
+		Status:     event.Status,
+		Amount:     amount,
+	}, nil
+}
+
+// Webhook builds a signed webhook for a payment, as the real provider would send it.
+func (f *FakeProvider) Webhook(providerID string, intent domain.PaymentIntent, status domain.PaymentIntentStatus) (http.Header, []byte) {
+	body, _ := json.Marshal(fakeEvent{
+		ID:       providerID,
+		IntentID: intent.ID,
+		Status:   status,
+		Amount:   intent.Amount.String(),
+	})
+	header := http.Header{}
+	header.Set(SignatureHeader, hex.EncodeToString(f.sign(body)))
+	return header, body
+}
+
+func (f *FakeProvider) sign(body []byte) []byte {
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
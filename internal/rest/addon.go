@@ -0,0 +1,115 @@
+package rest
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"net/http"
+)
+
+// AddonHandler handles add-on service requests.
+type AddonHandler struct {
+	Service AddonService
+}
+
+// AddonService defines the interface for add-on services.
+type AddonService interface {
+	Addons(ctx context.Context, token string) (domain.AddonList, error)
+	ChangeAddon(ctx context.Context, token, addonID string, enable, confirm bool) (domain.AddonChange, error)
+}
+
+// NewAddonHandler initializes the add-on handler with the given service and routes.
+func NewAddonHandler(e *echo.Echo, svc AddonService) {
+	handler := &AddonHandler{
+		Service: svc, // Initialize the handler with the service
+	}
+	addonGroup := e.Group("/api/v1/addons")
+	addonGroup.GET("", handler.Addons)
+	addonGroup.POST("/:id/enable", handler.Enable)
+	addonGroup.POST("/:id/disable", handler.Disable)
+}
+
+// addonConfirmation is the body of enable and disable requests.
+type addonConfirmation struct {
+	Confirm bool `json:"confirm"`
+}
+
+// Addons handles the GET /addons endpoint.
+// @Summary List add-on services
+// @Description List connected and available add-on services with monthly prices and their effect on the amount due
+// @Tags Addons
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Success 200 {object} domain.AddonList "Add-on services"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/addons [get]
+func (h *AddonHandler) Addons(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	addons, err := h.Service.Addons(c.Request().Context(), token)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, addons)
+}
+
+// Enable handles the POST /addons/{id}/enable endpoint.
+// @Summary Enable an add-on service
+// @Description Without "confirm": true only the effect on the amount due is returned; with it the add-on is connected
+// @Tags Addons
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param id path string true "Add-on ID"
+// @Param confirmation body addonConfirmation false "Confirmation"
+// @Success 200 {object} domain.AddonChange "Preview or confirmed change"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Add-on not found"
+// @Failure 409 {object} ResponseError "Add-on is already connected"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/addons/{id}/enable [post]
+func (h *AddonHandler) Enable(c echo.Context) error {
+	return h.change(c, true)
+}
+
+// Disable handles the POST /addons/{id}/disable endpoint.
+// @Summary Disable an add-on service
+// @Description Without "confirm": true only the effect on the amount due is returned; with it the add-on is disconnected
+// @Tags Addons
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param id path string true "Add-on ID"
+// @Param confirmation body addonConfirmation false "Confirmation"
+// @Success 200 {object} domain.AddonChange "Preview or confirmed change"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Add-on not found"
+// @Failure 409 {object} ResponseError "Add-on is not connected"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/addons/{id}/disable [post]
+func (h *AddonHandler) Disable(c echo.Context) error {
+	return h.change(c, false)
+}
+
+func (h *AddonHandler) change(c echo.Context, enable bool) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	var payload addonConfirmation
+	if err := c.Bind(&payload); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	change, err := h.Service.ChangeAddon(c.Request().Context(), token, c.Param("id"), enable, payload.Confirm)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, change)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"github.com/labstack/echo/v4"
 	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/i18n"
 	"net/http"
 )
 
@@ -15,9 +16,11 @@ type AuthHandler struct {
 
 // AuthService defines the interface for authentication services.
 type AuthService interface {
-	Login(ctx context.Context, user domain.Auth) (string, error)
+	Login(ctx context.Context, user domain.Auth) (domain.Session, error)
 	RequestPasswordResetToken(ctx context.Context, login string) error
 	UpdatePassword(ctx context.Context, token, password string) error
+	Contracts(ctx context.Context, principal domain.Principal) ([]domain.Contract, error)
+	SwitchContract(ctx context.Context, principal domain.Principal, contractID string) (domain.Session, error)
 }
 
 func NewAuthHandler(e *echo.Echo, svc AuthService) {
@@ -28,16 +31,18 @@ func NewAuthHandler(e *echo.Echo, svc AuthService) {
 	authGroup.POST("/sign-in", handler.Login)
 	authGroup.POST("/request-password-reset-token", handler.RequestPasswordResetToken)
 	authGroup.POST("/update-password", handler.UpdatePassword)
+	authGroup.GET("/contracts", handler.Contracts)
+	authGroup.POST("/switch-contract", handler.SwitchContract)
 }
 
 // Login handles the /sign-in endpoint.
 // @Summary Login a user
-// @Description Logs the user in using their credentials (username and password)
+// @Description Logs the user in using their credentials (login or phone and password) and lists the linked contracts
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param user body domain.Auth true "Login credentials"
-// @Success 200 {object} map[string]string "token"
+// @Success 200 {object} domain.Session "Token scoped to the selected contract"
 // @Failure 400 {object} ResponseError "Invalid request payload"
 // @Failure 401 {object} ResponseError "Invalid credentials"
 // @Failure 403 {object} ResponseError "Account is locked"
@@ -47,22 +52,74 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	var auth domain.Auth
 	// Bind the incoming JSON payload to the auth struct
 	if err := c.Bind(&auth); err != nil {
-		return c.JSON(http.StatusBadRequest, ResponseError{
-			Message: "Invalid request payload", // Handle binding errors
-		})
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload")) // Handle binding errors
 	}
 
 	// Attempt to log in with the provided credentials
-	token, err := h.Service.Login(c.Request().Context(), auth)
+	session, err := h.Service.Login(c.Request().Context(), auth)
 	if err != nil {
 		// Handle errors from the service layer
 		return handleError(c, err)
 	}
 
 	// Return the token on successful login
-	return c.JSON(http.StatusOK, map[string]string{
-		"token": token,
-	})
+	return c.JSON(http.StatusOK, session)
+}
+
+// Contracts handles the /contracts endpoint.
+// @Summary List contracts
+// @Description Lists the contracts linked to the login, marking the one the token is scoped to
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Success 200 {array} domain.Contract "Contracts"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/auth/contracts [get]
+func (h *AuthHandler) Contracts(c echo.Context) error {
+	principal, ok := domain.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	contracts, err := h.Service.Contracts(c.Request().Context(), principal)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, contracts)
+}
+
+// SwitchContract handles the /switch-contract endpoint.
+// @Summary Switch the active contract
+// @Description Issues a new token for the same session scoped to another contract linked to the login
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param request body domain.ContractSwitch true "Contract to switch to"
+// @Success 200 {object} domain.Session "Token scoped to the selected contract"
+// @Failure 400 {object} ResponseError "Invalid request payload"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Contract is not linked to the login"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/auth/switch-contract [post]
+func (h *AuthHandler) SwitchContract(c echo.Context) error {
+	principal, ok := domain.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	var request domain.ContractSwitch
+	if err := c.Bind(&request); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	session, err := h.Service.SwitchContract(c.Request().Context(), principal, request.ContractID)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, session)
 }
 
 // RequestPasswordResetToken handles the /request-password-reset-token endpoint.
@@ -81,9 +138,7 @@ func (h *AuthHandler) RequestPasswordResetToken(c echo.Context) error {
 	var auth domain.Auth
 	// Bind the incoming JSON payload to the auth struct
 	if err := c.Bind(&auth); err != nil {
-		return c.JSON(http.StatusBadRequest, ResponseError{
-			Message: "Invalid request payload", // Handle binding errors
-		})
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload")) // Handle binding errors
 	}
 
 	// Request the password reset token
@@ -95,7 +150,7 @@ func (h *AuthHandler) RequestPasswordResetToken(c echo.Context) error {
 
 	// Return a success message after requesting the reset token
 	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Password reset token has been sent to your email",
+		"message": localize(c, "auth.reset_token_sent"),
 	})
 }
 
@@ -116,9 +171,7 @@ func (h *AuthHandler) UpdatePassword(c echo.Context) error {
 	var request domain.Auth
 	// Bind the incoming JSON payload to the request struct
 	if err := c.Bind(&request); err != nil {
-		return c.JSON(http.StatusBadRequest, ResponseError{
-			Message: "Invalid request payload", // Handle binding errors
-		})
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload")) // Handle binding errors
 	}
 
 	// Attempt to update the password with the provided token and password
@@ -130,7 +183,7 @@ func (h *AuthHandler) UpdatePassword(c echo.Context) error {
 
 	// Return a success message on successful password update
 	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Password updated successfully",
+		"message": localize(c, "auth.password_updated"),
 	})
 }
 
@@ -138,34 +191,52 @@ func (h *AuthHandler) UpdatePassword(c echo.Context) error {
 func handleError(c echo.Context, err error) error {
 	switch {
 	case errors.Is(err, domain.ErrInvalidCredentials):
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: domain.ErrInvalidCredentials.Error(),
-		})
+		return respondError(c, http.StatusUnauthorized, localize(c, "error.invalid_credentials"))
 	case errors.Is(err, domain.ErrAccountLocked):
-		return c.JSON(http.StatusForbidden, ResponseError{
-			Message: domain.ErrAccountLocked.Error(),
-		})
+		return respondError(c, http.StatusForbidden, localize(c, "error.account_locked"))
 	case errors.Is(err, domain.ErrSessionExpired):
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: domain.ErrSessionExpired.Error(),
-		})
+		return respondError(c, http.StatusUnauthorized, localize(c, "error.session_expired"))
 	case errors.Is(err, domain.ErrTooManyRequests):
-		return c.JSON(http.StatusTooManyRequests, ResponseError{
-			Message: domain.ErrTooManyRequests.Error(),
-		})
+		setRetryAfter(c, err)
+		return respondError(c, http.StatusTooManyRequests, localize(c, "error.too_many_requests"))
+	case errors.Is(err, domain.ErrBadParamInput):
+		return respondError(c, http.StatusBadRequest, localize(c, "error.bad_param"))
+	case errors.Is(err, domain.ErrInsufficientFunds):
+		return respondError(c, http.StatusPaymentRequired, localize(c, "error.insufficient_funds"))
+	case errors.Is(err, domain.ErrConflict):
+		return respondError(c, http.StatusConflict, localize(c, "error.conflict"))
+	case errors.Is(err, domain.ErrPaymentDeclined):
+		return respondError(c, http.StatusPaymentRequired, localize(c, "error.payment_declined"))
+	case errors.Is(err, domain.ErrNotEligible):
+		return respondNotEligible(c, err)
+	case errors.Is(err, domain.ErrPreconditionFailed):
+		return respondError(c, http.StatusPreconditionFailed, localize(c, "error.precondition_failed"))
+	case errors.Is(err, domain.ErrBalanceUnknown):
+		return respondError(c, http.StatusServiceUnavailable, localize(c, "error.balance_unknown"))
+	case errors.Is(err, domain.ErrForbidden):
+		return respondError(c, http.StatusForbidden, localize(c, "error.forbidden"))
+	case errors.Is(err, domain.ErrNotFound):
+		return respondError(c, http.StatusNotFound, localize(c, "error.not_found"))
 	case errors.Is(err, domain.ErrUnauthorized):
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: domain.ErrUnauthorized.Error(),
-		})
+		return respondError(c, http.StatusUnauthorized, localize(c, "error.unauthorized"))
 	default:
 		// For any other unhandled errors, return 500 Internal Server Error
-		return c.JSON(http.StatusInternalServerError, ResponseError{
-			Message: domain.ErrInternalServerError.Error(),
-		})
+		return respondError(c, http.StatusInternalServerError, localize(c, "error.internal"))
 	}
 }
 
-// ResponseError is used to send error messages to the client
-type ResponseError struct {
-	Message string `json:"message"`
+// localize translates key into the language negotiated for the request.
+func localize(c echo.Context, key string, args ...any) string {
+	return i18n.FromContext(c.Request().Context()).T(key, args...)
+}
+
+// bearerToken returns the billing session of the authenticated principal.
+// The middleware.Principal middleware has already verified the token and
+// scoped the request context to the selected contract.
+func bearerToken(c echo.Context) (string, bool) {
+	principal, ok := domain.PrincipalFromContext(c.Request().Context())
+	if !ok {
+		return "", false
+	}
+	return principal.Token, true
 }
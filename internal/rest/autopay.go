@@ -0,0 +1,166 @@
+package rest
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"net/http"
+)
+
+// AutopayHandler handles autopay-related requests.
+type AutopayHandler struct {
+	Service AutopayService
+}
+
+// AutopayService defines the interface for autopay services.
+type AutopayService interface {
+	Rule(ctx context.Context, token string) (domain.AutopayRule, error)
+	SetRule(ctx context.Context, token string, setup domain.AutopaySetup) (domain.AutopayRule, error)
+	Pause(ctx context.Context, token string) (domain.AutopayRule, error)
+	Resume(ctx context.Context, token string) (domain.AutopayRule, error)
+	Cancel(ctx context.Context, token string) error
+}
+
+// NewAutopayHandler initializes the autopay handler with the given service and routes.
+func NewAutopayHandler(e *echo.Echo, svc AutopayService) {
+	handler := &AutopayHandler{
+		Service: svc, // Initialize the handler with the service
+	}
+	autopayGroup := e.Group("/api/v1/autopay")
+	autopayGroup.GET("", handler.Rule)
+	autopayGroup.PUT("", handler.SetRule)
+	autopayGroup.DELETE("", handler.Cancel)
+	autopayGroup.POST("/pause", handler.Pause)
+	autopayGroup.POST("/resume", handler.Resume)
+}
+
+// Rule handles the GET /autopay endpoint.
+// @Summary Get autopay rule
+// @Description Retrieve the autopay rule of the authenticated user
+// @Tags Autopay
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Success 200 {object} domain.AutopayRule "Autopay rule"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Autopay is not set up"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/autopay [get]
+func (h *AutopayHandler) Rule(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	rule, err := h.Service.Rule(c.Request().Context(), token)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, rule)
+}
+
+// SetRule handles the PUT /autopay endpoint.
+// @Summary Set up autopay
+// @Description Save a tokenised payment method and choose when it is charged: before the next payment date or when the balance drops below a threshold
+// @Tags Autopay
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param setup body domain.AutopaySetup true "Autopay rule"
+// @Success 200 {object} domain.AutopayRule "Saved autopay rule"
+// @Failure 400 {object} ResponseError "Invalid request payload"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 402 {object} ResponseError "Payment method was declined"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/autopay [put]
+func (h *AutopayHandler) SetRule(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	var setup domain.AutopaySetup
+	if err := c.Bind(&setup); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	rule, err := h.Service.SetRule(c.Request().Context(), token, setup)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, rule)
+}
+
+// Pause handles the POST /autopay/pause endpoint.
+// @Summary Pause autopay
+// @Description Stop autopay charges until it is resumed
+// @Tags Autopay
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Success 200 {object} domain.AutopayRule "Paused autopay rule"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Autopay is not set up"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/autopay/pause [post]
+func (h *AutopayHandler) Pause(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	rule, err := h.Service.Pause(c.Request().Context(), token)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, rule)
+}
+
+// Resume handles the POST /autopay/resume endpoint.
+// @Summary Resume autopay
+// @Description Resume a paused autopay rule
+// @Tags Autopay
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Success 200 {object} domain.AutopayRule "Active autopay rule"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Autopay is not set up"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/autopay/resume [post]
+func (h *AutopayHandler) Resume(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	rule, err := h.Service.Resume(c.Request().Context(), token)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, rule)
+}
+
+// Cancel handles the DELETE /autopay endpoint.
+// @Summary Cancel autopay
+// @Description Remove the autopay rule and the saved payment method
+// @Tags Autopay
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Success 204 "Autopay cancelled"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Autopay is not set up"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/autopay [delete]
+func (h *AutopayHandler) Cancel(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	if err := h.Service.Cancel(c.Request().Context(), token); err != nil {
+		return handleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
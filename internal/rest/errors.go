@@ -0,0 +1,101 @@
+package rest
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"go.opentelemetry.io/otel/trace"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// defaultRetryAfter is sent when the billing API throttled the client
+// without saying for how long.
+const defaultRetryAfter = time.Minute
+
+// ResponseError is used to send error messages to the client
+type ResponseError struct {
+	Message string `json:"message"`
+	// Reason is a machine-readable code for errors that have several causes.
+	Reason string `json:"reason,omitempty"`
+	// RequestID identifies the request when reporting the failure.
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// respondError writes an error body carrying the request ID.
+func respondError(c echo.Context, status int, message string) error {
+	return c.JSON(status, ResponseError{
+		Message:   message,
+		RequestID: logging.RequestIDFromContext(c.Request().Context()),
+	})
+}
+
+// respondNotEligible explains why the account doesn't qualify when the
+// service said so.
+func respondNotEligible(c echo.Context, err error) error {
+	var notEligible *domain.NotEligibleError
+	if !errors.As(err, &notEligible) || notEligible.Message == "" {
+		return respondError(c, http.StatusForbidden, localize(c, "error.not_eligible"))
+	}
+	return c.JSON(http.StatusForbidden, ResponseError{
+		Message:   notEligible.Message,
+		Reason:    notEligible.Reason,
+		RequestID: logging.RequestIDFromContext(c.Request().Context()),
+	})
+}
+
+// ErrorHandler renders the errors returned by middleware and unknown routes
+// in the same shape as the handlers' own errors. Anything that is not an
+// echo.HTTPError or ErrTooManyRequests is an internal error whose details
+// stay in the logs and the request's span.
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	message := localize(c, "error.internal")
+	var he *echo.HTTPError
+	if errors.Is(err, domain.ErrTooManyRequests) {
+		status = http.StatusTooManyRequests
+		message = localize(c, "error.too_many_requests")
+		setRetryAfter(c, err)
+	} else if errors.As(err, &he) {
+		status = he.Code
+		if m, ok := he.Message.(string); ok {
+			message = m
+		} else {
+			message = fmt.Sprint(he.Message)
+		}
+	}
+
+	if status >= http.StatusInternalServerError {
+		// The Tracing middleware sets the span status from the response.
+		trace.SpanFromContext(c.Request().Context()).RecordError(err)
+	}
+
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(status)
+	} else {
+		err = respondError(c, status, message)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+// setRetryAfter tells the client when to retry a request rejected with
+// ErrTooManyRequests.
+func setRetryAfter(c echo.Context, err error) {
+	retry := defaultRetryAfter
+	var limited *domain.RateLimitError
+	if errors.As(err, &limited) {
+		retry = limited.RetryAfter
+	}
+	seconds := max(int64(math.Ceil(retry.Seconds())), 1)
+	c.Response().Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+}
@@ -0,0 +1,90 @@
+package rest
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestErrorHandlerRecordsErrorOnSpan(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler
+	// AccessLog handles the error before Tracing sees it, as in main.
+	e.Use(middleware.Tracing(), middleware.AccessLog(logging.Discard()))
+	e.GET("/fail", func(echo.Context) error { return errors.New("billing unreachable") })
+	e.GET("/missing", func(echo.Context) error { return echo.ErrNotFound })
+
+	tests := []struct {
+		path       string
+		wantStatus codes.Code
+		wantEvent  bool
+	}{
+		{"/fail", codes.Error, true},
+		{"/missing", codes.Unset, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			spans := recorder.Ended()
+			if len(spans) != 1 {
+				t.Fatalf("%d spans ended, want 1", len(spans))
+			}
+			span := spans[0]
+			if span.Status().Code != tt.wantStatus {
+				t.Errorf("status = %v, want %v", span.Status().Code, tt.wantStatus)
+			}
+			recorded := false
+			for _, event := range span.Events() {
+				recorded = recorded || event.Name == "exception"
+			}
+			if recorded != tt.wantEvent {
+				t.Errorf("error recorded = %v, want %v", recorded, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestHandleErrorNotEligible(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantReason string
+		wantBody   string
+	}{
+		{"Reason", &domain.NotEligibleError{Reason: "cooldown", Message: "Try later"}, "cooldown", "Try later"},
+		{"Bare", domain.ErrNotEligible, "", "Действие недоступно для вашей учётной записи"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/", nil), rec)
+			if err := handleError(c, tt.err); err != nil {
+				t.Fatal(err)
+			}
+
+			var body ResponseError
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusForbidden || body.Reason != tt.wantReason || body.Message != tt.wantBody {
+				t.Errorf("got %d %+v, want 403 with reason %q and message %q", rec.Code, body, tt.wantReason, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"net/http"
+)
+
+// HealthHandler serves the liveness and readiness probes.
+type HealthHandler struct {
+	Service HealthService
+}
+
+// HealthService defines the interface for readiness checks.
+type HealthService interface {
+	Ready(ctx context.Context) domain.HealthReport
+}
+
+// NewHealthHandler registers the probes. The detailed report names the
+// dependencies and their errors, so it requires a partner or admin API key.
+func NewHealthHandler(e *echo.Echo, svc HealthService, keys middleware.APIKeyAuthenticator) {
+	handler := &HealthHandler{
+		Service: svc,
+	}
+	e.GET("/healthz", handler.Live)
+	e.GET("/readyz", handler.Ready)
+	e.GET("/readyz/details", handler.Details, middleware.APIKey(keys, domain.ScopePartner))
+}
+
+// Live handles the liveness probe.
+// @Summary Liveness probe
+// @Description Reports that the process is up and serving requests
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]string "Alive"
+// @Router /healthz [get]
+func (h *HealthHandler) Live(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]domain.HealthStatus{"status": domain.HealthOK})
+}
+
+// Ready handles the readiness probe.
+// @Summary Readiness probe
+// @Description Reports whether the billing API, storage and configuration are usable
+// @Tags Health
+// @Produce json
+// @Success 200 {object} map[string]string "Ready"
+// @Failure 503 {object} map[string]string "Not ready"
+// @Router /readyz [get]
+func (h *HealthHandler) Ready(c echo.Context) error {
+	report := h.Service.Ready(c.Request().Context())
+	return c.JSON(readyStatus(report), map[string]domain.HealthStatus{"status": report.Status})
+}
+
+// Details handles the detailed readiness report.
+// @Summary Readiness report
+// @Description Runs the readiness checks and reports the outcome of each
+// @Tags Health
+// @Produce json
+// @Param X-API-Key header string true "API key"
+// @Success 200 {object} domain.HealthReport "Ready"
+// @Failure 401 {object} ResponseError "Missing or invalid API key"
+// @Failure 403 {object} ResponseError "API key without the partner or admin scope"
+// @Failure 503 {object} domain.HealthReport "Not ready"
+// @Router /readyz/details [get]
+func (h *HealthHandler) Details(c echo.Context) error {
+	report := h.Service.Ready(c.Request().Context())
+	return c.JSON(readyStatus(report), report)
+}
+
+func readyStatus(report domain.HealthReport) int {
+	if report.Ready() {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
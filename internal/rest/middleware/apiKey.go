@@ -1,26 +1,54 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
 	"net/http"
 )
 
-// APIKeyMiddleware проверяет наличие и корректность API ключа в заголовке.
-func APIKey(apiKey string) echo.MiddlewareFunc {
+// APIKeyAuthenticator finds the active key for the secret a request sent
+// and records its use.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, secret string) (domain.APIKey, error)
+	RecordUse(ctx context.Context, key domain.APIKey)
+}
+
+// APIKey lets through only requests whose X-API-Key header holds a key
+// granting scope, and stores the key in the request context.
+func APIKey(keys APIKeyAuthenticator, scope domain.APIKeyScope) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Получаем API ключ из заголовка запроса
-			key := c.Request().Header.Get("X-API-Key")
-			if key == "" {
+			// Get the API key from the request header
+			secret := c.Request().Header.Get("X-API-Key")
+			if secret == "" {
 				return echo.NewHTTPError(http.StatusUnauthorized, "Missing API Key")
 			}
 
-			// Проверяем, совпадает ли переданный API ключ с ожидаемым
-			if key != apiKey {
-				return echo.NewHTTPError(http.StatusUnauthorized, "Invalid API Key")
+			// RateLimit may have checked the key already; don't load it twice
+			ctx := c.Request().Context()
+			key, ok := domain.APIKeyFromContext(ctx)
+			if ok {
+				keys.RecordUse(ctx, key)
+			} else {
+				// Look the key up; secrets are compared in constant time
+				var err error
+				key, err = keys.Authenticate(ctx, secret)
+				if errors.Is(err, domain.ErrUnauthorized) {
+					return echo.NewHTTPError(http.StatusUnauthorized, "Invalid API Key")
+				}
+				if err != nil {
+					return err
+				}
+			}
+
+			// The key is valid but may not grant this scope
+			if !key.Permits(scope, c.Request().Method) {
+				return echo.NewHTTPError(http.StatusForbidden, "API Key does not grant access")
 			}
 
-			// Если ключ правильный, продолжаем обработку
+			c.SetRequest(c.Request().WithContext(domain.ContextWithAPIKey(ctx, key)))
 			return next(c)
 		}
 	}
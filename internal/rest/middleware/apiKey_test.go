@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type fakeKeys map[string]domain.APIKey
+
+func (f fakeKeys) Authenticate(_ context.Context, secret string) (domain.APIKey, error) {
+	key, ok := f[secret]
+	if !ok {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	return key, nil
+}
+
+func (f fakeKeys) RecordUse(context.Context, domain.APIKey) {}
+
+func TestAPIKey(t *testing.T) {
+	keys := fakeKeys{
+		"admin":   {ID: "k1", Scopes: []domain.APIKeyScope{domain.ScopeAdmin}},
+		"docs":    {ID: "k2", Scopes: []domain.APIKeyScope{domain.ScopeSwagger}},
+		"partner": {ID: "k3", Scopes: []domain.APIKeyScope{domain.ScopePartner}},
+	}
+	tests := []struct {
+		name   string
+		scope  domain.APIKeyScope
+		method string
+		key    string
+		want   int
+	}{
+		{"missing", domain.ScopeSwagger, http.MethodGet, "", http.StatusUnauthorized},
+		{"invalid", domain.ScopeSwagger, http.MethodGet, "nope", http.StatusUnauthorized},
+		{"matching scope", domain.ScopeSwagger, http.MethodGet, "docs", http.StatusOK},
+		{"other scope", domain.ScopePartner, http.MethodGet, "docs", http.StatusForbidden},
+		{"admin opens everything", domain.ScopePartner, http.MethodPost, "admin", http.StatusOK},
+		{"partner reads", domain.ScopePartner, http.MethodGet, "partner", http.StatusOK},
+		{"partner can't write", domain.ScopePartner, http.MethodPost, "partner", http.StatusForbidden},
+		{"identified by RateLimit", domain.ScopeSwagger, http.MethodGet, "identified", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			want := keys[tt.key]
+			if tt.key == "identified" {
+				// Not among keys: it must not be looked up again.
+				want = domain.APIKey{ID: "k4", Scopes: []domain.APIKeyScope{domain.ScopeSwagger}}
+				req = req.WithContext(domain.ContextWithAPIKey(req.Context(), want))
+			}
+			rec := httptest.NewRecorder()
+			c := echo.New().NewContext(req, rec)
+			handler := APIKey(keys, tt.scope)(func(c echo.Context) error {
+				if key, ok := domain.APIKeyFromContext(c.Request().Context()); !ok || key.ID != want.ID {
+					t.Errorf("key in context = %+v, %v", key, ok)
+				}
+				return c.NoContent(http.StatusOK)
+			})
+
+			status := http.StatusOK
+			if err := handler(c); err != nil {
+				he, ok := err.(*echo.HTTPError)
+				if !ok {
+					t.Fatalf("unexpected error %v", err)
+				}
+				status = he.Code
+			}
+			if status != tt.want {
+				t.Errorf("status = %d, want %d", status, tt.want)
+			}
+		})
+	}
+}
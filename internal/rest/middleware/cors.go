@@ -3,30 +3,158 @@ package middleware
 import (
 	"github.com/labstack/echo/v4"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 )
 
-// CORSWithConfig - конфигурируемая версия middleware для CORS
-func CORS(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		// Разрешаем доступ с любых доменов
-		c.Response().Header().Set("Access-Control-Allow-Origin", "*")
+// CORSPolicy describes which origins browsers let call the API and how.
+type CORSPolicy struct {
+	// AllowOrigins lists exact origins (https://app.example.com),
+	// subdomains (https://*.example.com) or "*" for any origin.
+	AllowOrigins []string
+	// AllowCredentials lets browsers send cookies and the Authorization
+	// header.
+	AllowCredentials bool
+	// AllowHeaders and ExposeHeaders are the headers a script may send and
+	// read.
+	AllowHeaders  []string
+	ExposeHeaders []string
+	// MaxAge is how long a browser may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// corsPolicy is a CORSPolicy prepared for checking requests.
+type corsPolicy struct {
+	allowAny      bool
+	exact         []string
+	suffixes      []string // ".example.com" for https://*.example.com
+	schemes       []string // schemes of suffixes
+	credentials   bool
+	allowHeaders  string
+	exposeHeaders string
+	maxAge        string
+}
+
+func compileCORS(p CORSPolicy) corsPolicy {
+	compiled := corsPolicy{
+		credentials:   p.AllowCredentials,
+		allowHeaders:  strings.Join(p.AllowHeaders, ", "),
+		exposeHeaders: strings.Join(p.ExposeHeaders, ", "),
+		maxAge:        strconv.Itoa(int(p.MaxAge.Seconds())),
+	}
+	for _, origin := range p.AllowOrigins {
+		origin = strings.ToLower(origin)
+		if origin == "*" {
+			compiled.allowAny = true
+			continue
+		}
+		if scheme, host, ok := strings.Cut(origin, "://*."); ok {
+			compiled.schemes = append(compiled.schemes, scheme)
+			compiled.suffixes = append(compiled.suffixes, "."+host)
+			continue
+		}
+		compiled.exact = append(compiled.exact, origin)
+	}
+	return compiled
+}
+
+// allows reports whether origin is allowed.
+func (p corsPolicy) allows(origin string) bool {
+	if p.allowAny {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if slices.Contains(p.exact, origin) {
+		return true
+	}
+	scheme, host, ok := strings.Cut(origin, "://")
+	if !ok {
+		return false
+	}
+	for i, suffix := range p.suffixes {
+		// *.example.com matches subdomains only, not example.com itself
+		if p.schemes[i] == scheme && strings.HasSuffix(host, suffix) && len(host) > len(suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS lets browsers call the API as policy allows. groups overrides the
+// policy for requests whose path starts with the key; the longest matching
+// prefix wins.
+func CORS(policy CORSPolicy, groups map[string]CORSPolicy) echo.MiddlewareFunc {
+	base := compileCORS(policy)
+	prefixes := make([]string, 0, len(groups))
+	compiled := make(map[string]corsPolicy, len(groups))
+	for prefix, group := range groups {
+		prefixes = append(prefixes, prefix)
+		compiled[prefix] = compileCORS(group)
+	}
+	// Longer prefixes are checked first
+	slices.SortFunc(prefixes, func(a, b string) int { return len(b) - len(a) })
 
-		// Указываем, какие методы разрешены
-		c.Response().Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p := base
+			path := c.Request().URL.Path
+			for _, prefix := range prefixes {
+				if strings.HasPrefix(path, prefix) {
+					p = compiled[prefix]
+					break
+				}
+			}
 
-		// Указываем, какие заголовки разрешены
-		c.Response().Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			header := c.Response().Header()
+			origin := c.Request().Header.Get(echo.HeaderOrigin)
+			// The response depends on the origin, caches must know it
+			header.Add(echo.HeaderVary, echo.HeaderOrigin)
+			if origin == "" || !p.allows(origin) {
+				// Not a browser request or a foreign origin: without CORS
+				// headers the browser won't hand the response to the script
+				return next(c)
+			}
 
-		// Разрешаем использовать credentials (если нужно)
-		// c.Response().Header().Set("Access-Control-Allow-Credentials", "true")
+			if p.allowAny && !p.credentials {
+				header.Set(echo.HeaderAccessControlAllowOrigin, "*")
+			} else {
+				header.Set(echo.HeaderAccessControlAllowOrigin, origin)
+			}
+			if p.credentials {
+				header.Set(echo.HeaderAccessControlAllowCredentials, "true")
+			}
 
-		// Обработка Preflight запроса (OPTIONS)
-		if c.Request().Method == http.MethodOptions {
-			// Для OPTIONS запроса возвращаем успешный ответ с нужными заголовками
+			req := c.Request()
+			requested := req.Header.Get(echo.HeaderAccessControlRequestMethod)
+			if req.Method != http.MethodOptions || requested == "" {
+				// Simple request
+				if p.exposeHeaders != "" {
+					header.Set(echo.HeaderAccessControlExposeHeaders, p.exposeHeaders)
+				}
+				return next(c)
+			}
+
+			// Preflight. The allowed methods come from the route: the router
+			// stores them when the path matches but has no OPTIONS handler.
+			// An unknown path has none and the request gets a 404.
+			allowed, _ := c.Get(echo.ContextKeyHeaderAllow).(string)
+			if allowed == "" {
+				return next(c)
+			}
+			if !slices.Contains(strings.Split(allowed, ", "), strings.ToUpper(requested)) {
+				// Unsupported method: respond without allowing it
+				return c.NoContent(http.StatusNoContent)
+			}
+			header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestMethod)
+			header.Add(echo.HeaderVary, echo.HeaderAccessControlRequestHeaders)
+			header.Set(echo.HeaderAccessControlAllowMethods, allowed)
+			if p.allowHeaders != "" {
+				header.Set(echo.HeaderAccessControlAllowHeaders, p.allowHeaders)
+			}
+			header.Set(echo.HeaderAccessControlMaxAge, p.maxAge)
 			return c.NoContent(http.StatusNoContent)
 		}
-
-		// Продолжаем выполнение запроса
-		return next(c)
 	}
 }
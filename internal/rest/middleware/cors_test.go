@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	e := echo.New()
+	e.Use(CORS(CORSPolicy{
+		AllowOrigins:  []string{"https://app.example.com", "https://*.example.org"},
+		AllowHeaders:  []string{"Authorization", "Content-Type"},
+		ExposeHeaders: []string{"ETag"},
+		MaxAge:        10 * time.Minute,
+	}, map[string]CORSPolicy{
+		"/api/v1/payments": {
+			AllowOrigins:     []string{"https://pay.example.com"},
+			AllowCredentials: true,
+			MaxAge:           time.Minute,
+		},
+		"/api": {AllowOrigins: []string{"*"}},
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.GET("/profile", ok)
+	e.PATCH("/profile", ok)
+	e.POST("/api/v1/payments", ok)
+	e.GET("/api/v1/tariffs", ok)
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		origin      string
+		preflight   string
+		wantStatus  int
+		wantOrigin  string
+		wantMethods string
+		wantHeaders map[string]string
+	}{
+		{
+			name: "allowed origin", method: http.MethodGet, path: "/profile", origin: "https://app.example.com",
+			wantStatus: http.StatusOK, wantOrigin: "https://app.example.com",
+			wantHeaders: map[string]string{"Access-Control-Expose-Headers": "ETag"},
+		},
+		{
+			name: "other origin", method: http.MethodGet, path: "/profile", origin: "https://evil.example.com",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "no origin", method: http.MethodGet, path: "/profile",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "wildcard subdomain", method: http.MethodGet, path: "/profile", origin: "https://a.b.example.org",
+			wantStatus: http.StatusOK, wantOrigin: "https://a.b.example.org",
+		},
+		{
+			name: "wildcard does not match the domain itself", method: http.MethodGet, path: "/profile", origin: "https://example.org",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "wildcard keeps the scheme", method: http.MethodGet, path: "/profile", origin: "http://a.example.org",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "preflight for patch", method: http.MethodOptions, path: "/profile", origin: "https://app.example.com", preflight: http.MethodPatch,
+			wantStatus: http.StatusNoContent, wantOrigin: "https://app.example.com", wantMethods: "OPTIONS, GET, PATCH",
+			wantHeaders: map[string]string{"Access-Control-Allow-Headers": "Authorization, Content-Type", "Access-Control-Max-Age": "600"},
+		},
+		{
+			name: "preflight for a method the route lacks", method: http.MethodOptions, path: "/profile", origin: "https://app.example.com", preflight: http.MethodDelete,
+			wantStatus: http.StatusNoContent, wantOrigin: "https://app.example.com",
+		},
+		{
+			name: "preflight from other origin", method: http.MethodOptions, path: "/profile", origin: "https://evil.example.com", preflight: http.MethodPatch,
+			wantStatus: http.StatusNoContent,
+		},
+		{
+			name: "preflight for a missing route", method: http.MethodOptions, path: "/nope", origin: "https://app.example.com", preflight: http.MethodGet,
+			wantStatus: http.StatusNotFound, wantOrigin: "https://app.example.com",
+		},
+		{
+			name: "group with credentials", method: http.MethodOptions, path: "/api/v1/payments", origin: "https://pay.example.com", preflight: http.MethodPost,
+			wantStatus: http.StatusNoContent, wantOrigin: "https://pay.example.com", wantMethods: "OPTIONS, POST",
+			wantHeaders: map[string]string{"Access-Control-Allow-Credentials": "true", "Access-Control-Max-Age": "60"},
+		},
+		{
+			name: "longest prefix wins", method: http.MethodPost, path: "/api/v1/payments", origin: "https://app.example.com",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "group allowing any origin", method: http.MethodGet, path: "/api/v1/tariffs", origin: "https://evil.example.com",
+			wantStatus: http.StatusOK, wantOrigin: "*",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.preflight != "" {
+				req.Header.Set("Access-Control-Request-Method", tt.preflight)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Methods"); got != tt.wantMethods {
+				t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, tt.wantMethods)
+			}
+			for name, want := range tt.wantHeaders {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
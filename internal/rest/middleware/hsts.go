@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"time"
+)
+
+// HSTS tells browsers to reach the API over HTTPS only for maxAge. The
+// header is only sent over HTTPS, as browsers ignore it on plain HTTP.
+func HSTS(maxAge time.Duration, includeSubdomains bool) echo.MiddlewareFunc {
+	value := fmt.Sprintf("max-age=%d", int64(maxAge.Seconds()))
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if c.Request().TLS != nil {
+				c.Response().Header().Set("Strict-Transport-Security", value)
+			}
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"github.com/labstack/echo/v4"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHSTS(t *testing.T) {
+	tests := []struct {
+		name       string
+		tls        bool
+		subdomains bool
+		want       string
+	}{
+		{"https", true, false, "max-age=31536000"},
+		{"https with subdomains", true, true, "max-age=31536000; includeSubDomains"},
+		{"plain http", false, true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			rec := httptest.NewRecorder()
+			handler := HSTS(365*24*time.Hour, tt.subdomains)(func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			})
+			if err := handler(echo.New().NewContext(req, rec)); err != nil {
+				t.Fatal(err)
+			}
+			if got := rec.Header().Get("Strict-Transport-Security"); got != tt.want {
+				t.Errorf("Strict-Transport-Security = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
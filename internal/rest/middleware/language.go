@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/internal/i18n"
+)
+
+// Language negotiates the response language from Accept-Language and stores
+// the localizer in the request context.
+func Language(bundle *i18n.Bundle) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			lang := bundle.Negotiate(c.Request().Header.Get("Accept-Language"))
+			localizer := bundle.Localizer(lang)
+
+			ctx := i18n.WithLocalizer(c.Request().Context(), localizer)
+			c.SetRequest(c.Request().WithContext(ctx))
+			c.Response().Header().Set("Content-Language", localizer.Language())
+			c.Response().Header().Add("Vary", "Accept-Language")
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+// validRequestID limits client supplied IDs to something safe to log.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID stores the ID of the request in its context so every log line,
+// error body and billing call made while serving it can be correlated, and
+// returns it in the response. A well-formed ID sent by the client is kept,
+// otherwise a new one is generated.
+func RequestID() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := c.Request().Header.Get(logging.RequestIDHeader)
+			if !validRequestID.MatchString(id) {
+				id = newRequestID()
+			}
+			c.Response().Header().Set(logging.RequestIDHeader, id)
+			ctx := logging.ContextWithRequestID(c.Request().Context(), id)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// AccessLog logs every request once it has been served. The route pattern
+// is logged instead of the URL, which may carry personal data.
+func AccessLog(logger *slog.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				// Let echo write the error response so its status is known.
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			level := slog.LevelInfo
+			switch {
+			case status >= 500:
+				level = slog.LevelError
+			case status >= 400:
+				level = slog.LevelWarn
+			}
+			attrs := []any{
+				"method", c.Request().Method,
+				"route", c.Path(),
+				"status", status,
+				"duration", time.Since(start),
+				"remote_ip", c.RealIP(),
+			}
+			if err != nil {
+				attrs = append(attrs, "error", err)
+			}
+			logger.Log(c.Request().Context(), level, "Request served", attrs...)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantSame bool
+	}{
+		{"kept", "abc-123", true},
+		{"missing", "", false},
+		{"unsafe", "abc\n123", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(logging.RequestIDHeader, tt.header)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			var got string
+			handler := RequestID()(func(c echo.Context) error {
+				got = logging.RequestIDFromContext(c.Request().Context())
+				return nil
+			})
+			if err := handler(c); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantSame && got != tt.header {
+				t.Errorf("request ID = %q, want %q", got, tt.header)
+			}
+			if !tt.wantSame && (got == "" || got == tt.header) {
+				t.Errorf("request ID = %q, want a generated one", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
+	"net/http"
+	"time"
+)
+
+// unmatchedRoute labels requests that matched no route, so probes for
+// random URLs don't create a series each.
+const unmatchedRoute = "unmatched"
+
+// Metrics counts every request and observes its latency by method, route
+// pattern and status.
+func Metrics() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			start := time.Now()
+			err := next(c)
+			if err != nil {
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" || c.Response().Status == http.StatusNotFound && route == "/*" {
+				route = unmatchedRoute
+			}
+			metrics.ObserveRequest(c.Request().Method, route, c.Response().Status, time.Since(start))
+			return nil
+		}
+	}
+}
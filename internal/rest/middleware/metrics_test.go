@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	e := echo.New()
+	e.Use(Metrics())
+	e.GET("/metrics-test/:id", func(c echo.Context) error {
+		if c.Param("id") == "missing" {
+			return echo.NewHTTPError(http.StatusNotFound)
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, target := range []string{"/metrics-test/1", "/metrics-test/2", "/metrics-test/missing", "/no-such-route/x"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+	out := string(body)
+
+	for _, want := range []string{
+		`spektr_http_requests_total{method="GET",route="/metrics-test/:id",status="200"} 2`,
+		`spektr_http_requests_total{method="GET",route="/metrics-test/:id",status="404"} 1`,
+		`spektr_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`spektr_http_request_duration_seconds_count{method="GET",route="/metrics-test/:id",status="200"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics missing %s", want)
+		}
+	}
+	if strings.Contains(out, "/no-such-route") {
+		t.Error("unmatched URL used as a label")
+	}
+}
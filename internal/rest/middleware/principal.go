@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/i18n"
+	"net/http"
+	"strings"
+)
+
+// TokenParser verifies a bearer token and returns the principal it carries.
+type TokenParser interface {
+	Parse(token string) (domain.Principal, error)
+}
+
+// Principal authenticates the bearer token, when there is one, and stores
+// the principal in the request context. Requests without an Authorization
+// header pass through; handlers that need a session reject them.
+func Principal(parser TokenParser) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			authHeader := c.Request().Header.Get("Authorization")
+			if authHeader == "" {
+				return next(c)
+			}
+
+			token := strings.TrimSpace(strings.TrimPrefix(authHeader, "Bearer "))
+			principal, err := parser.Parse(token)
+			if err != nil {
+				ctx := c.Request().Context()
+				return echo.NewHTTPError(http.StatusUnauthorized, i18n.FromContext(ctx).T("request.invalid_token"))
+			}
+
+			ctx := domain.ContextWithPrincipal(c.Request().Context(), principal)
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
+	"github.com/llchhh/spektr-account-api/internal/ratelimit"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIKeyIdentifier finds the active key for a secret without recording its
+// use.
+type APIKeyIdentifier interface {
+	Identify(ctx context.Context, secret string) (domain.APIKey, error)
+}
+
+// RateLimitRule sets a separate quota for requests whose path starts with
+// Prefix and whose method is in Methods (empty for any method).
+type RateLimitRule struct {
+	Prefix  string
+	Methods []string
+	Limit   ratelimit.Limit
+}
+
+// name names the quota in bucket keys and metrics.
+func (r RateLimitRule) name() string {
+	if len(r.Methods) == 0 {
+		return r.Prefix
+	}
+	return strings.Join(r.Methods, ",") + " " + r.Prefix
+}
+
+// RateLimit limits how often each client may call the API. A client is its
+// API key, else its session, else its IP address, so the middleware must run
+// after Principal. Requests matching no rule count against the fallback
+// quota. When the store is unavailable requests are let through.
+func RateLimit(store ratelimit.Store, fallback ratelimit.Limit, rules []RateLimitRule, keys APIKeyIdentifier, logger *slog.Logger) echo.MiddlewareFunc {
+	rules = slices.Clone(rules)
+	// Longer prefixes first, then rules with methods among equal ones
+	slices.SortStableFunc(rules, func(a, b RateLimitRule) int {
+		if len(a.Prefix) != len(b.Prefix) {
+			return len(b.Prefix) - len(a.Prefix)
+		}
+		return len(b.Methods) - len(a.Methods)
+	})
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			quota, limit := "default", fallback
+			for _, rule := range rules {
+				if strings.HasPrefix(req.URL.Path, rule.Prefix) &&
+					(len(rule.Methods) == 0 || slices.Contains(rule.Methods, req.Method)) {
+					quota, limit = rule.name(), rule.Limit
+					break
+				}
+			}
+			if limit.Unlimited() {
+				return next(c)
+			}
+
+			kind, client := rateLimitClient(c, keys)
+			ctx := req.Context()
+			res, err := store.Take(ctx, quota+"|"+kind+":"+client, limit)
+			if err != nil {
+				// Better to let the request through than to refuse every client
+				logger.WarnContext(ctx, "Rate limit store failed, request let through", "error", err)
+				return next(c)
+			}
+
+			header := c.Response().Header()
+			header.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+			header.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			header.Set("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(res.Reset), 10))
+			if !res.Allowed {
+				metrics.RateLimited(quota, kind)
+				logger.InfoContext(ctx, "Rate limit exceeded", "quota", quota, "client", kind)
+				return &domain.RateLimitError{RetryAfter: res.RetryAfter}
+			}
+			return next(c)
+		}
+	}
+}
+
+// rateLimitClient tells whose quota the request spends. An unverified key or
+// token doesn't count, otherwise random values would dodge the quota. A
+// verified key is stored in the request context for the APIKey middleware.
+func rateLimitClient(c echo.Context, keys APIKeyIdentifier) (kind, id string) {
+	ctx := c.Request().Context()
+	if secret := c.Request().Header.Get("X-API-Key"); secret != "" && keys != nil {
+		if key, err := keys.Identify(ctx, secret); err == nil {
+			c.SetRequest(c.Request().WithContext(domain.ContextWithAPIKey(ctx, key)))
+			return "api_key", key.ID
+		}
+	}
+	// Principal has already verified the token signature
+	if principal, ok := domain.PrincipalFromContext(ctx); ok {
+		sum := sha256.Sum256([]byte(principal.Token))
+		return "session", hex.EncodeToString(sum[:16])
+	}
+	return "ip", c.RealIP()
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
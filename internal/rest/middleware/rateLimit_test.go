@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/ratelimit"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type identifyKeys map[string]domain.APIKey
+
+func (f identifyKeys) Identify(_ context.Context, secret string) (domain.APIKey, error) {
+	key, ok := f[secret]
+	if !ok {
+		return domain.APIKey{}, domain.ErrUnauthorized
+	}
+	return key, nil
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+type request struct {
+	method  string
+	path    string
+	ip      string
+	apiKey  string
+	session string
+}
+
+func serve(t *testing.T, mw echo.MiddlewareFunc, r request) (*httptest.ResponseRecorder, error) {
+	t.Helper()
+	req := httptest.NewRequest(r.method, r.path, nil)
+	req.RemoteAddr = r.ip + ":1234"
+	if r.apiKey != "" {
+		req.Header.Set("X-API-Key", r.apiKey)
+	}
+	if r.session != "" {
+		req = req.WithContext(domain.ContextWithPrincipal(req.Context(), domain.Principal{Token: r.session}))
+	}
+	rec := httptest.NewRecorder()
+	err := mw(func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})(echo.New().NewContext(req, rec))
+	return rec, err
+}
+
+func TestRateLimit(t *testing.T) {
+	// Rates are low enough for buckets not to refill during the test.
+	limit := func(burst int) ratelimit.Limit { return ratelimit.Limit{Rate: 0.001, Burst: burst} }
+	rules := []RateLimitRule{
+		{Prefix: "/healthz"},
+		{Prefix: "/api/v1/auth", Limit: limit(1)},
+		{Prefix: "/api/v1/profile", Methods: []string{http.MethodGet}, Limit: limit(3)},
+	}
+	keys := identifyKeys{"valid": {ID: "k1"}}
+
+	get := func(path, ip string) request { return request{method: http.MethodGet, path: path, ip: ip} }
+	tests := []struct {
+		name string
+		// requests are made in order; all but the last must pass.
+		requests []request
+		wantPass bool
+	}{
+		{"default quota", []request{get("/api/v1/usage", "10.0.0.1"), get("/api/v1/usage", "10.0.0.1"), get("/api/v1/usage", "10.0.0.1")}, false},
+		{"strict group", []request{{method: http.MethodPost, path: "/api/v1/auth/login", ip: "10.0.0.1"}, {method: http.MethodPost, path: "/api/v1/auth/login", ip: "10.0.0.1"}}, false},
+		{"generous reads", []request{get("/api/v1/profile", "10.0.0.1"), get("/api/v1/profile", "10.0.0.1"), get("/api/v1/profile", "10.0.0.1")}, true},
+		{"writes fall back to the default", []request{
+			{method: http.MethodPut, path: "/api/v1/profile", ip: "10.0.0.1"},
+			{method: http.MethodPut, path: "/api/v1/profile", ip: "10.0.0.1"},
+			{method: http.MethodPut, path: "/api/v1/profile", ip: "10.0.0.1"},
+		}, false},
+		{"groups count apart", []request{get("/api/v1/usage", "10.0.0.1"), get("/api/v1/usage", "10.0.0.1"), {method: http.MethodPost, path: "/api/v1/auth/login", ip: "10.0.0.1"}}, true},
+		{"clients count apart", []request{get("/api/v1/usage", "10.0.0.1"), get("/api/v1/usage", "10.0.0.1"), get("/api/v1/usage", "10.0.0.2")}, true},
+		{"sessions count apart from their IP", []request{
+			get("/api/v1/usage", "10.0.0.1"), get("/api/v1/usage", "10.0.0.1"),
+			{method: http.MethodGet, path: "/api/v1/usage", ip: "10.0.0.1", session: "s1"},
+		}, true},
+		{"api keys count apart from their IP", []request{
+			get("/api/v1/usage", "10.0.0.1"), get("/api/v1/usage", "10.0.0.1"),
+			{method: http.MethodGet, path: "/api/v1/usage", ip: "10.0.0.1", apiKey: "valid"},
+		}, true},
+		{"unknown api keys count as the IP", []request{
+			get("/api/v1/usage", "10.0.0.1"), get("/api/v1/usage", "10.0.0.1"),
+			{method: http.MethodGet, path: "/api/v1/usage", ip: "10.0.0.1", apiKey: "random"},
+		}, false},
+		{"unlimited", []request{get("/healthz", "10.0.0.1"), get("/healthz", "10.0.0.1"), get("/healthz", "10.0.0.1")}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mw := RateLimit(ratelimit.NewMemoryStore(), limit(2), rules, keys, logging.Discard())
+			last := len(tt.requests) - 1
+			for i, r := range tt.requests[:last] {
+				if _, err := serve(t, mw, r); err != nil {
+					t.Fatalf("request %d: %v", i, err)
+				}
+			}
+			_, err := serve(t, mw, tt.requests[last])
+			if passed := err == nil; passed != tt.wantPass {
+				t.Errorf("last request passed = %v (%v), want %v", passed, err, tt.wantPass)
+			}
+		})
+	}
+}
+
+func TestRateLimitStoresIdentifiedKey(t *testing.T) {
+	mw := RateLimit(ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 1, Burst: 1}, nil, identifyKeys{"valid": {ID: "k1"}}, logging.Discard())
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/usage", nil)
+	req.Header.Set("X-API-Key", "valid")
+	err := mw(func(c echo.Context) error {
+		if key, ok := domain.APIKeyFromContext(c.Request().Context()); !ok || key.ID != "k1" {
+			t.Errorf("key in context = %+v, %v", key, ok)
+		}
+		return nil
+	})(echo.New().NewContext(req, httptest.NewRecorder()))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	mw := RateLimit(ratelimit.NewMemoryStore(), ratelimit.Limit{Rate: 0.5, Burst: 2}, nil, nil, logging.Discard())
+	r := request{method: http.MethodGet, path: "/api/v1/usage", ip: "10.0.0.1"}
+
+	rec, err := serve(t, mw, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"X-RateLimit-Limit": "2", "X-RateLimit-Remaining": "1", "X-RateLimit-Reset": "2"} {
+		if got := rec.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	serve(t, mw, r)
+	rec, err = serve(t, mw, r)
+	var limited *domain.RateLimitError
+	if !errors.As(err, &limited) || !errors.Is(err, domain.ErrTooManyRequests) {
+		t.Fatalf("error = %v, want a RateLimitError", err)
+	}
+	if limited.RetryAfter <= 0 || limited.RetryAfter > 2*time.Second {
+		t.Errorf("RetryAfter = %v", limited.RetryAfter)
+	}
+	if got := rec.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+}
+
+func TestRateLimitStoreDown(t *testing.T) {
+	mw := RateLimit(failingStore{}, ratelimit.Limit{Rate: 1, Burst: 1}, nil, nil, logging.Discard())
+	if _, err := serve(t, mw, request{method: http.MethodGet, path: "/", ip: "10.0.0.1"}); err != nil {
+		t.Errorf("request failed with the store down: %v", err)
+	}
+}
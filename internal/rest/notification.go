@@ -5,7 +5,6 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/llchhh/spektr-account-api/domain"
 	"net/http"
-	"strings"
 )
 
 // NotificationHandler handles notification-related requests.
@@ -39,19 +38,12 @@ func NewNotificationHandler(e *echo.Echo, svc NotificationService) {
 // @Failure 500 {object} ResponseError "Internal server error"
 // @Router /api/v1/notifications [get]
 func (h *NotificationHandler) GetNotifications(c echo.Context) error {
-	// Extract the Authorization header
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: "Authorization token is required",
-		})
+	// The principal middleware has already verified the bearer token
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
 	}
 
-	// Check if the header starts with "Bearer "
-	const bearerPrefix = "Bearer "
-
-	token := strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix))
-
 	notifications, err := h.Service.GetNotifications(c.Request().Context(), token)
 	if err != nil {
 		return handleError(c, err)
@@ -0,0 +1,205 @@
+package rest
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// PaymentHandler handles payment-related requests.
+type PaymentHandler struct {
+	Service      PaymentService
+	TopUpService TopUpService
+}
+
+// PaymentService defines the interface for payment services.
+type PaymentService interface {
+	Payments(ctx context.Context, token string, filter domain.PaymentFilter) (domain.PaymentPage, error)
+}
+
+// TopUpService defines the interface for online top-up services.
+type TopUpService interface {
+	CreateTopUp(ctx context.Context, token, idempotencyKey string, topUp domain.TopUp) (domain.PaymentIntent, error)
+	TopUp(ctx context.Context, token, id string) (domain.PaymentIntent, error)
+	HandleWebhook(ctx context.Context, header http.Header, body []byte) error
+}
+
+// NewPaymentHandler initializes the payment handler with the given services and routes.
+// The top-up routes are left out when topUpSvc is nil.
+func NewPaymentHandler(e *echo.Echo, svc PaymentService, topUpSvc TopUpService) {
+	handler := &PaymentHandler{
+		Service:      svc, // Initialize the handler with the service
+		TopUpService: topUpSvc,
+	}
+	paymentGroup := e.Group("/api/v1/payments")
+	paymentGroup.GET("", handler.Payments) // Retrieve payment history
+	if topUpSvc == nil {
+		return
+	}
+	paymentGroup.POST("/top-up", handler.CreateTopUp)      // Start an online top-up
+	paymentGroup.GET("/top-up/:id", handler.TopUp)         // Check a top-up status
+	paymentGroup.POST("/webhook", handler.ProviderWebhook) // Payment provider callbacks
+}
+
+// Payments handles the request to get the payment history for a user.
+// @Summary Get payment history
+// @Description Retrieve the payment history of the authenticated user within a date range
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param from query string false "Start date (YYYY-MM-DD), defaults to 90 days before 'to'"
+// @Param to query string false "End date (YYYY-MM-DD), defaults to today"
+// @Param page query int false "Page number, starting from 1"
+// @Param per_page query int false "Items per page (max 100)"
+// @Success 200 {object} domain.PaymentPage "Page of payments"
+// @Failure 400 {object} ResponseError "Invalid request"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/payments [get]
+func (h *PaymentHandler) Payments(c echo.Context) error {
+	// The principal middleware has already verified the bearer token
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	filter, err := parsePaymentFilter(c)
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_query"))
+	}
+
+	payments, err := h.Service.Payments(c.Request().Context(), token, filter)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	// Return the payment history
+	return c.JSON(http.StatusOK, payments)
+}
+
+// CreateTopUp handles the request to start an online top-up.
+// @Summary Create an online top-up
+// @Description Create a payment intent for the given amount, or for the amount due when it is omitted. Repeating the request with the same Idempotency-Key returns the same intent, or starts a new payment for it when the previous one was canceled.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param Idempotency-Key header string false "Client-generated key that makes retries safe"
+// @Param redirect query bool false "Redirect to the confirmation URL instead of returning it"
+// @Param topUp body domain.TopUp true "Top-up request"
+// @Success 201 {object} domain.PaymentIntent "Created payment intent"
+// @Success 303 "Redirect to the provider's confirmation page"
+// @Failure 400 {object} ResponseError "Invalid request"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 409 {object} ResponseError "A request with the same Idempotency-Key is still in progress"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/payments/top-up [post]
+func (h *PaymentHandler) CreateTopUp(c echo.Context) error {
+	// The principal middleware has already verified the bearer token
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	var topUp domain.TopUp
+	if err := c.Bind(&topUp); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	idempotencyKey := c.Request().Header.Get("Idempotency-Key")
+	intent, err := h.TopUpService.CreateTopUp(c.Request().Context(), token, idempotencyKey, topUp)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	if redirect, _ := strconv.ParseBool(c.QueryParam("redirect")); redirect {
+		return c.Redirect(http.StatusSeeOther, intent.ConfirmationURL)
+	}
+	return c.JSON(http.StatusCreated, intent)
+}
+
+// TopUp handles the request to check an online top-up.
+// @Summary Get an online top-up
+// @Description Retrieve the status of a payment intent created by the authenticated user
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param id path string true "Payment intent ID"
+// @Success 200 {object} domain.PaymentIntent "Payment intent"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Not found"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/payments/top-up/{id} [get]
+func (h *PaymentHandler) TopUp(c echo.Context) error {
+	// The principal middleware has already verified the bearer token
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	intent, err := h.TopUpService.TopUp(c.Request().Context(), token, c.Param("id"))
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, intent)
+}
+
+// ProviderWebhook handles payment notifications sent by the payment provider.
+// @Summary Payment provider webhook
+// @Description Receives signed payment status notifications from the payment provider
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]string "Webhook accepted"
+// @Failure 400 {object} ResponseError "Invalid webhook"
+// @Failure 403 {object} ResponseError "Invalid signature"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/payments/webhook [post]
+func (h *PaymentHandler) ProviderWebhook(c echo.Context) error {
+	// The raw body is needed to verify the signature
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, 1<<20))
+	if err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	if err := h.TopUpService.HandleWebhook(c.Request().Context(), c.Request().Header, body); err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, map[string]string{
+		"status": "ok",
+	})
+}
+
+// parsePaymentFilter reads the date range and pagination from the query string.
+func parsePaymentFilter(c echo.Context) (domain.PaymentFilter, error) {
+	var filter domain.PaymentFilter
+	var err error
+
+	if from := c.QueryParam("from"); from != "" {
+		if filter.From, err = time.ParseInLocation(time.DateOnly, from, time.Local); err != nil {
+			return filter, err
+		}
+	}
+	if to := c.QueryParam("to"); to != "" {
+		if filter.To, err = time.ParseInLocation(time.DateOnly, to, time.Local); err != nil {
+			return filter, err
+		}
+	}
+	if page := c.QueryParam("page"); page != "" {
+		if filter.Page, err = strconv.Atoi(page); err != nil {
+			return filter, err
+		}
+	}
+	if perPage := c.QueryParam("per_page"); perPage != "" {
+		if filter.PerPage, err = strconv.Atoi(perPage); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
@@ -2,8 +2,10 @@ package rest
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/labstack/echo/v4"
 	"github.com/llchhh/spektr-account-api/domain"
+	"mime"
 	"net/http"
 	"strings"
 )
@@ -16,9 +18,12 @@ type ProfileHandler struct {
 // ProfileService defines the interface for profile services.
 type ProfileService interface {
 	Profile(ctx context.Context, token string) (domain.Profile, error)
+	UpdateProfile(ctx context.Context, token string, update domain.ProfileUpdate, ifMatch string) (domain.Profile, error)
 	ChangePassword(ctx context.Context, token string, newPassword string) error
 	ChangeEmail(ctx context.Context, token string, newEmail string) error
 	ChangePhone(ctx context.Context, token string, newPhone string) error
+	PromisedPaymentOffer(ctx context.Context, token string) (domain.PromisedPaymentOffer, error)
+	CreatePromisedPayment(ctx context.Context, token string, amount domain.Money) (domain.PromisedPayment, error)
 }
 
 // NewProfileHandler initializes the profile handler with the given service and routes.
@@ -28,9 +33,12 @@ func NewProfileHandler(e *echo.Echo, svc ProfileService) {
 	}
 	profileGroup := e.Group("/api/v1/profile")
 	profileGroup.GET("", handler.Profile)
+	profileGroup.PATCH("", handler.UpdateProfile)
 	profileGroup.POST("/change-password", handler.ChangePassword)
 	profileGroup.POST("/change-email", handler.ChangeEmail)
 	profileGroup.POST("/change-phone", handler.ChangePhone)
+	profileGroup.GET("/promised-payment", handler.PromisedPaymentOffer)
+	profileGroup.POST("/promised-payment", handler.CreatePromisedPayment)
 }
 
 // GetProfile handles the GET /profile endpoint.
@@ -41,32 +49,92 @@ func NewProfileHandler(e *echo.Echo, svc ProfileService) {
 // @Produce json
 // @Param Authorization header string true "Authorization token (Bearer <token>)"
 // @Success 200 {object} domain.Profile "User profile"
+// @Param If-None-Match header string false "ETag of the profile the client already has"
+// @Header 200 {string} ETag "Version of the profile for If-Match and If-None-Match"
+// @Success 304 "Profile has not changed"
 // @Failure 400 {object} ResponseError "Invalid request"
 // @Failure 401 {object} ResponseError "Unauthorized"
 // @Router /api/v1/profile [get]
 func (h *ProfileHandler) Profile(c echo.Context) error {
-	// Extract the Authorization header
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: "Authorization token is required",
-		})
+	// The principal middleware has already verified the bearer token
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
 	}
 
-	// Check if the header starts with "Bearer "
-	const bearerPrefix = "Bearer "
-
-	token := strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix))
-
 	profile, err := h.Service.Profile(c.Request().Context(), token)
 	if err != nil {
 		return handleError(c, err)
 	}
 
+	// Clients may keep the profile but must revalidate it, which is cheap
+	// while the profile is cached.
+	etag := profile.ETag()
+	c.Response().Header().Set("ETag", etag)
+	c.Response().Header().Set("Cache-Control", "private, no-cache")
+	if etagListed(c.Request().Header.Get("If-None-Match"), etag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
 	// Return the profile data
 	return c.JSON(http.StatusOK, profile)
 }
 
+// UpdateProfile handles the PATCH /profile endpoint.
+// @Summary Update user profile
+// @Description Change the email and phone in one request. The body is a JSON Merge Patch: absent fields stay, a null email removes it.
+// @Tags Profile
+// @Accept application/merge-patch+json
+// @Produce json
+// @Param Authorization header string true "Authorization token (Bearer <token>)"
+// @Param If-Match header string false "ETag of the profile the change is based on"
+// @Param update body domain.ProfileUpdate true "Fields to change"
+// @Success 200 {object} domain.Profile "Updated profile"
+// @Header 200 {string} ETag "Version of the updated profile"
+// @Failure 400 {object} ResponseError "Invalid patch or field values"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 412 {object} ResponseError "Profile changed since it was read"
+// @Failure 415 {object} ResponseError "Unsupported content type"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/profile [patch]
+func (h *ProfileHandler) UpdateProfile(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(c.Request().Header.Get(echo.HeaderContentType))
+	if mediaType != "application/merge-patch+json" && mediaType != echo.MIMEApplicationJSON {
+		return respondError(c, http.StatusUnsupportedMediaType, localize(c, "request.unsupported_media_type"))
+	}
+
+	var update domain.ProfileUpdate
+	if err := json.NewDecoder(c.Request().Body).Decode(&update); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	ifMatch := c.Request().Header.Get("If-Match")
+	profile, err := h.Service.UpdateProfile(c.Request().Context(), token, update, ifMatch)
+	if err != nil {
+		return handleError(c, err)
+	}
+
+	c.Response().Header().Set("ETag", profile.ETag())
+	c.Response().Header().Set("Cache-Control", "private, no-cache")
+	return c.JSON(http.StatusOK, profile)
+}
+
+// etagListed reports whether a conditional header lists etag or is "*".
+func etagListed(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // ChangePassword handles the POST /profile/change-password endpoint.
 // @Summary Change user password
 // @Description Change the password for the authenticated user
@@ -81,22 +149,13 @@ func (h *ProfileHandler) Profile(c echo.Context) error {
 // @Failure 500 {object} ResponseError "Internal server error"
 // @Router /api/v1/profile/change-password [post]
 func (h *ProfileHandler) ChangePassword(c echo.Context) error {
-	// Extract the Authorization header
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: "Authorization token is required",
-		})
+	// The principal middleware has already verified the bearer token
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
 	}
-
-	// Check if the header starts with "Bearer "
-	const bearerPrefix = "Bearer "
-
-	token := strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix))
 	if token == "" {
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: "Invalid token",
-		})
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.invalid_token"))
 	}
 
 	var payload struct {
@@ -105,9 +164,7 @@ func (h *ProfileHandler) ChangePassword(c echo.Context) error {
 
 	// Bind the request payload
 	if err := c.Bind(&payload); err != nil {
-		return c.JSON(http.StatusBadRequest, ResponseError{
-			Message: "Invalid request payload",
-		})
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
 	}
 
 	// Attempt to change the password
@@ -118,7 +175,7 @@ func (h *ProfileHandler) ChangePassword(c echo.Context) error {
 
 	// Respond with success
 	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Password changed successfully",
+		"message": localize(c, "profile.password_changed"),
 	})
 }
 
@@ -136,22 +193,13 @@ func (h *ProfileHandler) ChangePassword(c echo.Context) error {
 // @Failure 500 {object} ResponseError "Internal server error"
 // @Router /api/v1/profile/change-email [post]
 func (h *ProfileHandler) ChangeEmail(c echo.Context) error {
-	// Extract the Authorization header
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: "Authorization token is required",
-		})
+	// The principal middleware has already verified the bearer token
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
 	}
-
-	// Check if the header starts with "Bearer "
-	const bearerPrefix = "Bearer "
-
-	token := strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix))
 	if token == "" {
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: "Invalid token",
-		})
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.invalid_token"))
 	}
 	var payload struct {
 		NewEmail string `json:"new_email"`
@@ -159,9 +207,7 @@ func (h *ProfileHandler) ChangeEmail(c echo.Context) error {
 
 	// Bind the request payload
 	if err := c.Bind(&payload); err != nil {
-		return c.JSON(http.StatusBadRequest, ResponseError{
-			Message: "Invalid request payload",
-		})
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
 	}
 
 	// Attempt to change the email
@@ -172,7 +218,7 @@ func (h *ProfileHandler) ChangeEmail(c echo.Context) error {
 
 	// Respond with success
 	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Email changed successfully",
+		"message": localize(c, "profile.email_changed"),
 	})
 }
 
@@ -190,22 +236,13 @@ func (h *ProfileHandler) ChangeEmail(c echo.Context) error {
 // @Failure 500 {object} ResponseError "Internal server error"
 // @Router /api/v1/profile/change-phone [post]
 func (h *ProfileHandler) ChangePhone(c echo.Context) error {
-	// Extract the Authorization header
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: "Authorization token is required",
-		})
+	// The principal middleware has already verified the bearer token
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
 	}
-
-	// Check if the header starts with "Bearer "
-	const bearerPrefix = "Bearer "
-
-	token := strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix))
 	if token == "" {
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: "Invalid token",
-		})
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.invalid_token"))
 	}
 	var payload struct {
 		NewPhone string `json:"new_phone"`
@@ -213,9 +250,7 @@ func (h *ProfileHandler) ChangePhone(c echo.Context) error {
 
 	// Bind the request payload
 	if err := c.Bind(&payload); err != nil {
-		return c.JSON(http.StatusBadRequest, ResponseError{
-			Message: "Invalid request payload",
-		})
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
 	}
 
 	// Attempt to change the email
@@ -226,6 +261,64 @@ func (h *ProfileHandler) ChangePhone(c echo.Context) error {
 
 	// Respond with success
 	return c.JSON(http.StatusOK, map[string]string{
-		"message": "Phone changed successfully",
+		"message": localize(c, "profile.phone_changed"),
 	})
 }
+
+// PromisedPaymentOffer handles the GET /profile/promised-payment endpoint.
+// @Summary Get promised payment offer
+// @Description Check whether the authenticated user can take a promised payment, for how much, and see the current one
+// @Tags Profile
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token (Bearer <token>)"
+// @Success 200 {object} domain.PromisedPaymentOffer "Promised payment offer"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/profile/promised-payment [get]
+func (h *ProfileHandler) PromisedPaymentOffer(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	offer, err := h.Service.PromisedPaymentOffer(c.Request().Context(), token)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, offer)
+}
+
+// CreatePromisedPayment handles the POST /profile/promised-payment endpoint.
+// @Summary Take a promised payment
+// @Description Restore the service for a few days on credit. The amount defaults to the maximum offered.
+// @Tags Profile
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Authorization token (Bearer <token>)"
+// @Param amount body number false "Promised payment amount"
+// @Success 201 {object} domain.PromisedPayment "Promised payment"
+// @Failure 400 {object} ResponseError "Invalid request payload"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 403 {object} ResponseError "Promised payment is not available"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/profile/promised-payment [post]
+func (h *ProfileHandler) CreatePromisedPayment(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	var payload struct {
+		Amount domain.Money `json:"amount"`
+	}
+	if err := c.Bind(&payload); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	promised, err := h.Service.CreatePromisedPayment(c.Request().Context(), token, payload.Amount)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, promised)
+}
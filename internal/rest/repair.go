@@ -6,7 +6,6 @@ import (
 	"github.com/llchhh/spektr-account-api/domain"
 	"github.com/llchhh/spektr-account-api/repair"
 	"net/http"
-	"strings"
 )
 
 // RepairHandler handles repair-related requests.
@@ -42,24 +41,16 @@ func NewRepairHandler(e *echo.Echo, svc repair.Service) {
 // @Failure 500 {object} ResponseError "Internal server error"
 // @Router /api/v1/repairs [post]
 func (h *RepairHandler) CreateRepair(c echo.Context) error {
-	// Extract the Authorization header
-	authHeader := c.Request().Header.Get("Authorization")
-	if authHeader == "" {
-		return c.JSON(http.StatusUnauthorized, ResponseError{
-			Message: "Authorization token is required",
-		})
+	// The principal middleware has already verified the bearer token
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
 	}
 
-	// Check if the header starts with "Bearer "
-	const bearerPrefix = "Bearer "
-	token := strings.TrimSpace(strings.TrimPrefix(authHeader, bearerPrefix))
-
 	// Parse the repair request from the body
 	var repair domain.Repair
 	if err := c.Bind(&repair); err != nil {
-		return c.JSON(http.StatusBadRequest, ResponseError{
-			Message: "Invalid repair request format",
-		})
+		return respondError(c, http.StatusBadRequest, localize(c, "repair.invalid_payload"))
 	}
 
 	// Call the service to create a new repair request
@@ -70,6 +61,6 @@ func (h *RepairHandler) CreateRepair(c echo.Context) error {
 
 	// Return a success message
 	return c.JSON(201, map[string]string{
-		"message": "Repair created",
+		"message": localize(c, "repair.created"),
 	})
 }
@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"net/http"
+)
+
+// SuspensionHandler handles voluntary suspension requests.
+type SuspensionHandler struct {
+	Service SuspensionService
+}
+
+// SuspensionService defines the interface for suspension services.
+type SuspensionService interface {
+	Suspensions(ctx context.Context, token string) (domain.SuspensionSummary, error)
+	Schedule(ctx context.Context, token string, req domain.SuspensionRequest) (domain.Suspension, error)
+	Cancel(ctx context.Context, token, id string) error
+}
+
+// NewSuspensionHandler initializes the suspension handler with the given service and routes.
+func NewSuspensionHandler(e *echo.Echo, svc SuspensionService) {
+	handler := &SuspensionHandler{
+		Service: svc, // Initialize the handler with the service
+	}
+	suspensionGroup := e.Group("/api/v1/suspensions")
+	suspensionGroup.GET("", handler.Suspensions)
+	suspensionGroup.POST("", handler.Schedule)
+	suspensionGroup.DELETE("/:id", handler.Cancel)
+}
+
+// Suspensions handles the GET /suspensions endpoint.
+// @Summary List suspensions
+// @Description List past, active and scheduled suspensions with the days left for this year
+// @Tags Suspensions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Success 200 {object} domain.SuspensionSummary "Suspensions"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/suspensions [get]
+func (h *SuspensionHandler) Suspensions(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	summary, err := h.Service.Suspensions(c.Request().Context(), token)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, summary)
+}
+
+// Schedule handles the POST /suspensions endpoint.
+// @Summary Schedule a suspension
+// @Description Pause the internet and its charges between two dates
+// @Tags Suspensions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param suspension body domain.SuspensionRequest true "Suspension dates"
+// @Success 201 {object} domain.Suspension "Scheduled suspension"
+// @Failure 400 {object} ResponseError "Invalid dates"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 403 {object} ResponseError "Yearly allowance exceeded"
+// @Failure 409 {object} ResponseError "Overlaps another suspension"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/suspensions [post]
+func (h *SuspensionHandler) Schedule(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	var req domain.SuspensionRequest
+	if err := c.Bind(&req); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	suspension, err := h.Service.Schedule(c.Request().Context(), token, req)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusCreated, suspension)
+}
+
+// Cancel handles the DELETE /suspensions/{id} endpoint.
+// @Summary Cancel a suspension
+// @Description Cancel a scheduled suspension or end an active one early
+// @Tags Suspensions
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param id path string true "Suspension ID"
+// @Success 204 "Suspension cancelled"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Suspension not found"
+// @Failure 409 {object} ResponseError "Suspension has already finished"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/suspensions/{id} [delete]
+func (h *SuspensionHandler) Cancel(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	if err := h.Service.Cancel(c.Request().Context(), token, c.Param("id")); err != nil {
+		return handleError(c, err)
+	}
+	return c.NoContent(http.StatusNoContent)
+}
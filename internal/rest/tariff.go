@@ -0,0 +1,120 @@
+package rest
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"net/http"
+)
+
+// TariffHandler handles tariff-related requests.
+type TariffHandler struct {
+	Service TariffService
+}
+
+// TariffService defines the interface for tariff services.
+type TariffService interface {
+	Tariffs(ctx context.Context, token string) ([]domain.Tariff, error)
+	PreviewChange(ctx context.Context, token string, change domain.TariffChange) (domain.TariffChangePreview, error)
+	ChangeTariff(ctx context.Context, token string, change domain.TariffChange) (domain.TariffChangePreview, error)
+}
+
+// NewTariffHandler initializes the tariff handler with the given service and routes.
+func NewTariffHandler(e *echo.Echo, svc TariffService) {
+	handler := &TariffHandler{
+		Service: svc, // Initialize the handler with the service
+	}
+	e.GET("/api/v1/tariffs", handler.Tariffs)
+	e.POST("/api/v1/profile/tariff/preview", handler.PreviewChange)
+	e.POST("/api/v1/profile/tariff", handler.ChangeTariff)
+}
+
+// Tariffs handles the GET /tariffs endpoint.
+// @Summary List tariffs
+// @Description List the tariffs available at the authenticated user's address
+// @Tags Tariffs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Success 200 {array} domain.Tariff "Available tariffs"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/tariffs [get]
+func (h *TariffHandler) Tariffs(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	tariffs, err := h.Service.Tariffs(c.Request().Context(), token)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, tariffs)
+}
+
+// PreviewChange handles the POST /profile/tariff/preview endpoint.
+// @Summary Preview a tariff change
+// @Description Show what switching to another tariff costs and whether the balance covers it
+// @Tags Tariffs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param change body domain.TariffChange true "Tariff change"
+// @Success 200 {object} domain.TariffChangePreview "Price preview"
+// @Failure 400 {object} ResponseError "Invalid request payload"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 404 {object} ResponseError "Tariff is not available"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/profile/tariff/preview [post]
+func (h *TariffHandler) PreviewChange(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	var change domain.TariffChange
+	if err := c.Bind(&change); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	preview, err := h.Service.PreviewChange(c.Request().Context(), token, change)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, preview)
+}
+
+// ChangeTariff handles the POST /profile/tariff endpoint.
+// @Summary Change tariff
+// @Description Switch to another tariff now or from the next billing period
+// @Tags Tariffs
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param change body domain.TariffChange true "Tariff change"
+// @Success 200 {object} domain.TariffChangePreview "Scheduled tariff change"
+// @Failure 400 {object} ResponseError "Invalid request payload"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 402 {object} ResponseError "Insufficient funds"
+// @Failure 404 {object} ResponseError "Tariff is not available"
+// @Failure 409 {object} ResponseError "Tariff is already active"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/profile/tariff [post]
+func (h *TariffHandler) ChangeTariff(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	var change domain.TariffChange
+	if err := c.Bind(&change); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_payload"))
+	}
+
+	preview, err := h.Service.ChangeTariff(c.Request().Context(), token, change)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, preview)
+}
@@ -0,0 +1,59 @@
+package rest
+
+import (
+	"context"
+	"github.com/labstack/echo/v4"
+	"github.com/llchhh/spektr-account-api/domain"
+	"net/http"
+)
+
+// UsageHandler handles traffic and connection session statistics requests.
+type UsageHandler struct {
+	Service UsageService
+}
+
+// UsageService defines the interface for usage statistics services.
+type UsageService interface {
+	Usage(ctx context.Context, token string, query domain.UsageQuery) (domain.Usage, error)
+}
+
+// NewUsageHandler initializes the usage handler with the given service and routes.
+func NewUsageHandler(e *echo.Echo, svc UsageService) {
+	handler := &UsageHandler{
+		Service: svc, // Initialize the handler with the service
+	}
+	e.GET("/api/v1/usage", handler.Usage)
+}
+
+// Usage handles the GET /usage endpoint.
+// @Summary Get usage statistics
+// @Description Get the traffic per day or month and the recent PPPoE/IPoE sessions over a date range
+// @Tags Usage
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer <token>"
+// @Param from query string false "First day, YYYY-MM-DD (default: 30 days ago)"
+// @Param to query string false "Last day, YYYY-MM-DD (default: today)"
+// @Param bucket query string false "Aggregation bucket: day or month (default: day)"
+// @Success 200 {object} domain.Usage "Usage statistics"
+// @Failure 400 {object} ResponseError "Invalid date range or bucket"
+// @Failure 401 {object} ResponseError "Unauthorized"
+// @Failure 500 {object} ResponseError "Internal server error"
+// @Router /api/v1/usage [get]
+func (h *UsageHandler) Usage(c echo.Context) error {
+	token, ok := bearerToken(c)
+	if !ok {
+		return respondError(c, http.StatusUnauthorized, localize(c, "request.token_required"))
+	}
+
+	var query domain.UsageQuery
+	if err := (&echo.DefaultBinder{}).BindQueryParams(c, &query); err != nil {
+		return respondError(c, http.StatusBadRequest, localize(c, "request.invalid_query"))
+	}
+
+	usage, err := h.Service.Usage(c.Request().Context(), token, query)
+	if err != nil {
+		return handleError(c, err)
+	}
+	return c.JSON(http.StatusOK, usage)
+}
@@ -0,0 +1,600 @@
+// Package memory is a storage backend that keeps everything in process
+// memory. State is lost on restart, so it is meant for tests and local runs.
+package memory
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/storage"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Store is a storage.Store backed by maps.
+type Store struct {
+	mu          sync.Mutex
+	sessions    map[string]storage.Session
+	readMarks   map[string]map[string]time.Time
+	preferences map[string]map[string]string
+	audit       []storage.AuditEntry
+	otp         map[[2]string]storage.OTPCode
+	apiKeys     map[string]storage.APIKey
+	intents     map[string]domain.PaymentIntent
+	// intentKeys maps contract ID + idempotency key to the intent ID.
+	intentKeys   map[[2]string]string
+	autopayRules map[string]domain.AutopayRule
+	inbox        map[string][]storage.InboxMessage
+	// suspensions is keyed by contract ID + suspension ID.
+	suspensions map[[2]string]storage.WatchedSuspension
+}
+
+// New creates an empty Store.
+func New() *Store {
+	return &Store{
+		sessions:     make(map[string]storage.Session),
+		readMarks:    make(map[string]map[string]time.Time),
+		preferences:  make(map[string]map[string]string),
+		otp:          make(map[[2]string]storage.OTPCode),
+		apiKeys:      make(map[string]storage.APIKey),
+		intents:      make(map[string]domain.PaymentIntent),
+		intentKeys:   make(map[[2]string]string),
+		autopayRules: make(map[string]domain.AutopayRule),
+		inbox:        make(map[string][]storage.InboxMessage),
+		suspensions:  make(map[[2]string]storage.WatchedSuspension),
+	}
+}
+
+func (s *Store) Sessions() storage.SessionRepository             { return sessions{s} }
+func (s *Store) ReadMarks() storage.ReadMarkRepository           { return readMarks{s} }
+func (s *Store) Preferences() storage.PreferenceRepository       { return preferences{s} }
+func (s *Store) Audit() storage.AuditRepository                  { return audit{s} }
+func (s *Store) OTP() storage.OTPRepository                      { return otp{s} }
+func (s *Store) APIKeys() storage.APIKeyRepository               { return apiKeys{s} }
+func (s *Store) PaymentIntents() storage.PaymentIntentRepository { return intents{s} }
+func (s *Store) AutopayRules() storage.AutopayRuleRepository     { return autopayRules{s} }
+func (s *Store) Inbox() storage.InboxRepository                  { return inbox{s} }
+func (s *Store) WatchedSuspensions() storage.WatchedSuspensionRepository {
+	return watchedSuspensions{s}
+}
+
+func (s *Store) Ping(context.Context) error { return nil }
+func (s *Store) Close() error               { return nil }
+
+type sessions struct{ *Store }
+
+func (r sessions) Create(_ context.Context, session storage.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sessions[session.ID]; ok {
+		return domain.ErrConflict
+	}
+	r.sessions[session.ID] = session
+	return nil
+}
+
+func (r sessions) Get(_ context.Context, id string) (storage.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return storage.Session{}, domain.ErrNotFound
+	}
+	return session, nil
+}
+
+func (r sessions) ListByContract(_ context.Context, contractID string) ([]storage.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var list []storage.Session
+	for _, session := range r.sessions {
+		if session.ContractID == contractID && session.RevokedAt == nil {
+			list = append(list, session)
+		}
+	}
+	slices.SortFunc(list, func(a, b storage.Session) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return list, nil
+}
+
+func (r sessions) Touch(_ context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	session.LastSeenAt = at
+	r.sessions[id] = session
+	return nil
+}
+
+func (r sessions) Revoke(_ context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if session.RevokedAt == nil {
+		session.RevokedAt = &at
+		r.sessions[id] = session
+	}
+	return nil
+}
+
+func (r sessions) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for id, session := range r.sessions {
+		if session.ExpiresAt.Before(now) {
+			delete(r.sessions, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type readMarks struct{ *Store }
+
+func (r readMarks) MarkRead(_ context.Context, contractID string, notificationIDs []string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	marks := r.readMarks[contractID]
+	if marks == nil {
+		marks = make(map[string]time.Time)
+		r.readMarks[contractID] = marks
+	}
+	for _, id := range notificationIDs {
+		if _, ok := marks[id]; !ok {
+			marks[id] = at
+		}
+	}
+	return nil
+}
+
+func (r readMarks) ReadAt(_ context.Context, contractID string) (map[string]time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	marks := maps.Clone(r.readMarks[contractID])
+	if marks == nil {
+		marks = make(map[string]time.Time)
+	}
+	return marks, nil
+}
+
+type preferences struct{ *Store }
+
+func (r preferences) Get(_ context.Context, contractID, key string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	value, ok := r.preferences[contractID][key]
+	if !ok {
+		return "", domain.ErrNotFound
+	}
+	return value, nil
+}
+
+func (r preferences) List(_ context.Context, contractID string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prefs := maps.Clone(r.preferences[contractID])
+	if prefs == nil {
+		prefs = make(map[string]string)
+	}
+	return prefs, nil
+}
+
+func (r preferences) Set(_ context.Context, contractID, key, value string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	prefs := r.preferences[contractID]
+	if prefs == nil {
+		prefs = make(map[string]string)
+		r.preferences[contractID] = prefs
+	}
+	prefs[key] = value
+	return nil
+}
+
+func (r preferences) Delete(_ context.Context, contractID, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.preferences[contractID], key)
+	return nil
+}
+
+type audit struct{ *Store }
+
+func (r audit) Append(_ context.Context, entry storage.AuditEntry) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = int64(len(r.audit)) + 1
+	r.audit = append(r.audit, entry)
+	return entry.ID, nil
+}
+
+func (r audit) List(_ context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var list []storage.AuditEntry
+	for i := len(r.audit) - 1; i >= 0; i-- {
+		entry := r.audit[i]
+		if filter.ContractID != "" && entry.ContractID != filter.ContractID {
+			continue
+		}
+		if !filter.Since.IsZero() && entry.At.Before(filter.Since) {
+			continue
+		}
+		list = append(list, entry)
+	}
+	// Entries are appended in ID order, which may differ from At.
+	slices.SortStableFunc(list, func(a, b storage.AuditEntry) int {
+		return b.At.Compare(a.At)
+	})
+	if filter.Limit > 0 && len(list) > filter.Limit {
+		list = list[:filter.Limit]
+	}
+	return list, nil
+}
+
+type otp struct{ *Store }
+
+func (r otp) Save(_ context.Context, code storage.OTPCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.otp[[2]string{code.Subject, code.Purpose}] = code
+	return nil
+}
+
+func (r otp) Get(_ context.Context, subject, purpose string) (storage.OTPCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	code, ok := r.otp[[2]string{subject, purpose}]
+	if !ok {
+		return storage.OTPCode{}, domain.ErrNotFound
+	}
+	return code, nil
+}
+
+func (r otp) IncrementAttempts(_ context.Context, subject, purpose string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := [2]string{subject, purpose}
+	code, ok := r.otp[key]
+	if !ok {
+		return 0, domain.ErrNotFound
+	}
+	code.Attempts++
+	r.otp[key] = code
+	return code.Attempts, nil
+}
+
+func (r otp) Delete(_ context.Context, subject, purpose string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.otp, [2]string{subject, purpose})
+	return nil
+}
+
+func (r otp) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for key, code := range r.otp {
+		if code.ExpiresAt.Before(now) {
+			delete(r.otp, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+type apiKeys struct{ *Store }
+
+func (r apiKeys) Create(_ context.Context, key storage.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.apiKeys[key.ID]; ok {
+		return domain.ErrConflict
+	}
+	key.Scopes = slices.Clone(key.Scopes)
+	r.apiKeys[key.ID] = key
+	return nil
+}
+
+func (r apiKeys) Get(_ context.Context, id string) (storage.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if !ok {
+		return storage.APIKey{}, domain.ErrNotFound
+	}
+	key.Scopes = slices.Clone(key.Scopes)
+	return key, nil
+}
+
+func (r apiKeys) List(context.Context) ([]storage.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var list []storage.APIKey
+	for _, key := range r.apiKeys {
+		key.Scopes = slices.Clone(key.Scopes)
+		list = append(list, key)
+	}
+	slices.SortFunc(list, func(a, b storage.APIKey) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	return list, nil
+}
+
+func (r apiKeys) Expire(_ context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if key.ExpiresAt == nil || at.Before(*key.ExpiresAt) {
+		key.ExpiresAt = &at
+		r.apiKeys[id] = key
+	}
+	return nil
+}
+
+func (r apiKeys) Revoke(_ context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+		r.apiKeys[id] = key
+	}
+	return nil
+}
+
+func (r apiKeys) RecordUse(_ context.Context, id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.apiKeys[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	key.LastUsedAt = &at
+	key.UseCount++
+	r.apiKeys[id] = key
+	return nil
+}
+
+type intents struct{ *Store }
+
+func (r intents) Create(_ context.Context, intent domain.PaymentIntent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.intents[intent.ID]; ok {
+		return domain.ErrConflict
+	}
+	if intent.IdempotencyKey != "" {
+		key := [2]string{intent.ContractID, intent.IdempotencyKey}
+		if _, ok := r.intentKeys[key]; ok {
+			return domain.ErrConflict
+		}
+		r.intentKeys[key] = intent.ID
+	}
+	r.intents[intent.ID] = intent
+	return nil
+}
+
+func (r intents) Update(_ context.Context, intent domain.PaymentIntent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.intents[intent.ID]; !ok {
+		return domain.ErrNotFound
+	}
+	r.intents[intent.ID] = intent
+	return nil
+}
+
+func (r intents) Get(_ context.Context, id string) (domain.PaymentIntent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	intent, ok := r.intents[id]
+	if !ok {
+		return domain.PaymentIntent{}, domain.ErrNotFound
+	}
+	return intent, nil
+}
+
+func (r intents) GetByIdempotencyKey(_ context.Context, contractID, key string) (domain.PaymentIntent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id, ok := r.intentKeys[[2]string{contractID, key}]
+	if !ok {
+		return domain.PaymentIntent{}, domain.ErrNotFound
+	}
+	return r.intents[id], nil
+}
+
+func (r intents) TransitionStatus(_ context.Context, id string, from, to domain.PaymentIntentStatus) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	intent, ok := r.intents[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	if intent.Status != from {
+		return domain.ErrConflict
+	}
+	intent.Status = to
+	r.intents[id] = intent
+	return nil
+}
+
+type autopayRules struct{ *Store }
+
+// cloneRule copies what the rule points to, so callers can't change the
+// stored rule.
+func cloneRule(rule domain.AutopayRule) domain.AutopayRule {
+	if rule.Threshold != nil {
+		threshold := *rule.Threshold
+		rule.Threshold = &threshold
+	}
+	if rule.Amount != nil {
+		amount := *rule.Amount
+		rule.Amount = &amount
+	}
+	if rule.LastChargeAt != nil {
+		at := *rule.LastChargeAt
+		rule.LastChargeAt = &at
+	}
+	return rule
+}
+
+func (r autopayRules) Get(_ context.Context, contractID string) (domain.AutopayRule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rule, ok := r.autopayRules[contractID]
+	if !ok {
+		return domain.AutopayRule{}, domain.ErrNotFound
+	}
+	return cloneRule(rule), nil
+}
+
+func (r autopayRules) Save(_ context.Context, rule domain.AutopayRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.autopayRules[rule.ContractID] = cloneRule(rule)
+	return nil
+}
+
+func (r autopayRules) Delete(_ context.Context, contractID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.autopayRules[contractID]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.autopayRules, contractID)
+	return nil
+}
+
+func (r autopayRules) List(context.Context) ([]domain.AutopayRule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]domain.AutopayRule, 0, len(r.autopayRules))
+	for _, rule := range r.autopayRules {
+		list = append(list, cloneRule(rule))
+	}
+	slices.SortFunc(list, func(a, b domain.AutopayRule) int {
+		return strings.Compare(a.ContractID, b.ContractID)
+	})
+	return list, nil
+}
+
+type inbox struct{ *Store }
+
+func (r inbox) Push(_ context.Context, msg storage.InboxMessage, keep int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := append(r.inbox[msg.ContractID], msg)
+	if len(messages) > keep {
+		messages = slices.Clone(messages[len(messages)-keep:])
+	}
+	r.inbox[msg.ContractID] = messages
+	return nil
+}
+
+func (r inbox) List(_ context.Context, contractID string) ([]storage.InboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return slices.Clone(r.inbox[contractID]), nil
+}
+
+type watchedSuspensions struct{ *Store }
+
+func (r watchedSuspensions) Get(_ context.Context, contractID, suspensionID string) (storage.WatchedSuspension, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.suspensions[[2]string{contractID, suspensionID}]
+	if !ok {
+		return storage.WatchedSuspension{}, domain.ErrNotFound
+	}
+	return s, nil
+}
+
+func (r watchedSuspensions) Save(_ context.Context, s storage.WatchedSuspension) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.suspensions[[2]string{s.ContractID, s.SuspensionID}] = s
+	return nil
+}
+
+func (r watchedSuspensions) Delete(_ context.Context, contractID, suspensionID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := [2]string{contractID, suspensionID}
+	if _, ok := r.suspensions[key]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(r.suspensions, key)
+	return nil
+}
+
+func (r watchedSuspensions) List(context.Context) ([]storage.WatchedSuspension, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := slices.Collect(maps.Values(r.suspensions))
+	slices.SortFunc(list, func(a, b storage.WatchedSuspension) int {
+		return strings.Compare(a.ContractID+"/"+a.SuspensionID, b.ContractID+"/"+b.SuspensionID)
+	})
+	if list == nil {
+		list = []storage.WatchedSuspension{}
+	}
+	return list, nil
+}
@@ -0,0 +1,11 @@
+package memory
+
+import (
+	"github.com/llchhh/spektr-account-api/internal/storage"
+	"github.com/llchhh/spektr-account-api/internal/storage/storagetest"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(*testing.T) storage.Store { return New() })
+}
@@ -0,0 +1,47 @@
+CREATE TABLE sessions (
+    id           TEXT PRIMARY KEY,
+    contract_id  TEXT NOT NULL,
+    user_agent   TEXT NOT NULL DEFAULT '',
+    remote_ip    TEXT NOT NULL DEFAULT '',
+    created_at   INTEGER NOT NULL,
+    last_seen_at INTEGER NOT NULL,
+    expires_at   INTEGER NOT NULL,
+    revoked_at   INTEGER
+);
+CREATE INDEX sessions_contract_id ON sessions (contract_id);
+CREATE INDEX sessions_expires_at ON sessions (expires_at);
+
+CREATE TABLE read_marks (
+    contract_id     TEXT NOT NULL,
+    notification_id TEXT NOT NULL,
+    read_at         INTEGER NOT NULL,
+    PRIMARY KEY (contract_id, notification_id)
+);
+
+CREATE TABLE preferences (
+    contract_id TEXT NOT NULL,
+    key         TEXT NOT NULL,
+    value       TEXT NOT NULL,
+    PRIMARY KEY (contract_id, key)
+);
+
+CREATE TABLE audit_log (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    at          INTEGER NOT NULL,
+    contract_id TEXT NOT NULL,
+    action      TEXT NOT NULL,
+    detail      TEXT NOT NULL DEFAULT '',
+    remote_ip   TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX audit_log_contract_at ON audit_log (contract_id, at);
+
+CREATE TABLE otp_codes (
+    subject    TEXT NOT NULL,
+    purpose    TEXT NOT NULL,
+    code_hash  TEXT NOT NULL,
+    attempts   INTEGER NOT NULL DEFAULT 0,
+    created_at INTEGER NOT NULL,
+    expires_at INTEGER NOT NULL,
+    PRIMARY KEY (subject, purpose)
+);
+CREATE INDEX otp_codes_expires_at ON otp_codes (expires_at);
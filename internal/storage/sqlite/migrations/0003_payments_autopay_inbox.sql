@@ -0,0 +1,43 @@
+CREATE TABLE payment_intents (
+    id               TEXT PRIMARY KEY,
+    contract_id      TEXT NOT NULL,
+    amount_minor     INTEGER NOT NULL,
+    currency         TEXT NOT NULL,
+    status           TEXT NOT NULL,
+    confirmation_url TEXT NOT NULL DEFAULT '',
+    provider_id      TEXT NOT NULL DEFAULT '',
+    idempotency_key  TEXT NOT NULL DEFAULT '',
+    created_at       INTEGER NOT NULL
+);
+CREATE UNIQUE INDEX payment_intents_idempotency_key ON payment_intents (contract_id, idempotency_key)
+    WHERE idempotency_key <> '';
+
+CREATE TABLE autopay_rules (
+    contract_id           TEXT PRIMARY KEY,
+    principal_token       TEXT NOT NULL,
+    principal_contract_id TEXT NOT NULL DEFAULT '',
+    method_id             TEXT NOT NULL,
+    method_title          TEXT NOT NULL DEFAULT '',
+    mode                  TEXT NOT NULL,
+    status                TEXT NOT NULL,
+    days_before           INTEGER NOT NULL DEFAULT 0,
+    threshold_minor       INTEGER,
+    amount_minor          INTEGER,
+    currency              TEXT NOT NULL DEFAULT '',
+    last_period           TEXT NOT NULL DEFAULT '',
+    retry_period          TEXT NOT NULL DEFAULT '',
+    attempts              INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at       INTEGER NOT NULL DEFAULT 0,
+    last_charge_at        INTEGER,
+    last_error            TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE inbox_messages (
+    id          INTEGER PRIMARY KEY AUTOINCREMENT,
+    contract_id TEXT NOT NULL,
+    type        TEXT NOT NULL,
+    key         TEXT NOT NULL,
+    args        TEXT NOT NULL DEFAULT '',
+    created_at  INTEGER NOT NULL
+);
+CREATE INDEX inbox_messages_contract_id ON inbox_messages (contract_id, id);
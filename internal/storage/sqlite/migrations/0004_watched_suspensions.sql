@@ -0,0 +1,8 @@
+CREATE TABLE watched_suspensions (
+    contract_id   TEXT NOT NULL,
+    suspension_id TEXT NOT NULL,
+    start_date    INTEGER NOT NULL,
+    end_date      INTEGER NOT NULL,
+    started       INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (contract_id, suspension_id)
+);
@@ -0,0 +1,702 @@
+// Package sqlite is a storage backend on an embedded SQLite database.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/storage"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Store is a storage.Store backed by a SQLite database file.
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens the database at path, creating it if needed, and brings its
+// schema up to date. ":memory:" opens a private in-memory database.
+func Open(ctx context.Context, path string) (*Store, error) {
+	if path == "" {
+		return nil, errors.New("sqlite: empty database path")
+	}
+	query := url.Values{"_pragma": {"busy_timeout(5000)", "foreign_keys(1)"}}
+	if path != ":memory:" {
+		query.Add("_pragma", "journal_mode(WAL)")
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?"+query.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: open %s: %w", path, err)
+	}
+	// SQLite allows a single writer; one connection avoids "database is
+	// locked" errors and keeps ":memory:" databases from splitting per
+	// connection. The load is a few small queries per request.
+	db.SetMaxOpenConns(1)
+
+	list, err := loadMigrations(migrations)
+	if err == nil {
+		err = migrate(ctx, db, list)
+	}
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite: %s: %w", path, err)
+	}
+	return &Store{db: db}, nil
+}
+
+func (s *Store) Sessions() storage.SessionRepository             { return sessions{s.db} }
+func (s *Store) ReadMarks() storage.ReadMarkRepository           { return readMarks{s.db} }
+func (s *Store) Preferences() storage.PreferenceRepository       { return preferences{s.db} }
+func (s *Store) Audit() storage.AuditRepository                  { return audit{s.db} }
+func (s *Store) OTP() storage.OTPRepository                      { return otp{s.db} }
+func (s *Store) APIKeys() storage.APIKeyRepository               { return apiKeys{s.db} }
+func (s *Store) PaymentIntents() storage.PaymentIntentRepository { return intents{s.db} }
+func (s *Store) AutopayRules() storage.AutopayRuleRepository     { return autopayRules{s.db} }
+func (s *Store) Inbox() storage.InboxRepository                  { return inbox{s.db} }
+func (s *Store) WatchedSuspensions() storage.WatchedSuspensionRepository {
+	return watchedSuspensions{s.db}
+}
+
+func (s *Store) Ping(ctx context.Context) error { return s.db.PingContext(ctx) }
+func (s *Store) Close() error                   { return s.db.Close() }
+
+// Times are stored as Unix nanoseconds so they round-trip exactly.
+func toUnix(t time.Time) int64 { return t.UnixNano() }
+
+func fromUnix(n int64) time.Time { return time.Unix(0, n) }
+
+// affected maps an update that matched no rows to domain.ErrNotFound.
+func affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+type sessions struct{ db *sql.DB }
+
+const sessionColumns = `id, contract_id, user_agent, remote_ip, created_at, last_seen_at, expires_at, revoked_at`
+
+func scanSession(row interface{ Scan(...any) error }) (storage.Session, error) {
+	var s storage.Session
+	var created, lastSeen, expires int64
+	var revoked sql.NullInt64
+	if err := row.Scan(&s.ID, &s.ContractID, &s.UserAgent, &s.RemoteIP, &created, &lastSeen, &expires, &revoked); err != nil {
+		return storage.Session{}, err
+	}
+	s.CreatedAt = fromUnix(created)
+	s.LastSeenAt = fromUnix(lastSeen)
+	s.ExpiresAt = fromUnix(expires)
+	if revoked.Valid {
+		t := fromUnix(revoked.Int64)
+		s.RevokedAt = &t
+	}
+	return s, nil
+}
+
+func (r sessions) Create(ctx context.Context, s storage.Session) error {
+	var revoked sql.NullInt64
+	if s.RevokedAt != nil {
+		revoked = sql.NullInt64{Int64: toUnix(*s.RevokedAt), Valid: true}
+	}
+	res, err := r.db.ExecContext(ctx, `INSERT INTO sessions (`+sessionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+		s.ID, s.ContractID, s.UserAgent, s.RemoteIP,
+		toUnix(s.CreatedAt), toUnix(s.LastSeenAt), toUnix(s.ExpiresAt), revoked)
+	if err := affected(res, err); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r sessions) Get(ctx context.Context, id string) (storage.Session, error) {
+	s, err := scanSession(r.db.QueryRowContext(ctx, `SELECT `+sessionColumns+` FROM sessions WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Session{}, domain.ErrNotFound
+	}
+	return s, err
+}
+
+func (r sessions) ListByContract(ctx context.Context, contractID string) ([]storage.Session, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+sessionColumns+` FROM sessions
+		WHERE contract_id = ? AND revoked_at IS NULL ORDER BY created_at DESC`, contractID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []storage.Session
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func (r sessions) Touch(ctx context.Context, id string, at time.Time) error {
+	return affected(r.db.ExecContext(ctx, `UPDATE sessions SET last_seen_at = ? WHERE id = ?`, toUnix(at), id))
+}
+
+func (r sessions) Revoke(ctx context.Context, id string, at time.Time) error {
+	return affected(r.db.ExecContext(ctx,
+		`UPDATE sessions SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`, toUnix(at), id))
+}
+
+func (r sessions) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM sessions WHERE expires_at < ?`, toUnix(now))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+type readMarks struct{ db *sql.DB }
+
+func (r readMarks) MarkRead(ctx context.Context, contractID string, notificationIDs []string, at time.Time) error {
+	if len(notificationIDs) == 0 {
+		return nil
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO read_marks (contract_id, notification_id, read_at)
+		VALUES (?, ?, ?) ON CONFLICT DO NOTHING`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, id := range notificationIDs {
+		if _, err := stmt.ExecContext(ctx, contractID, id, toUnix(at)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r readMarks) ReadAt(ctx context.Context, contractID string) (map[string]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT notification_id, read_at FROM read_marks WHERE contract_id = ?`, contractID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	marks := make(map[string]time.Time)
+	for rows.Next() {
+		var id string
+		var at int64
+		if err := rows.Scan(&id, &at); err != nil {
+			return nil, err
+		}
+		marks[id] = fromUnix(at)
+	}
+	return marks, rows.Err()
+}
+
+type preferences struct{ db *sql.DB }
+
+func (r preferences) Get(ctx context.Context, contractID, key string) (string, error) {
+	var value string
+	err := r.db.QueryRowContext(ctx, `SELECT value FROM preferences WHERE contract_id = ? AND key = ?`,
+		contractID, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", domain.ErrNotFound
+	}
+	return value, err
+}
+
+func (r preferences) List(ctx context.Context, contractID string) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT key, value FROM preferences WHERE contract_id = ?`, contractID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		prefs[key] = value
+	}
+	return prefs, rows.Err()
+}
+
+func (r preferences) Set(ctx context.Context, contractID, key, value string) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO preferences (contract_id, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (contract_id, key) DO UPDATE SET value = excluded.value`, contractID, key, value)
+	return err
+}
+
+func (r preferences) Delete(ctx context.Context, contractID, key string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM preferences WHERE contract_id = ? AND key = ?`, contractID, key)
+	return err
+}
+
+type audit struct{ db *sql.DB }
+
+func (r audit) Append(ctx context.Context, e storage.AuditEntry) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO audit_log (at, contract_id, action, detail, remote_ip)
+		VALUES (?, ?, ?, ?, ?)`, toUnix(e.At), e.ContractID, e.Action, e.Detail, e.RemoteIP)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+func (r audit) List(ctx context.Context, filter storage.AuditFilter) ([]storage.AuditEntry, error) {
+	var where []string
+	var args []any
+	if filter.ContractID != "" {
+		where = append(where, "contract_id = ?")
+		args = append(args, filter.ContractID)
+	}
+	if !filter.Since.IsZero() {
+		where = append(where, "at >= ?")
+		args = append(args, toUnix(filter.Since))
+	}
+	query := `SELECT id, at, contract_id, action, detail, remote_ip FROM audit_log`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY at DESC, id DESC"
+	if filter.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []storage.AuditEntry
+	for rows.Next() {
+		var e storage.AuditEntry
+		var at int64
+		if err := rows.Scan(&e.ID, &at, &e.ContractID, &e.Action, &e.Detail, &e.RemoteIP); err != nil {
+			return nil, err
+		}
+		e.At = fromUnix(at)
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+type otp struct{ db *sql.DB }
+
+func (r otp) Save(ctx context.Context, c storage.OTPCode) error {
+	_, err := r.db.ExecContext(ctx, `INSERT INTO otp_codes (subject, purpose, code_hash, attempts, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (subject, purpose) DO UPDATE SET code_hash = excluded.code_hash, attempts = excluded.attempts,
+			created_at = excluded.created_at, expires_at = excluded.expires_at`,
+		c.Subject, c.Purpose, c.CodeHash, c.Attempts, toUnix(c.CreatedAt), toUnix(c.ExpiresAt))
+	return err
+}
+
+func (r otp) Get(ctx context.Context, subject, purpose string) (storage.OTPCode, error) {
+	c := storage.OTPCode{Subject: subject, Purpose: purpose}
+	var created, expires int64
+	err := r.db.QueryRowContext(ctx, `SELECT code_hash, attempts, created_at, expires_at FROM otp_codes
+		WHERE subject = ? AND purpose = ?`, subject, purpose).Scan(&c.CodeHash, &c.Attempts, &created, &expires)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.OTPCode{}, domain.ErrNotFound
+	}
+	if err != nil {
+		return storage.OTPCode{}, err
+	}
+	c.CreatedAt = fromUnix(created)
+	c.ExpiresAt = fromUnix(expires)
+	return c, nil
+}
+
+func (r otp) IncrementAttempts(ctx context.Context, subject, purpose string) (int, error) {
+	var attempts int
+	err := r.db.QueryRowContext(ctx, `UPDATE otp_codes SET attempts = attempts + 1
+		WHERE subject = ? AND purpose = ? RETURNING attempts`, subject, purpose).Scan(&attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, domain.ErrNotFound
+	}
+	return attempts, err
+}
+
+func (r otp) Delete(ctx context.Context, subject, purpose string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM otp_codes WHERE subject = ? AND purpose = ?`, subject, purpose)
+	return err
+}
+
+func (r otp) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM otp_codes WHERE expires_at < ?`, toUnix(now))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+type apiKeys struct{ db *sql.DB }
+
+const apiKeyColumns = `id, name, secret_hash, scopes, created_at, expires_at, revoked_at, last_used_at, use_count`
+
+// nullUnix stores an optional time.
+func nullUnix(t *time.Time) sql.NullInt64 {
+	if t == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: toUnix(*t), Valid: true}
+}
+
+func fromNullUnix(n sql.NullInt64) *time.Time {
+	if !n.Valid {
+		return nil
+	}
+	t := fromUnix(n.Int64)
+	return &t
+}
+
+func scanAPIKey(row interface{ Scan(...any) error }) (storage.APIKey, error) {
+	var k storage.APIKey
+	var scopes string
+	var created int64
+	var expires, revoked, lastUsed sql.NullInt64
+	if err := row.Scan(&k.ID, &k.Name, &k.SecretHash, &scopes, &created, &expires, &revoked, &lastUsed, &k.UseCount); err != nil {
+		return storage.APIKey{}, err
+	}
+	if scopes != "" {
+		k.Scopes = strings.Split(scopes, ",")
+	}
+	k.CreatedAt = fromUnix(created)
+	k.ExpiresAt = fromNullUnix(expires)
+	k.RevokedAt = fromNullUnix(revoked)
+	k.LastUsedAt = fromNullUnix(lastUsed)
+	return k, nil
+}
+
+func (r apiKeys) Create(ctx context.Context, k storage.APIKey) error {
+	res, err := r.db.ExecContext(ctx, `INSERT INTO api_keys (`+apiKeyColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT (id) DO NOTHING`,
+		k.ID, k.Name, k.SecretHash, strings.Join(k.Scopes, ","), toUnix(k.CreatedAt),
+		nullUnix(k.ExpiresAt), nullUnix(k.RevokedAt), nullUnix(k.LastUsedAt), k.UseCount)
+	if err := affected(res, err); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r apiKeys) Get(ctx context.Context, id string) (storage.APIKey, error) {
+	k, err := scanAPIKey(r.db.QueryRowContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.APIKey{}, domain.ErrNotFound
+	}
+	return k, err
+}
+
+func (r apiKeys) List(ctx context.Context) ([]storage.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY created_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []storage.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, k)
+	}
+	return list, rows.Err()
+}
+
+func (r apiKeys) Expire(ctx context.Context, id string, at time.Time) error {
+	return affected(r.db.ExecContext(ctx,
+		`UPDATE api_keys SET expires_at = MIN(COALESCE(expires_at, ?1), ?1) WHERE id = ?2`, toUnix(at), id))
+}
+
+func (r apiKeys) Revoke(ctx context.Context, id string, at time.Time) error {
+	return affected(r.db.ExecContext(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, ?) WHERE id = ?`, toUnix(at), id))
+}
+
+func (r apiKeys) RecordUse(ctx context.Context, id string, at time.Time) error {
+	return affected(r.db.ExecContext(ctx,
+		`UPDATE api_keys SET last_used_at = ?, use_count = use_count + 1 WHERE id = ?`, toUnix(at), id))
+}
+
+type intents struct{ db *sql.DB }
+
+const intentColumns = `id, contract_id, amount_minor, currency, status, confirmation_url, provider_id, idempotency_key, created_at`
+
+func scanIntent(row interface{ Scan(...any) error }) (domain.PaymentIntent, error) {
+	var i domain.PaymentIntent
+	var created int64
+	if err := row.Scan(&i.ID, &i.ContractID, &i.Amount.Minor, &i.Amount.Currency, &i.Status,
+		&i.ConfirmationURL, &i.ProviderID, &i.IdempotencyKey, &created); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.PaymentIntent{}, domain.ErrNotFound
+		}
+		return domain.PaymentIntent{}, err
+	}
+	i.CreatedAt = fromUnix(created)
+	return i, nil
+}
+
+func (r intents) Create(ctx context.Context, i domain.PaymentIntent) error {
+	// DO NOTHING covers both the ID and the idempotency key.
+	res, err := r.db.ExecContext(ctx, `INSERT INTO payment_intents (`+intentColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`,
+		i.ID, i.ContractID, i.Amount.Minor, i.Amount.Currency, i.Status,
+		i.ConfirmationURL, i.ProviderID, i.IdempotencyKey, toUnix(i.CreatedAt))
+	if err := affected(res, err); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.ErrConflict
+		}
+		return err
+	}
+	return nil
+}
+
+func (r intents) Update(ctx context.Context, i domain.PaymentIntent) error {
+	return affected(r.db.ExecContext(ctx, `UPDATE payment_intents SET amount_minor = ?, currency = ?, status = ?,
+		confirmation_url = ?, provider_id = ? WHERE id = ?`,
+		i.Amount.Minor, i.Amount.Currency, i.Status, i.ConfirmationURL, i.ProviderID, i.ID))
+}
+
+func (r intents) Get(ctx context.Context, id string) (domain.PaymentIntent, error) {
+	return scanIntent(r.db.QueryRowContext(ctx, `SELECT `+intentColumns+` FROM payment_intents WHERE id = ?`, id))
+}
+
+func (r intents) GetByIdempotencyKey(ctx context.Context, contractID, key string) (domain.PaymentIntent, error) {
+	if key == "" {
+		return domain.PaymentIntent{}, domain.ErrNotFound
+	}
+	return scanIntent(r.db.QueryRowContext(ctx, `SELECT `+intentColumns+` FROM payment_intents
+		WHERE contract_id = ? AND idempotency_key = ?`, contractID, key))
+}
+
+func (r intents) TransitionStatus(ctx context.Context, id string, from, to domain.PaymentIntentStatus) error {
+	err := affected(r.db.ExecContext(ctx, `UPDATE payment_intents SET status = ? WHERE id = ? AND status = ?`, to, id, from))
+	if !errors.Is(err, domain.ErrNotFound) {
+		return err
+	}
+	// Tell a missing intent from one in another status.
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+	return domain.ErrConflict
+}
+
+type autopayRules struct{ db *sql.DB }
+
+const autopayRuleColumns = `contract_id, principal_token, principal_contract_id, method_id, method_title, mode, status,
+	days_before, threshold_minor, amount_minor, currency, last_period, retry_period, attempts, next_attempt_at,
+	last_charge_at, last_error`
+
+// optionalUnix stores a time whose zero value means unset. The zero time
+// is out of the range of Unix nanoseconds.
+func optionalUnix(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return toUnix(t)
+}
+
+func fromOptionalUnix(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return fromUnix(n)
+}
+
+func nullMinor(m *domain.Money) sql.NullInt64 {
+	if m == nil {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: m.Minor, Valid: true}
+}
+
+func scanAutopayRule(row interface{ Scan(...any) error }) (domain.AutopayRule, error) {
+	var r domain.AutopayRule
+	var threshold, amount, lastCharge sql.NullInt64
+	var currency string
+	var nextAttempt int64
+	if err := row.Scan(&r.ContractID, &r.Principal.Token, &r.Principal.ContractID, &r.Method.ID, &r.Method.Title,
+		&r.Mode, &r.Status, &r.DaysBefore, &threshold, &amount, &currency, &r.LastPeriod, &r.RetryPeriod,
+		&r.Attempts, &nextAttempt, &lastCharge, &r.LastError); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.AutopayRule{}, domain.ErrNotFound
+		}
+		return domain.AutopayRule{}, err
+	}
+	if threshold.Valid {
+		r.Threshold = &domain.Money{Minor: threshold.Int64, Currency: currency}
+	}
+	if amount.Valid {
+		r.Amount = &domain.Money{Minor: amount.Int64, Currency: currency}
+	}
+	r.NextAttemptAt = fromOptionalUnix(nextAttempt)
+	r.LastChargeAt = fromNullUnix(lastCharge)
+	return r, nil
+}
+
+func (r autopayRules) Get(ctx context.Context, contractID string) (domain.AutopayRule, error) {
+	return scanAutopayRule(r.db.QueryRowContext(ctx, `SELECT `+autopayRuleColumns+` FROM autopay_rules WHERE contract_id = ?`, contractID))
+}
+
+func (r autopayRules) Save(ctx context.Context, rule domain.AutopayRule) error {
+	// Threshold and Amount share the currency; the service only sets them
+	// together.
+	currency := ""
+	for _, m := range []*domain.Money{rule.Threshold, rule.Amount} {
+		if m != nil {
+			currency = m.Currency
+		}
+	}
+	_, err := r.db.ExecContext(ctx, `INSERT OR REPLACE INTO autopay_rules (`+autopayRuleColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		rule.ContractID, rule.Principal.Token, rule.Principal.ContractID, rule.Method.ID, rule.Method.Title,
+		rule.Mode, rule.Status, rule.DaysBefore, nullMinor(rule.Threshold), nullMinor(rule.Amount), currency,
+		rule.LastPeriod, rule.RetryPeriod, rule.Attempts, optionalUnix(rule.NextAttemptAt),
+		nullUnix(rule.LastChargeAt), rule.LastError)
+	return err
+}
+
+func (r autopayRules) Delete(ctx context.Context, contractID string) error {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM autopay_rules WHERE contract_id = ?`, contractID))
+}
+
+func (r autopayRules) List(ctx context.Context) ([]domain.AutopayRule, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+autopayRuleColumns+` FROM autopay_rules ORDER BY contract_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []domain.AutopayRule{}
+	for rows.Next() {
+		rule, err := scanAutopayRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, rule)
+	}
+	return list, rows.Err()
+}
+
+type inbox struct{ db *sql.DB }
+
+func (r inbox) Push(ctx context.Context, m storage.InboxMessage, keep int) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO inbox_messages (contract_id, type, key, args, created_at)
+		VALUES (?, ?, ?, ?, ?)`, m.ContractID, m.Type, m.Key, m.Args, toUnix(m.CreatedAt)); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM inbox_messages WHERE contract_id = ?1 AND id NOT IN
+		(SELECT id FROM inbox_messages WHERE contract_id = ?1 ORDER BY id DESC LIMIT ?2)`, m.ContractID, keep); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r inbox) List(ctx context.Context, contractID string) ([]storage.InboxMessage, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT type, key, args, created_at FROM inbox_messages
+		WHERE contract_id = ? ORDER BY id`, contractID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []storage.InboxMessage
+	for rows.Next() {
+		m := storage.InboxMessage{ContractID: contractID}
+		var created int64
+		if err := rows.Scan(&m.Type, &m.Key, &m.Args, &created); err != nil {
+			return nil, err
+		}
+		m.CreatedAt = fromUnix(created)
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
+
+type watchedSuspensions struct{ db *sql.DB }
+
+func scanWatchedSuspension(row interface{ Scan(...any) error }) (storage.WatchedSuspension, error) {
+	var s storage.WatchedSuspension
+	var start, end int64
+	if err := row.Scan(&s.ContractID, &s.SuspensionID, &start, &end, &s.Started); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.WatchedSuspension{}, domain.ErrNotFound
+		}
+		return storage.WatchedSuspension{}, err
+	}
+	s.StartDate = fromUnix(start)
+	s.EndDate = fromUnix(end)
+	return s, nil
+}
+
+func (r watchedSuspensions) Get(ctx context.Context, contractID, suspensionID string) (storage.WatchedSuspension, error) {
+	return scanWatchedSuspension(r.db.QueryRowContext(ctx, `SELECT contract_id, suspension_id, start_date, end_date, started
+		FROM watched_suspensions WHERE contract_id = ? AND suspension_id = ?`, contractID, suspensionID))
+}
+
+func (r watchedSuspensions) Save(ctx context.Context, s storage.WatchedSuspension) error {
+	_, err := r.db.ExecContext(ctx, `INSERT OR REPLACE INTO watched_suspensions
+		(contract_id, suspension_id, start_date, end_date, started) VALUES (?, ?, ?, ?, ?)`,
+		s.ContractID, s.SuspensionID, toUnix(s.StartDate), toUnix(s.EndDate), s.Started)
+	return err
+}
+
+func (r watchedSuspensions) Delete(ctx context.Context, contractID, suspensionID string) error {
+	return affected(r.db.ExecContext(ctx, `DELETE FROM watched_suspensions WHERE contract_id = ? AND suspension_id = ?`,
+		contractID, suspensionID))
+}
+
+func (r watchedSuspensions) List(ctx context.Context) ([]storage.WatchedSuspension, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT contract_id, suspension_id, start_date, end_date, started
+		FROM watched_suspensions ORDER BY contract_id, suspension_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []storage.WatchedSuspension{}
+	for rows.Next() {
+		s, err := scanWatchedSuspension(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"github.com/llchhh/spektr-account-api/internal/storage"
+	"github.com/llchhh/spektr-account-api/internal/storage/storagetest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStore(t *testing.T) {
+	storagetest.Run(t, func(t *testing.T) storage.Store {
+		s, err := Open(context.Background(), filepath.Join(t.TempDir(), "state.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
+
+func TestReopenKeepsData(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "state.db")
+
+	s, err := Open(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Preferences().Set(ctx, "c1", "lang", "ru"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = Open(ctx, path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	if got, err := s.Preferences().Get(ctx, "c1", "lang"); err != nil || got != "ru" {
+		t.Errorf("Get = %q, %v, want ru", got, err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	v1 := fstest.MapFS{
+		"migrations/0001_create.sql": {Data: []byte("CREATE TABLE t (a TEXT);")},
+	}
+	v2 := fstest.MapFS{
+		"migrations/0001_create.sql": v1["migrations/0001_create.sql"],
+		"migrations/0002_column.sql": {Data: []byte("ALTER TABLE t ADD COLUMN b TEXT;")},
+	}
+
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Every connection to :memory: is a new database.
+	db.SetMaxOpenConns(1)
+
+	for _, step := range []struct {
+		fsys    fstest.MapFS
+		version int
+	}{{v1, 1}, {v2, 2}, {v2, 2}} {
+		list, err := loadMigrations(step.fsys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := migrate(ctx, db, list); err != nil {
+			t.Fatalf("migrate to %d: %v", step.version, err)
+		}
+		var version int
+		if err := db.QueryRowContext(ctx, "SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+			t.Fatal(err)
+		}
+		if version != step.version {
+			t.Errorf("version = %d, want %d", version, step.version)
+		}
+	}
+	if _, err := db.ExecContext(ctx, "INSERT INTO t (a, b) VALUES ('x', 'y')"); err != nil {
+		t.Errorf("schema not migrated: %v", err)
+	}
+
+	list, _ := loadMigrations(v1)
+	err = migrate(ctx, db, list)
+	if err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("migrating back: got %v, want an error about a newer schema", err)
+	}
+}
+
+func TestLoadMigrationsRejectsBadNames(t *testing.T) {
+	for _, fsys := range []fstest.MapFS{
+		{"migrations/init.sql": {}},
+		{"migrations/0001_a.sql": {}, "migrations/1_b.sql": {}},
+	} {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Errorf("loadMigrations(%v) succeeded", fsys)
+		}
+	}
+}
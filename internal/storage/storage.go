@@ -0,0 +1,208 @@
+// Package storage defines the repositories for state kept by this service
+// rather than the billing API. Backends live in the memory and sqlite
+// subpackages; both report missing records with domain.ErrNotFound and
+// duplicates with domain.ErrConflict.
+package storage
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"time"
+)
+
+// Store groups the repositories of one backend.
+type Store interface {
+	Sessions() SessionRepository
+	ReadMarks() ReadMarkRepository
+	Preferences() PreferenceRepository
+	Audit() AuditRepository
+	OTP() OTPRepository
+	APIKeys() APIKeyRepository
+	PaymentIntents() PaymentIntentRepository
+	AutopayRules() AutopayRuleRepository
+	Inbox() InboxRepository
+	WatchedSuspensions() WatchedSuspensionRepository
+	// Ping checks that the backend is usable.
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+// Session is a signed-in session. The billing token itself is never stored
+// here; only autopay rules keep one, encrypted.
+type Session struct {
+	ID         string
+	ContractID string
+	UserAgent  string
+	RemoteIP   string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// Active reports whether the session can still be used at now.
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+type SessionRepository interface {
+	Create(ctx context.Context, session Session) error
+	Get(ctx context.Context, id string) (Session, error)
+	// ListByContract returns the sessions of a contract that are not
+	// revoked, newest first.
+	ListByContract(ctx context.Context, contractID string) ([]Session, error)
+	Touch(ctx context.Context, id string, at time.Time) error
+	Revoke(ctx context.Context, id string, at time.Time) error
+	// DeleteExpired removes sessions that expired before now and returns
+	// how many were removed.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// ReadMarkRepository remembers which notifications a contract has read.
+type ReadMarkRepository interface {
+	// MarkRead marks the notifications read at at. Marking one again keeps
+	// the time it was first read.
+	MarkRead(ctx context.Context, contractID string, notificationIDs []string, at time.Time) error
+	// ReadAt maps the IDs of read notifications to when they were read.
+	ReadAt(ctx context.Context, contractID string) (map[string]time.Time, error)
+}
+
+// PreferenceRepository keeps per-contract settings as key/value pairs.
+type PreferenceRepository interface {
+	Get(ctx context.Context, contractID, key string) (string, error)
+	List(ctx context.Context, contractID string) (map[string]string, error)
+	Set(ctx context.Context, contractID, key, value string) error
+	Delete(ctx context.Context, contractID, key string) error
+}
+
+// AuditEntry records an action taken on behalf of a contract.
+type AuditEntry struct {
+	ID         int64
+	At         time.Time
+	ContractID string
+	Action     string
+	Detail     string
+	RemoteIP   string
+}
+
+// AuditFilter selects audit entries. Zero fields don't filter.
+type AuditFilter struct {
+	ContractID string
+	Since      time.Time
+	Limit      int
+}
+
+type AuditRepository interface {
+	// Append stores the entry and returns the ID it was given.
+	Append(ctx context.Context, entry AuditEntry) (int64, error)
+	// List returns matching entries, newest first.
+	List(ctx context.Context, filter AuditFilter) ([]AuditEntry, error)
+}
+
+// OTPCode is a one-time code sent to Subject (a phone or an email) for
+// Purpose. Only a hash of the code is kept.
+type OTPCode struct {
+	Subject   string
+	Purpose   string
+	CodeHash  string
+	Attempts  int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+type OTPRepository interface {
+	// Save stores the code, replacing the one for the same subject and purpose.
+	Save(ctx context.Context, code OTPCode) error
+	Get(ctx context.Context, subject, purpose string) (OTPCode, error)
+	// IncrementAttempts counts a failed check and returns the new count.
+	IncrementAttempts(ctx context.Context, subject, purpose string) (int, error)
+	Delete(ctx context.Context, subject, purpose string) error
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// APIKey is an API key for integrations. Only a hash of its secret is kept.
+type APIKey struct {
+	ID         string
+	Name       string
+	SecretHash string
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  *time.Time
+	RevokedAt  *time.Time
+	LastUsedAt *time.Time
+	UseCount   int64
+}
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key APIKey) error
+	Get(ctx context.Context, id string) (APIKey, error)
+	// List returns every key, revoked and expired ones included, oldest
+	// first.
+	List(ctx context.Context) ([]APIKey, error)
+	// Expire moves the expiry of the key to at, unless it expires earlier.
+	Expire(ctx context.Context, id string, at time.Time) error
+	// Revoke disables the key. Revoking it again keeps the first time.
+	Revoke(ctx context.Context, id string, at time.Time) error
+	// RecordUse counts a request made with the key at at.
+	RecordUse(ctx context.Context, id string, at time.Time) error
+}
+
+type PaymentIntentRepository interface {
+	// Create stores a new intent. It returns domain.ErrConflict when the
+	// contract already used the intent's idempotency key.
+	Create(ctx context.Context, intent domain.PaymentIntent) error
+	Update(ctx context.Context, intent domain.PaymentIntent) error
+	Get(ctx context.Context, id string) (domain.PaymentIntent, error)
+	GetByIdempotencyKey(ctx context.Context, contractID, key string) (domain.PaymentIntent, error)
+	// TransitionStatus moves the intent from one status to another and
+	// returns domain.ErrConflict if it is not in the expected status.
+	TransitionStatus(ctx context.Context, id string, from, to domain.PaymentIntentStatus) error
+}
+
+// AutopayRuleRepository keeps one autopay rule per contract. The rule
+// carries the session token the scheduler charges with, which the autopay
+// package encrypts before saving; backends store it as given.
+type AutopayRuleRepository interface {
+	Get(ctx context.Context, contractID string) (domain.AutopayRule, error)
+	// Save creates the rule of the contract or replaces it.
+	Save(ctx context.Context, rule domain.AutopayRule) error
+	Delete(ctx context.Context, contractID string) error
+	List(ctx context.Context) ([]domain.AutopayRule, error)
+}
+
+// InboxMessage is a notification raised by this service for a contract.
+// Args holds the message arguments as encoded by the notification package.
+type InboxMessage struct {
+	ContractID string
+	Type       string
+	Key        string
+	Args       string
+	CreatedAt  time.Time
+}
+
+type InboxRepository interface {
+	// Push stores the message and drops the oldest messages of the
+	// contract beyond the newest keep.
+	Push(ctx context.Context, msg InboxMessage, keep int) error
+	// List returns the messages of a contract, oldest first.
+	List(ctx context.Context, contractID string) ([]InboxMessage, error)
+}
+
+// WatchedSuspension is a suspension scheduled through this service whose
+// start or end the user is still to be told about.
+type WatchedSuspension struct {
+	ContractID   string
+	SuspensionID string
+	StartDate    time.Time
+	EndDate      time.Time
+	// Started is set once the start has been announced.
+	Started bool
+}
+
+type WatchedSuspensionRepository interface {
+	Get(ctx context.Context, contractID, suspensionID string) (WatchedSuspension, error)
+	// Save creates the entry or replaces it.
+	Save(ctx context.Context, s WatchedSuspension) error
+	Delete(ctx context.Context, contractID, suspensionID string) error
+	List(ctx context.Context) ([]WatchedSuspension, error)
+}
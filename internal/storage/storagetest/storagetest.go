@@ -0,0 +1,629 @@
+// Package storagetest checks that a storage backend behaves like the others.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/storage"
+	"testing"
+	"time"
+)
+
+// Run runs the conformance tests against stores made by open. Every call
+// to open must return an empty store.
+func Run(t *testing.T, open func(t *testing.T) storage.Store) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, s storage.Store)
+	}{
+		{"Sessions", testSessions},
+		{"ReadMarks", testReadMarks},
+		{"Preferences", testPreferences},
+		{"Audit", testAudit},
+		{"OTP", testOTP},
+		{"APIKeys", testAPIKeys},
+		{"PaymentIntents", testPaymentIntents},
+		{"AutopayRules", testAutopayRules},
+		{"Inbox", testInbox},
+		{"WatchedSuspensions", testWatchedSuspensions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := open(t)
+			t.Cleanup(func() { s.Close() })
+			if err := s.Ping(context.Background()); err != nil {
+				t.Fatalf("Ping: %v", err)
+			}
+			tt.test(t, s)
+		})
+	}
+}
+
+var base = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func testSessions(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.Sessions()
+
+	first := storage.Session{
+		ID: "s1", ContractID: "c1", UserAgent: "curl", RemoteIP: "10.0.0.1",
+		CreatedAt: base, LastSeenAt: base, ExpiresAt: base.Add(time.Hour),
+	}
+	second := storage.Session{ID: "s2", ContractID: "c1", CreatedAt: base.Add(time.Minute), ExpiresAt: base.Add(2 * time.Hour)}
+	other := storage.Session{ID: "s3", ContractID: "c2", CreatedAt: base, ExpiresAt: base.Add(time.Hour)}
+	for _, session := range []storage.Session{first, second, other} {
+		if err := repo.Create(ctx, session); err != nil {
+			t.Fatalf("Create(%s): %v", session.ID, err)
+		}
+	}
+	if err := repo.Create(ctx, first); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("Create duplicate: got %v, want ErrConflict", err)
+	}
+
+	got, err := repo.Get(ctx, "s1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.ID != first.ID || got.ContractID != first.ContractID || got.UserAgent != first.UserAgent ||
+		got.RemoteIP != first.RemoteIP || !got.CreatedAt.Equal(first.CreatedAt) ||
+		!got.ExpiresAt.Equal(first.ExpiresAt) || got.RevokedAt != nil {
+		t.Errorf("Get = %+v, want %+v", got, first)
+	}
+	if !got.Active(base) || got.Active(base.Add(time.Hour)) {
+		t.Errorf("Active should hold until ExpiresAt")
+	}
+	if _, err := repo.Get(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Get missing: got %v, want ErrNotFound", err)
+	}
+
+	seen := base.Add(10 * time.Minute)
+	if err := repo.Touch(ctx, "s1", seen); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if got, _ := repo.Get(ctx, "s1"); !got.LastSeenAt.Equal(seen) {
+		t.Errorf("LastSeenAt = %v, want %v", got.LastSeenAt, seen)
+	}
+	if err := repo.Touch(ctx, "missing", seen); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Touch missing: got %v, want ErrNotFound", err)
+	}
+
+	list, err := repo.ListByContract(ctx, "c1")
+	if err != nil {
+		t.Fatalf("ListByContract: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "s2" || list[1].ID != "s1" {
+		t.Errorf("ListByContract = %v, want s2, s1", ids(list))
+	}
+
+	revoked := base.Add(20 * time.Minute)
+	if err := repo.Revoke(ctx, "s2", revoked); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	// Revoking again keeps the first time.
+	if err := repo.Revoke(ctx, "s2", revoked.Add(time.Minute)); err != nil {
+		t.Fatalf("Revoke again: %v", err)
+	}
+	got, _ = repo.Get(ctx, "s2")
+	if got.RevokedAt == nil || !got.RevokedAt.Equal(revoked) {
+		t.Errorf("RevokedAt = %v, want %v", got.RevokedAt, revoked)
+	}
+	if got.Active(base) {
+		t.Errorf("revoked session should not be active")
+	}
+	if list, _ := repo.ListByContract(ctx, "c1"); len(list) != 1 || list[0].ID != "s1" {
+		t.Errorf("ListByContract after revoke = %v, want s1", ids(list))
+	}
+
+	n, err := repo.DeleteExpired(ctx, base.Add(90*time.Minute))
+	if err != nil {
+		t.Fatalf("DeleteExpired: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("DeleteExpired removed %d, want 2", n)
+	}
+	if _, err := repo.Get(ctx, "s2"); err != nil {
+		t.Errorf("unexpired session was removed: %v", err)
+	}
+}
+
+func ids(list []storage.Session) []string {
+	var ids []string
+	for _, s := range list {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func testReadMarks(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.ReadMarks()
+
+	if marks, err := repo.ReadAt(ctx, "c1"); err != nil || len(marks) != 0 {
+		t.Fatalf("ReadAt empty = %v, %v", marks, err)
+	}
+	if err := repo.MarkRead(ctx, "c1", []string{"n1", "n2"}, base); err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+	later := base.Add(time.Hour)
+	if err := repo.MarkRead(ctx, "c1", []string{"n2", "n3"}, later); err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+	if err := repo.MarkRead(ctx, "c2", []string{"n1"}, later); err != nil {
+		t.Fatalf("MarkRead: %v", err)
+	}
+
+	marks, err := repo.ReadAt(ctx, "c1")
+	if err != nil {
+		t.Fatalf("ReadAt: %v", err)
+	}
+	want := map[string]time.Time{"n1": base, "n2": base, "n3": later}
+	if len(marks) != len(want) {
+		t.Fatalf("ReadAt = %v, want %v", marks, want)
+	}
+	for id, at := range want {
+		if !marks[id].Equal(at) {
+			t.Errorf("ReadAt[%s] = %v, want %v", id, marks[id], at)
+		}
+	}
+}
+
+func testPreferences(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.Preferences()
+
+	if _, err := repo.Get(ctx, "c1", "lang"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Get missing: got %v, want ErrNotFound", err)
+	}
+	for _, kv := range [][2]string{{"lang", "en"}, {"theme", "dark"}, {"lang", "ru"}} {
+		if err := repo.Set(ctx, "c1", kv[0], kv[1]); err != nil {
+			t.Fatalf("Set(%s): %v", kv[0], err)
+		}
+	}
+	if err := repo.Set(ctx, "c2", "lang", "en"); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	if got, err := repo.Get(ctx, "c1", "lang"); err != nil || got != "ru" {
+		t.Errorf("Get = %q, %v, want ru", got, err)
+	}
+	prefs, err := repo.List(ctx, "c1")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(prefs) != 2 || prefs["lang"] != "ru" || prefs["theme"] != "dark" {
+		t.Errorf("List = %v", prefs)
+	}
+
+	if err := repo.Delete(ctx, "c1", "theme"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete(ctx, "c1", "missing"); err != nil {
+		t.Errorf("Delete missing: %v", err)
+	}
+	if prefs, _ := repo.List(ctx, "c1"); len(prefs) != 1 {
+		t.Errorf("List after delete = %v", prefs)
+	}
+}
+
+func testAudit(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.Audit()
+
+	entries := []storage.AuditEntry{
+		{At: base, ContractID: "c1", Action: "login", RemoteIP: "10.0.0.1"},
+		{At: base.Add(2 * time.Hour), ContractID: "c1", Action: "tariff.change", Detail: "t2"},
+		{At: base.Add(time.Hour), ContractID: "c2", Action: "login"},
+		{At: base.Add(time.Hour), ContractID: "c1", Action: "profile.update"},
+	}
+	var lastID int64
+	for _, e := range entries {
+		id, err := repo.Append(ctx, e)
+		if err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+		if id <= lastID {
+			t.Errorf("Append returned ID %d after %d", id, lastID)
+		}
+		lastID = id
+	}
+
+	tests := []struct {
+		name   string
+		filter storage.AuditFilter
+		want   []string
+	}{
+		{"all", storage.AuditFilter{}, []string{"tariff.change", "profile.update", "login", "login"}},
+		{"contract", storage.AuditFilter{ContractID: "c1"}, []string{"tariff.change", "profile.update", "login"}},
+		{"since", storage.AuditFilter{ContractID: "c1", Since: base.Add(time.Hour)}, []string{"tariff.change", "profile.update"}},
+		{"limit", storage.AuditFilter{Limit: 1}, []string{"tariff.change"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := repo.List(ctx, tt.filter)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var got []string
+			for _, e := range list {
+				got = append(got, e.Action)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("List = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("List = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	list, _ := repo.List(ctx, storage.AuditFilter{ContractID: "c1", Limit: 1})
+	if e := list[0]; e.Detail != "t2" || !e.At.Equal(base.Add(2*time.Hour)) || e.ID == 0 {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func testOTP(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.OTP()
+
+	code := storage.OTPCode{
+		Subject: "+79991234567", Purpose: "phone", CodeHash: "h1",
+		CreatedAt: base, ExpiresAt: base.Add(5 * time.Minute),
+	}
+	if err := repo.Save(ctx, code); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if n, err := repo.IncrementAttempts(ctx, code.Subject, code.Purpose); err != nil || n != 1 {
+		t.Errorf("IncrementAttempts = %d, %v, want 1", n, err)
+	}
+	if n, _ := repo.IncrementAttempts(ctx, code.Subject, code.Purpose); n != 2 {
+		t.Errorf("IncrementAttempts = %d, want 2", n)
+	}
+	if _, err := repo.IncrementAttempts(ctx, code.Subject, "email"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("IncrementAttempts missing: got %v, want ErrNotFound", err)
+	}
+
+	// A new code replaces the old one and resets the attempts.
+	code.CodeHash = "h2"
+	code.ExpiresAt = base.Add(10 * time.Minute)
+	if err := repo.Save(ctx, code); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := repo.Get(ctx, code.Subject, code.Purpose)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.CodeHash != "h2" || got.Attempts != 0 || !got.ExpiresAt.Equal(code.ExpiresAt) || !got.CreatedAt.Equal(base) {
+		t.Errorf("Get = %+v", got)
+	}
+
+	other := storage.OTPCode{Subject: "a@b.c", Purpose: "email", CodeHash: "h3", CreatedAt: base, ExpiresAt: base.Add(time.Minute)}
+	if err := repo.Save(ctx, other); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if n, err := repo.DeleteExpired(ctx, base.Add(2*time.Minute)); err != nil || n != 1 {
+		t.Errorf("DeleteExpired = %d, %v, want 1", n, err)
+	}
+	if _, err := repo.Get(ctx, other.Subject, other.Purpose); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expired code still stored: %v", err)
+	}
+
+	if err := repo.Delete(ctx, code.Subject, code.Purpose); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := repo.Get(ctx, code.Subject, code.Purpose); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Get after delete: got %v, want ErrNotFound", err)
+	}
+}
+
+func testAPIKeys(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.APIKeys()
+
+	expires := base.Add(30 * 24 * time.Hour)
+	first := storage.APIKey{
+		ID: "k1", Name: "partner-acme", SecretHash: "h1", Scopes: []string{"partner", "swagger"},
+		CreatedAt: base, ExpiresAt: &expires,
+	}
+	second := storage.APIKey{ID: "k2", Name: "ops", SecretHash: "h2", Scopes: []string{"admin"}, CreatedAt: base.Add(time.Minute)}
+	for _, key := range []storage.APIKey{second, first} {
+		if err := repo.Create(ctx, key); err != nil {
+			t.Fatalf("Create(%s): %v", key.ID, err)
+		}
+	}
+	if err := repo.Create(ctx, first); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("Create duplicate: got %v, want ErrConflict", err)
+	}
+
+	got, err := repo.Get(ctx, "k1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Name != first.Name || got.SecretHash != first.SecretHash || len(got.Scopes) != 2 || got.Scopes[1] != "swagger" ||
+		!got.CreatedAt.Equal(base) || got.ExpiresAt == nil || !got.ExpiresAt.Equal(expires) ||
+		got.RevokedAt != nil || got.LastUsedAt != nil || got.UseCount != 0 {
+		t.Errorf("Get = %+v, want %+v", got, first)
+	}
+	if _, err := repo.Get(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Get missing: got %v, want ErrNotFound", err)
+	}
+
+	used := base.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		if err := repo.RecordUse(ctx, "k2", used); err != nil {
+			t.Fatalf("RecordUse: %v", err)
+		}
+	}
+	if got, _ := repo.Get(ctx, "k2"); got.UseCount != 2 || got.LastUsedAt == nil || !got.LastUsedAt.Equal(used) {
+		t.Errorf("after RecordUse = %+v", got)
+	}
+	if err := repo.RecordUse(ctx, "missing", used); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("RecordUse missing: got %v, want ErrNotFound", err)
+	}
+
+	// Expire only ever brings the expiry forward.
+	soon := base.Add(2 * time.Hour)
+	if err := repo.Expire(ctx, "k1", soon); err != nil {
+		t.Fatalf("Expire: %v", err)
+	}
+	if err := repo.Expire(ctx, "k1", soon.Add(time.Hour)); err != nil {
+		t.Fatalf("Expire later: %v", err)
+	}
+	if err := repo.Expire(ctx, "k2", soon); err != nil {
+		t.Fatalf("Expire: %v", err)
+	}
+	for _, id := range []string{"k1", "k2"} {
+		if got, _ := repo.Get(ctx, id); got.ExpiresAt == nil || !got.ExpiresAt.Equal(soon) {
+			t.Errorf("%s ExpiresAt = %v, want %v", id, got.ExpiresAt, soon)
+		}
+	}
+	if err := repo.Expire(ctx, "missing", soon); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Expire missing: got %v, want ErrNotFound", err)
+	}
+
+	revoked := base.Add(3 * time.Hour)
+	if err := repo.Revoke(ctx, "k2", revoked); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if err := repo.Revoke(ctx, "k2", revoked.Add(time.Minute)); err != nil {
+		t.Fatalf("Revoke again: %v", err)
+	}
+	if got, _ := repo.Get(ctx, "k2"); got.RevokedAt == nil || !got.RevokedAt.Equal(revoked) {
+		t.Errorf("RevokedAt = %v, want %v", got.RevokedAt, revoked)
+	}
+
+	list, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "k1" || list[1].ID != "k2" {
+		t.Errorf("List = %+v, want k1, k2", list)
+	}
+}
+
+func testPaymentIntents(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.PaymentIntents()
+
+	intent := domain.PaymentIntent{
+		ID: "i1", ContractID: "c1", Amount: domain.RUB(65050), Status: domain.PaymentIntentPending,
+		IdempotencyKey: "k1", CreatedAt: base,
+	}
+	if err := repo.Create(ctx, intent); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, dup := range []domain.PaymentIntent{
+		{ID: "i1", ContractID: "c2", Status: domain.PaymentIntentPending, CreatedAt: base},
+		{ID: "i2", ContractID: "c1", IdempotencyKey: "k1", Status: domain.PaymentIntentPending, CreatedAt: base},
+	} {
+		if err := repo.Create(ctx, dup); !errors.Is(err, domain.ErrConflict) {
+			t.Errorf("Create(%s, %q): got %v, want ErrConflict", dup.ID, dup.IdempotencyKey, err)
+		}
+	}
+	// Keys are per contract, and intents without one don't clash.
+	for _, other := range []domain.PaymentIntent{
+		{ID: "i3", ContractID: "c2", IdempotencyKey: "k1", Status: domain.PaymentIntentPending, CreatedAt: base},
+		{ID: "i4", ContractID: "c1", Status: domain.PaymentIntentPending, CreatedAt: base},
+		{ID: "i5", ContractID: "c1", Status: domain.PaymentIntentPending, CreatedAt: base},
+	} {
+		if err := repo.Create(ctx, other); err != nil {
+			t.Errorf("Create(%s): %v", other.ID, err)
+		}
+	}
+
+	got, err := repo.GetByIdempotencyKey(ctx, "c1", "k1")
+	if err != nil {
+		t.Fatalf("GetByIdempotencyKey: %v", err)
+	}
+	if got.ID != "i1" || got.Amount != intent.Amount || got.Status != intent.Status || !got.CreatedAt.Equal(base) {
+		t.Errorf("GetByIdempotencyKey = %+v, want %+v", got, intent)
+	}
+	if _, err := repo.GetByIdempotencyKey(ctx, "c1", "k2"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("GetByIdempotencyKey missing: got %v, want ErrNotFound", err)
+	}
+
+	intent.ProviderID = "p1"
+	intent.ConfirmationURL = "https://pay.example/p1"
+	if err := repo.Update(ctx, intent); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := repo.Get(ctx, "i1"); got.ProviderID != "p1" || got.ConfirmationURL != intent.ConfirmationURL {
+		t.Errorf("after Update = %+v", got)
+	}
+	if err := repo.Update(ctx, domain.PaymentIntent{ID: "missing"}); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Update missing: got %v, want ErrNotFound", err)
+	}
+
+	if err := repo.TransitionStatus(ctx, "i1", domain.PaymentIntentPending, domain.PaymentIntentProcessing); err != nil {
+		t.Fatalf("TransitionStatus: %v", err)
+	}
+	if err := repo.TransitionStatus(ctx, "i1", domain.PaymentIntentPending, domain.PaymentIntentProcessing); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("TransitionStatus from a stale status: got %v, want ErrConflict", err)
+	}
+	if err := repo.TransitionStatus(ctx, "missing", domain.PaymentIntentPending, domain.PaymentIntentProcessing); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("TransitionStatus missing: got %v, want ErrNotFound", err)
+	}
+	if got, _ := repo.Get(ctx, "i1"); got.Status != domain.PaymentIntentProcessing {
+		t.Errorf("status = %s, want %s", got.Status, domain.PaymentIntentProcessing)
+	}
+	if _, err := repo.Get(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Get missing: got %v, want ErrNotFound", err)
+	}
+}
+
+func testAutopayRules(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.AutopayRules()
+
+	if list, err := repo.List(ctx); err != nil || len(list) != 0 {
+		t.Fatalf("List empty = %v, %v", list, err)
+	}
+	charged := base.Add(time.Hour)
+	threshold, amount := domain.RUB(10000), domain.RUB(50050)
+	rule := domain.AutopayRule{
+		ContractID: "c2",
+		Principal:  domain.Principal{Token: "t2", ContractID: "c2"},
+		Method:     domain.PaymentMethod{ID: "pm_1", Title: "Card •••• 4242"},
+		Mode:       domain.AutopayThreshold,
+		Status:     domain.AutopayActive,
+		Threshold:  &threshold,
+		Amount:     &amount,
+		LastPeriod: "2024-03-01", RetryPeriod: "2024-03-02", Attempts: 2,
+		NextAttemptAt: base.Add(2 * time.Hour), LastChargeAt: &charged, LastError: "declined",
+	}
+	other := domain.AutopayRule{
+		ContractID: "c1", Principal: domain.Principal{Token: "t1"}, Method: domain.PaymentMethod{ID: "pm_2"},
+		Mode: domain.AutopayBeforeDue, Status: domain.AutopayPaused, DaysBefore: 3,
+	}
+	for _, r := range []domain.AutopayRule{rule, other} {
+		if err := repo.Save(ctx, r); err != nil {
+			t.Fatalf("Save(%s): %v", r.ContractID, err)
+		}
+	}
+
+	got, err := repo.Get(ctx, "c2")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Principal != rule.Principal || got.Method != rule.Method || got.Mode != rule.Mode || got.Status != rule.Status ||
+		got.Threshold == nil || *got.Threshold != threshold || got.Amount == nil || *got.Amount != amount ||
+		got.LastPeriod != rule.LastPeriod || got.RetryPeriod != rule.RetryPeriod || got.Attempts != 2 ||
+		!got.NextAttemptAt.Equal(rule.NextAttemptAt) || got.LastChargeAt == nil || !got.LastChargeAt.Equal(charged) ||
+		got.LastError != rule.LastError {
+		t.Errorf("Get = %+v, want %+v", got, rule)
+	}
+	got, _ = repo.Get(ctx, "c1")
+	if got.Threshold != nil || got.Amount != nil || !got.NextAttemptAt.IsZero() || got.LastChargeAt != nil || got.DaysBefore != 3 {
+		t.Errorf("Get = %+v, want %+v", got, other)
+	}
+	if _, err := repo.Get(ctx, "missing"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Get missing: got %v, want ErrNotFound", err)
+	}
+
+	// Saving again replaces the rule.
+	other.Status = domain.AutopayActive
+	if err := repo.Save(ctx, other); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	list, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].ContractID != "c1" || list[0].Status != domain.AutopayActive || list[1].ContractID != "c2" {
+		t.Errorf("List = %+v, want c1 active, c2", list)
+	}
+
+	if err := repo.Delete(ctx, "c1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete(ctx, "c1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Delete missing: got %v, want ErrNotFound", err)
+	}
+	if list, _ := repo.List(ctx); len(list) != 1 {
+		t.Errorf("List after delete = %+v", list)
+	}
+}
+
+func testInbox(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.Inbox()
+
+	if list, err := repo.List(ctx, "c1"); err != nil || len(list) != 0 {
+		t.Fatalf("List empty = %v, %v", list, err)
+	}
+	for i, key := range []string{"m1", "m2", "m3", "m4"} {
+		msg := storage.InboxMessage{ContractID: "c1", Type: "autopay", Key: key, Args: `["650.00"]`, CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+		if err := repo.Push(ctx, msg, 3); err != nil {
+			t.Fatalf("Push(%s): %v", key, err)
+		}
+	}
+	if err := repo.Push(ctx, storage.InboxMessage{ContractID: "c2", Type: "suspension", Key: "m5", CreatedAt: base}, 3); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	list, err := repo.List(ctx, "c1")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	// Only the newest three are kept, oldest first.
+	if len(list) != 3 || list[0].Key != "m2" || list[2].Key != "m4" {
+		t.Fatalf("List = %+v, want m2..m4", list)
+	}
+	if m := list[0]; m.ContractID != "c1" || m.Type != "autopay" || m.Args != `["650.00"]` || !m.CreatedAt.Equal(base.Add(time.Minute)) {
+		t.Errorf("message = %+v", m)
+	}
+	if list, _ := repo.List(ctx, "c2"); len(list) != 1 {
+		t.Errorf("List c2 = %+v, want one message", list)
+	}
+}
+
+func testWatchedSuspensions(t *testing.T, s storage.Store) {
+	ctx := context.Background()
+	repo := s.WatchedSuspensions()
+
+	if list, err := repo.List(ctx); err != nil || len(list) != 0 {
+		t.Fatalf("List empty = %v, %v", list, err)
+	}
+	start := time.Date(2024, 7, 1, 0, 0, 0, 0, time.Local)
+	watched := storage.WatchedSuspension{ContractID: "c2", SuspensionID: "s1", StartDate: start, EndDate: start.AddDate(0, 0, 13)}
+	for _, w := range []storage.WatchedSuspension{watched, {ContractID: "c1", SuspensionID: "s1", StartDate: start, EndDate: start}} {
+		if err := repo.Save(ctx, w); err != nil {
+			t.Fatalf("Save(%s): %v", w.ContractID, err)
+		}
+	}
+
+	got, err := repo.Get(ctx, "c2", "s1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	// Dates come back in local time, so they format as the same day.
+	if got.Started || got.StartDate.Format(time.DateOnly) != "2024-07-01" || !got.EndDate.Equal(watched.EndDate) {
+		t.Errorf("Get = %+v, want %+v", got, watched)
+	}
+	if _, err := repo.Get(ctx, "c2", "s2"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Get missing: got %v, want ErrNotFound", err)
+	}
+
+	// Saving again replaces the entry.
+	watched.Started = true
+	if err := repo.Save(ctx, watched); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	list, err := repo.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 2 || list[0].ContractID != "c1" || list[1].ContractID != "c2" || !list[1].Started {
+		t.Errorf("List = %+v, want c1, then c2 started", list)
+	}
+
+	if err := repo.Delete(ctx, "c2", "s1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := repo.Delete(ctx, "c2", "s1"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Delete missing: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,80 @@
+// Package tracing sets up OpenTelemetry tracing and starts the spans of the
+// handler, service and repository layers.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"strings"
+)
+
+// ServiceName identifies this service in exported spans.
+const ServiceName = "spektr-account-api"
+
+const instrumentationName = "github.com/llchhh/spektr-account-api"
+
+// Setup installs the global tracer provider. exporter selects where spans
+// go:
+//   - "" or "none": nowhere, spans are not recorded;
+//   - "stdout": w, one JSON document per span;
+//   - "otlp": an OTLP/HTTP collector configured by the standard
+//     OTEL_EXPORTER_OTLP_* variables, e.g.
+//     OTEL_EXPORTER_OTLP_ENDPOINT=http://localhost:4318.
+//
+// The returned function flushes pending spans and must be called before exit.
+func Setup(ctx context.Context, exporter string, w io.Writer) (shutdown func(context.Context) error, err error) {
+	// Trace context is accepted and forwarded even when nothing is
+	// exported, so callers' traces stay connected.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var exp sdktrace.SpanExporter
+	switch strings.ToLower(exporter) {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case "otlp":
+		exp, err = otlptracehttp.New(ctx)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create %s trace exporter: %w", exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL, semconv.ServiceName(ServiceName),
+	))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span as a child of the one in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// Fail marks the span as failed with err.
+func Fail(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,34 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	ctx := context.Background()
+	if _, err := Setup(ctx, "zipkin", nil); err == nil {
+		t.Error("Setup accepted an unknown exporter")
+	}
+	shutdown, err := Setup(ctx, "none", nil)
+	if err != nil {
+		t.Fatalf("Setup(none) error = %v", err)
+	}
+	shutdown(ctx)
+
+	var buf bytes.Buffer
+	shutdown, err = Setup(ctx, "stdout", &buf)
+	if err != nil {
+		t.Fatalf("Setup(stdout) error = %v", err)
+	}
+	_, span := Start(ctx, "test.Span")
+	span.End()
+	if err := shutdown(ctx); err != nil {
+		t.Fatalf("shutdown error = %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "test.Span") || !strings.Contains(out, ServiceName) {
+		t.Errorf("exported span = %s", out)
+	}
+}
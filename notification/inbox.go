@@ -0,0 +1,122 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/i18n"
+	"github.com/llchhh/spektr-account-api/internal/storage"
+	"time"
+)
+
+// maxInboxSize bounds how many local notifications are kept per contract.
+const maxInboxSize = 50
+
+// Message is a notification raised by this service rather than the billing
+// API. It keeps the catalogue key and arguments instead of the rendered text
+// so it can be shown in the language of whoever reads it.
+type Message struct {
+	Type      string
+	Key       string
+	Args      []any
+	CreatedAt time.Time
+}
+
+// Plural is a Message argument rendered with Localizer.Plural, e.g. a
+// number of days.
+type Plural struct {
+	Key string
+	N   int
+}
+
+// Inbox stores local notifications per contract.
+type Inbox interface {
+	Push(ctx context.Context, contractID string, msg Message) error
+	List(ctx context.Context, contractID string) ([]Message, error)
+}
+
+// render localises a message for the reader.
+func render(l *i18n.Localizer, msg Message) domain.Notification {
+	args := make([]any, len(msg.Args))
+	for i, arg := range msg.Args {
+		if p, ok := arg.(Plural); ok {
+			arg = l.Plural(p.Key, p.N)
+		}
+		args[i] = arg
+	}
+	return domain.Notification{
+		Title: notificationTitle(l, msg.Type),
+		Body:  l.T(msg.Key, args...),
+		Type:  msg.Type,
+	}
+}
+
+// storeInbox is an Inbox kept in the service's storage.
+type storeInbox struct {
+	repo storage.InboxRepository
+}
+
+// NewInbox creates an Inbox on top of repo, keeping the newest
+// maxInboxSize messages per contract.
+func NewInbox(repo storage.InboxRepository) Inbox {
+	return storeInbox{repo: repo}
+}
+
+// storedArg is a message argument as saved in storage. Plurals keep their
+// key and count so they are still rendered in the reader's language; any
+// other argument is saved as its text, which is how the catalogue's %s
+// verbs print it anyway.
+type storedArg struct {
+	Text   string `json:"text,omitempty"`
+	Plural string `json:"plural,omitempty"`
+	N      int    `json:"n,omitempty"`
+}
+
+func (s storeInbox) Push(ctx context.Context, contractID string, msg Message) error {
+	args := make([]storedArg, len(msg.Args))
+	for i, arg := range msg.Args {
+		if p, ok := arg.(Plural); ok {
+			args[i] = storedArg{Plural: p.Key, N: p.N}
+		} else {
+			args[i] = storedArg{Text: fmt.Sprint(arg)}
+		}
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	return s.repo.Push(ctx, storage.InboxMessage{
+		ContractID: contractID,
+		Type:       msg.Type,
+		Key:        msg.Key,
+		Args:       string(data),
+		CreatedAt:  msg.CreatedAt,
+	}, maxInboxSize)
+}
+
+func (s storeInbox) List(ctx context.Context, contractID string) ([]Message, error) {
+	stored, err := s.repo.List(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]Message, 0, len(stored))
+	for _, m := range stored {
+		var args []storedArg
+		if m.Args != "" {
+			if err := json.Unmarshal([]byte(m.Args), &args); err != nil {
+				return nil, fmt.Errorf("notification %s arguments: %w", m.Key, err)
+			}
+		}
+		msg := Message{Type: m.Type, Key: m.Key, CreatedAt: m.CreatedAt}
+		for _, arg := range args {
+			if arg.Plural != "" {
+				msg.Args = append(msg.Args, Plural{Key: arg.Plural, N: arg.N})
+			} else {
+				msg.Args = append(msg.Args, arg.Text)
+			}
+		}
+		messages = append(messages, msg)
+	}
+	return messages, nil
+}
@@ -3,42 +3,114 @@ package notification
 import (
 	"context"
 	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/i18n"
 	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
-	"log"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+	"slices"
+	"time"
 )
 
 type NotificationRepository interface {
 	GetNotifications(ctx context.Context, token string) ([]domain.Notification, error)
 }
 
+// ProfileRepository resolves the contract a session belongs to.
+type ProfileRepository interface {
+	Profile(ctx context.Context, token string) (domain.Profile, error)
+}
+
 type Service struct {
 	notificationRepo NotificationRepository
+	profileRepo      ProfileRepository
+	inbox            Inbox
+	logger           *slog.Logger
 }
 
 // NewService creates a new Service instance with the provided NotificationRepository.
-func NewService(n NotificationRepository) *Service {
+// Local notifications from inbox are merged into the billing ones.
+func NewService(n NotificationRepository, p ProfileRepository, inbox Inbox, logger *slog.Logger) *Service {
 	return &Service{
 		notificationRepo: n,
+		profileRepo:      p,
+		inbox:            inbox,
+		logger:           logger,
 	}
 }
 
+// Notify raises a local notification for the contract. key and args refer
+// to the message catalogue and are rendered when the user reads them.
+func (s *Service) Notify(ctx context.Context, contractID, notificationType, key string, args ...any) error {
+	ctx, span := tracing.Start(ctx, "notification.Notify")
+	defer span.End()
+
+	s.logger.InfoContext(ctx, "Raising notification", "type", notificationType, "key", key, "contract_id", contractID)
+	return s.inbox.Push(ctx, contractID, Message{
+		Type:      notificationType,
+		Key:       key,
+		Args:      args,
+		CreatedAt: time.Now(),
+	})
+}
+
 // GetNotifications retrieves a list of notifications for the user using the provided token.
 func (s *Service) GetNotifications(ctx context.Context, token string) ([]domain.Notification, error) {
+	ctx, span := tracing.Start(ctx, "notification.GetNotifications")
+	defer span.End()
+
 	if token == "" {
-		log.Println("GetNotifications request failed: missing authorization token")
+		s.logger.WarnContext(ctx, "GetNotifications request failed: missing authorization token")
 		return nil, domain.ErrInvalidToken
 	}
 	if middleware.ContainsForbiddenChars(token) {
 		return nil, domain.ErrInvalidToken
 	}
-	log.Printf("Fetching notifications for token: %s", token)
+	s.logger.DebugContext(ctx, "Fetching notifications")
 
 	notifications, err := s.notificationRepo.GetNotifications(ctx, token)
 	if err != nil {
-		log.Printf("Error fetching notifications for token %s: %v", token, err)
+		s.logger.ErrorContext(ctx, "Error fetching notifications", "error", err)
 		return nil, err
 	}
 
-	log.Printf("Successfully fetched notifications for token: %s", token)
+	localizer := i18n.FromContext(ctx)
+	for i := range notifications {
+		notifications[i].Title = notificationTitle(localizer, notifications[i].Type)
+	}
+
+	// The billing notifications are still worth showing without the local ones.
+	local, err := s.localNotifications(ctx, token)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching local notifications, returning billing ones only", "error", err)
+	}
+	for _, msg := range local {
+		notifications = append(notifications, render(localizer, msg))
+	}
+
+	s.logger.DebugContext(ctx, "Successfully fetched notifications")
 	return notifications, nil
 }
+
+// localNotifications returns the inbox of the session's contract, newest first.
+func (s *Service) localNotifications(ctx context.Context, token string) ([]Message, error) {
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	messages, err := s.inbox.List(ctx, profile.ID)
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(messages)
+	return messages, nil
+}
+
+// notificationTitle returns a localised title for the notification type,
+// falling back to the raw type when the catalogue does not know it.
+func notificationTitle(l *i18n.Localizer, notificationType string) string {
+	key := "notification.type." + notificationType
+	if !l.Has(key) {
+		return notificationType
+	}
+	return l.T(key)
+}
@@ -0,0 +1,96 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+type stubRepos struct {
+	notifications []domain.Notification
+	profileErr    error
+}
+
+func (s stubRepos) GetNotifications(context.Context, string) ([]domain.Notification, error) {
+	return append([]domain.Notification(nil), s.notifications...), nil
+}
+
+func (s stubRepos) Profile(context.Context, string) (domain.Profile, error) {
+	return domain.Profile{ID: "1001"}, s.profileErr
+}
+
+type failingInbox struct{}
+
+func (failingInbox) Push(context.Context, string, Message) error { return errors.New("disk full") }
+
+func (failingInbox) List(context.Context, string) ([]Message, error) {
+	return nil, errors.New("disk full")
+}
+
+func TestGetNotifications(t *testing.T) {
+	billing := []domain.Notification{{Type: "payment", Body: "Payment received"}}
+	inbox := NewInbox(memory.New().Inbox())
+	inbox.Push(context.Background(), "1001", Message{Type: "suspension", Key: "suspension.started", CreatedAt: time.Now()})
+
+	tests := []struct {
+		name  string
+		repos stubRepos
+		inbox Inbox
+		want  int
+	}{
+		{"merged", stubRepos{notifications: billing}, inbox, 2},
+		{"profile unavailable", stubRepos{notifications: billing, profileErr: domain.ErrInternalServerError}, inbox, 1},
+		{"inbox unavailable", stubRepos{notifications: billing}, failingInbox{}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(tt.repos, tt.repos, tt.inbox, logging.Discard())
+			got, err := svc.GetNotifications(context.Background(), "token")
+			if err != nil {
+				t.Fatalf("GetNotifications() error = %v", err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got %d notifications, want %d", len(got), tt.want)
+			}
+			if got[0].Type != "payment" {
+				t.Errorf("first notification = %+v, want the billing one", got[0])
+			}
+		})
+	}
+}
+
+func TestInbox(t *testing.T) {
+	ctx := context.Background()
+	inbox := NewInbox(memory.New().Inbox())
+	created := time.Date(2024, 5, 28, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < maxInboxSize+1; i++ {
+		msg := Message{Type: "autopay", Key: "autopay.retry", Args: []any{domain.RUB(65050), Plural{Key: "common.hours", N: i}}, CreatedAt: created}
+		if err := inbox.Push(ctx, "1001", msg); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	}
+
+	got, err := inbox.List(ctx, "1001")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(got) != maxInboxSize {
+		t.Fatalf("got %d messages, want %d", len(got), maxInboxSize)
+	}
+	// The oldest message is dropped; arguments come back ready to render.
+	msg := got[0]
+	if msg.Type != "autopay" || msg.Key != "autopay.retry" || !msg.CreatedAt.Equal(created) || len(msg.Args) != 2 {
+		t.Fatalf("message = %+v", msg)
+	}
+	if msg.Args[0] != "650.50" {
+		t.Errorf("amount = %#v, want \"650.50\"", msg.Args[0])
+	}
+	if msg.Args[1] != (Plural{Key: "common.hours", N: 1}) {
+		t.Errorf("plural = %#v, want common.hours with 1", msg.Args[1])
+	}
+}
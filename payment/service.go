@@ -0,0 +1,106 @@
+package payment
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+	// defaultPeriod is how far back the history goes when no range is given.
+	defaultPeriod = 90 * 24 * time.Hour
+	// maxPeriod keeps a single upstream call from scanning years of history.
+	maxPeriod = 366 * 24 * time.Hour
+)
+
+type PaymentRepository interface {
+	Payments(ctx context.Context, token string, from, to time.Time) ([]domain.Payment, error)
+}
+
+type Service struct {
+	paymentRepo PaymentRepository
+	logger      *slog.Logger
+}
+
+// NewService creates a new Service instance with the provided PaymentRepository.
+func NewService(p PaymentRepository, logger *slog.Logger) *Service {
+	return &Service{
+		paymentRepo: p,
+		logger:      logger,
+	}
+}
+
+// Payments returns one page of the user's payment history within the filter's date range.
+func (s *Service) Payments(ctx context.Context, token string, filter domain.PaymentFilter) (domain.PaymentPage, error) {
+	ctx, span := tracing.Start(ctx, "payment.Payments")
+	defer span.End()
+
+	if token == "" {
+		s.logger.WarnContext(ctx, "Payments request failed: missing authorization token")
+		return domain.PaymentPage{}, domain.ErrInvalidToken
+	}
+	if middleware.ContainsForbiddenChars(token) {
+		return domain.PaymentPage{}, domain.ErrInvalidToken
+	}
+
+	filter, err := normalizeFilter(filter, time.Now())
+	if err != nil {
+		return domain.PaymentPage{}, err
+	}
+	s.logger.DebugContext(ctx, "Fetching payments")
+
+	payments, err := s.paymentRepo.Payments(ctx, token, filter.From, filter.To)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching payments", "error", err)
+		return domain.PaymentPage{}, err
+	}
+
+	sort.SliceStable(payments, func(i, j int) bool {
+		return payments[i].Date.After(payments[j].Date)
+	})
+
+	page := domain.PaymentPage{
+		Items:   []domain.Payment{},
+		Page:    filter.Page,
+		PerPage: filter.PerPage,
+		Total:   len(payments),
+	}
+	start := (filter.Page - 1) * filter.PerPage
+	if start < len(payments) {
+		end := min(start+filter.PerPage, len(payments))
+		page.Items = payments[start:end]
+	}
+
+	s.logger.DebugContext(ctx, "Successfully fetched payments")
+	return page, nil
+}
+
+// normalizeFilter fills in defaults and rejects ranges the billing API can't serve.
+func normalizeFilter(filter domain.PaymentFilter, now time.Time) (domain.PaymentFilter, error) {
+	if filter.To.IsZero() {
+		filter.To = now
+	}
+	if filter.From.IsZero() {
+		filter.From = filter.To.Add(-defaultPeriod)
+	}
+	if filter.From.After(filter.To) || filter.To.Sub(filter.From) > maxPeriod {
+		return domain.PaymentFilter{}, domain.ErrBadParamInput
+	}
+
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PerPage == 0 {
+		filter.PerPage = defaultPerPage
+	}
+	if filter.Page < 0 || filter.PerPage < 0 || filter.PerPage > maxPerPage {
+		return domain.PaymentFilter{}, domain.ErrBadParamInput
+	}
+	return filter, nil
+}
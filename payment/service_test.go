@@ -0,0 +1,131 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"testing"
+	"time"
+)
+
+type stubPaymentRepo struct {
+	payments []domain.Payment
+	err      error
+	from, to time.Time
+}
+
+func (s *stubPaymentRepo) Payments(_ context.Context, _ string, from, to time.Time) ([]domain.Payment, error) {
+	s.from, s.to = from, to
+	return append([]domain.Payment(nil), s.payments...), s.err
+}
+
+func TestNormalizeFilter(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return now.AddDate(0, 0, n) }
+
+	tests := []struct {
+		name    string
+		filter  domain.PaymentFilter
+		want    domain.PaymentFilter
+		wantErr error
+	}{
+		{"Defaults", domain.PaymentFilter{}, domain.PaymentFilter{From: now.Add(-defaultPeriod), To: now, Page: 1, PerPage: defaultPerPage}, nil},
+		{"From defaults relative to to", domain.PaymentFilter{To: day(-10)}, domain.PaymentFilter{From: day(-10).Add(-defaultPeriod), To: day(-10), Page: 1, PerPage: defaultPerPage}, nil},
+		{"Explicit range and page", domain.PaymentFilter{From: day(-30), To: day(-1), Page: 3, PerPage: 5}, domain.PaymentFilter{From: day(-30), To: day(-1), Page: 3, PerPage: 5}, nil},
+		{"Longest range", domain.PaymentFilter{From: now.Add(-maxPeriod), To: now}, domain.PaymentFilter{From: now.Add(-maxPeriod), To: now, Page: 1, PerPage: defaultPerPage}, nil},
+		{"Range too long", domain.PaymentFilter{From: now.Add(-maxPeriod - time.Second), To: now}, domain.PaymentFilter{}, domain.ErrBadParamInput},
+		{"From after to", domain.PaymentFilter{From: day(-1), To: day(-2)}, domain.PaymentFilter{}, domain.ErrBadParamInput},
+		{"Largest page", domain.PaymentFilter{PerPage: maxPerPage}, domain.PaymentFilter{From: now.Add(-defaultPeriod), To: now, Page: 1, PerPage: maxPerPage}, nil},
+		{"Page too large", domain.PaymentFilter{PerPage: maxPerPage + 1}, domain.PaymentFilter{}, domain.ErrBadParamInput},
+		{"Negative page", domain.PaymentFilter{Page: -1}, domain.PaymentFilter{}, domain.ErrBadParamInput},
+		{"Negative per page", domain.PaymentFilter{PerPage: -1}, domain.PaymentFilter{}, domain.ErrBadParamInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeFilter(tt.filter, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("normalizeFilter() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("normalizeFilter() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPayments(t *testing.T) {
+	base := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	// Out of order, as the billing API may return them.
+	var payments []domain.Payment
+	for _, n := range []int{2, 0, 4, 1, 3} {
+		payments = append(payments, domain.Payment{Amount: domain.RUB(int64(n+1) * 10000), Date: base.AddDate(0, 0, n)})
+	}
+	from, to := base.AddDate(0, 0, -1), base.AddDate(0, 0, 10)
+
+	tests := []struct {
+		name      string
+		filter    domain.PaymentFilter
+		wantDays  []int
+		wantTotal int
+	}{
+		{"Newest first", domain.PaymentFilter{From: from, To: to}, []int{4, 3, 2, 1, 0}, 5},
+		{"First page", domain.PaymentFilter{From: from, To: to, PerPage: 2}, []int{4, 3}, 5},
+		{"Last partial page", domain.PaymentFilter{From: from, To: to, Page: 3, PerPage: 2}, []int{0}, 5},
+		{"Past the end", domain.PaymentFilter{From: from, To: to, Page: 4, PerPage: 2}, []int{}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubPaymentRepo{payments: payments}
+			svc := NewService(repo, logging.Discard())
+			page, err := svc.Payments(context.Background(), "token", tt.filter)
+			if err != nil {
+				t.Fatalf("Payments() error = %v", err)
+			}
+			if !repo.from.Equal(from) || !repo.to.Equal(to) {
+				t.Errorf("range sent upstream = %v..%v, want %v..%v", repo.from, repo.to, from, to)
+			}
+			if page.Total != tt.wantTotal {
+				t.Errorf("total = %d, want %d", page.Total, tt.wantTotal)
+			}
+			if page.Items == nil {
+				t.Fatal("items = nil, want an empty page to encode as []")
+			}
+			if len(page.Items) != len(tt.wantDays) {
+				t.Fatalf("got %d items, want %d", len(page.Items), len(tt.wantDays))
+			}
+			for i, n := range tt.wantDays {
+				if want := base.AddDate(0, 0, n); !page.Items[i].Date.Equal(want) {
+					t.Errorf("item %d date = %v, want %v", i, page.Items[i].Date, want)
+				}
+			}
+		})
+	}
+}
+
+func TestPaymentsErrors(t *testing.T) {
+	upstream := errors.New("billing unavailable")
+	tests := []struct {
+		name    string
+		token   string
+		filter  domain.PaymentFilter
+		repoErr error
+		wantErr error
+	}{
+		{"Missing token", "", domain.PaymentFilter{}, nil, domain.ErrInvalidToken},
+		{"Forbidden characters in token", "tok'en", domain.PaymentFilter{}, nil, domain.ErrInvalidToken},
+		{"Bad filter", "token", domain.PaymentFilter{PerPage: maxPerPage + 1}, nil, domain.ErrBadParamInput},
+		{"Repository failure", "token", domain.PaymentFilter{}, upstream, upstream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := NewService(&stubPaymentRepo{err: tt.repoErr}, logging.Discard())
+			if _, err := svc.Payments(context.Background(), tt.token, tt.filter); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Payments() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package payment
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// maxTopUpAmount caps a single online top-up, in kopecks.
+const maxTopUpAmount = 100000_00
+
+// Provider is an online payment provider that takes the customer's money.
+type Provider interface {
+	// CreatePayment registers the intent with the provider and returns the
+	// provider's payment ID and the URL the customer confirms the payment at.
+	CreatePayment(ctx context.Context, intent domain.PaymentIntent, returnURL string) (providerID, confirmationURL string, err error)
+	// ParseWebhook verifies the webhook signature and decodes the event.
+	ParseWebhook(header http.Header, body []byte) (domain.ProviderEvent, error)
+}
+
+// RecurringProvider is a Provider that can save payment methods and charge
+// them later without the customer being present.
+type RecurringProvider interface {
+	Provider
+	// SavePaymentMethod exchanges a one-time token from the provider's
+	// client SDK for a reusable payment method.
+	SavePaymentMethod(ctx context.Context, paymentToken string) (domain.PaymentMethod, error)
+	// Charge charges a saved payment method. A pending status means the
+	// result will arrive through the webhook.
+	Charge(ctx context.Context, methodID string, intent domain.PaymentIntent) (providerID string, status domain.PaymentIntentStatus, err error)
+}
+
+// ProfileRepository resolves the contract and the amount due for a session.
+type ProfileRepository interface {
+	Profile(ctx context.Context, token string) (domain.Profile, error)
+}
+
+// BalanceRepository credits confirmed payments to the account upstream.
+type BalanceRepository interface {
+	CreditPayment(ctx context.Context, contractID string, amount domain.Money, transactionID string) error
+}
+
+// IntentStore keeps payment intents between creation and the provider webhook.
+type IntentStore interface {
+	// Create stores a new intent. It returns domain.ErrConflict when the
+	// contract already used the intent's idempotency key.
+	Create(ctx context.Context, intent domain.PaymentIntent) error
+	Update(ctx context.Context, intent domain.PaymentIntent) error
+	Get(ctx context.Context, id string) (domain.PaymentIntent, error)
+	GetByIdempotencyKey(ctx context.Context, contractID, key string) (domain.PaymentIntent, error)
+	// TransitionStatus moves the intent from one status to another and
+	// returns domain.ErrConflict if it is not in the expected status.
+	TransitionStatus(ctx context.Context, id string, from, to domain.PaymentIntentStatus) error
+}
+
+// ProfileInvalidator drops the cached profiles of a contract once a change
+// makes them stale.
+type ProfileInvalidator interface {
+	InvalidateContract(contractID string)
+}
+
+// TopUpService creates online top-ups and settles them from provider webhooks.
+type TopUpService struct {
+	provider    Provider
+	profileRepo ProfileRepository
+	balanceRepo BalanceRepository
+	intents     IntentStore
+	profiles    ProfileInvalidator
+	logger      *slog.Logger
+}
+
+// NewTopUpService creates a new TopUpService instance.
+func NewTopUpService(p Provider, pr ProfileRepository, b BalanceRepository, s IntentStore, i ProfileInvalidator, logger *slog.Logger) *TopUpService {
+	return &TopUpService{
+		provider:    p,
+		profileRepo: pr,
+		balanceRepo: b,
+		intents:     s,
+		profiles:    i,
+		logger:      logger,
+	}
+}
+
+// CreateTopUp creates a payment intent for the requested amount, or for
+// Profile.ToPay when no amount is given. Repeating the call with the same
+// idempotency key returns the intent created by the first call, or
+// domain.ErrConflict while that call is still talking to the provider.
+func (s *TopUpService) CreateTopUp(ctx context.Context, token, idempotencyKey string, topUp domain.TopUp) (domain.PaymentIntent, error) {
+	ctx, span := tracing.Start(ctx, "payment.CreateTopUp")
+	defer span.End()
+
+	if token == "" || middleware.ContainsForbiddenChars(token) {
+		return domain.PaymentIntent{}, domain.ErrInvalidToken
+	}
+	if middleware.ContainsForbiddenChars(idempotencyKey) || len(idempotencyKey) > 128 {
+		return domain.PaymentIntent{}, domain.ErrBadParamInput
+	}
+
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching profile for top-up", "error", err)
+		return domain.PaymentIntent{}, err
+	}
+
+	if idempotencyKey != "" {
+		existing, err := s.intents.GetByIdempotencyKey(ctx, profile.ID, idempotencyKey)
+		if err == nil {
+			return s.repeatTopUp(ctx, existing, topUp.ReturnURL)
+		}
+		if !errors.Is(err, domain.ErrNotFound) {
+			return domain.PaymentIntent{}, err
+		}
+	}
+
+	amount := topUp.Amount
+	if amount.Minor == 0 && profile.ToPay != nil {
+		amount = *profile.ToPay
+	}
+	if !validAmount(amount) {
+		return domain.PaymentIntent{}, domain.ErrBadParamInput
+	}
+
+	intent := domain.PaymentIntent{
+		ID:             newID(),
+		ContractID:     profile.ID,
+		Amount:         amount,
+		Status:         domain.PaymentIntentPending,
+		IdempotencyKey: idempotencyKey,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.intents.Create(ctx, intent); err != nil {
+		if errors.Is(err, domain.ErrConflict) && idempotencyKey != "" {
+			// A concurrent request with the same key won the race.
+			existing, err := s.intents.GetByIdempotencyKey(ctx, profile.ID, idempotencyKey)
+			if err != nil {
+				return domain.PaymentIntent{}, err
+			}
+			return s.repeatTopUp(ctx, existing, topUp.ReturnURL)
+		}
+		return domain.PaymentIntent{}, err
+	}
+	return s.startPayment(ctx, intent, topUp.ReturnURL)
+}
+
+// repeatTopUp answers a top-up repeated with the idempotency key of
+// existing. An intent the provider hasn't accepted yet has no confirmation
+// URL to return, and one it canceled is claimed to start a new payment.
+func (s *TopUpService) repeatTopUp(ctx context.Context, existing domain.PaymentIntent, returnURL string) (domain.PaymentIntent, error) {
+	switch {
+	case existing.Status == domain.PaymentIntentCanceled:
+		err := s.intents.TransitionStatus(ctx, existing.ID, domain.PaymentIntentCanceled, domain.PaymentIntentPending)
+		if errors.Is(err, domain.ErrConflict) {
+			// A concurrent retry claimed it first.
+			return domain.PaymentIntent{}, domain.ErrConflict
+		}
+		if err != nil {
+			return domain.PaymentIntent{}, err
+		}
+		existing.Status = domain.PaymentIntentPending
+		existing.ProviderID, existing.ConfirmationURL = "", ""
+		return s.startPayment(ctx, existing, returnURL)
+	case existing.Status == domain.PaymentIntentPending && existing.ProviderID == "":
+		return domain.PaymentIntent{}, domain.ErrConflict
+	}
+	return existing, nil
+}
+
+// startPayment creates the provider payment for a stored pending intent
+// and saves where the user confirms it.
+func (s *TopUpService) startPayment(ctx context.Context, intent domain.PaymentIntent, returnURL string) (domain.PaymentIntent, error) {
+	providerID, confirmationURL, err := s.provider.CreatePayment(ctx, intent, returnURL)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Provider failed to create payment", "intent_id", intent.ID, "error", err)
+		intent.Status = domain.PaymentIntentCanceled
+		if updateErr := s.intents.Update(ctx, intent); updateErr != nil {
+			s.logger.ErrorContext(ctx, "Failed to cancel payment intent", "intent_id", intent.ID, "error", updateErr)
+		}
+		return domain.PaymentIntent{}, domain.ErrInternalServerError
+	}
+	intent.ProviderID = providerID
+	intent.ConfirmationURL = confirmationURL
+	if err := s.intents.Update(ctx, intent); err != nil {
+		return domain.PaymentIntent{}, err
+	}
+
+	s.logger.InfoContext(ctx, "Created payment intent", "intent_id", intent.ID, "contract_id", intent.ContractID)
+	return intent, nil
+}
+
+// TopUp returns a payment intent owned by the session's contract.
+func (s *TopUpService) TopUp(ctx context.Context, token, id string) (domain.PaymentIntent, error) {
+	ctx, span := tracing.Start(ctx, "payment.TopUp")
+	defer span.End()
+
+	if token == "" || middleware.ContainsForbiddenChars(token) {
+		return domain.PaymentIntent{}, domain.ErrInvalidToken
+	}
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		return domain.PaymentIntent{}, err
+	}
+	intent, err := s.intents.Get(ctx, id)
+	if err != nil {
+		return domain.PaymentIntent{}, err
+	}
+	if intent.ContractID != profile.ID {
+		return domain.PaymentIntent{}, domain.ErrNotFound
+	}
+	return intent, nil
+}
+
+// HandleWebhook verifies a provider webhook and credits a succeeded payment
+// upstream exactly once. Redelivered webhooks are acknowledged without effect.
+func (s *TopUpService) HandleWebhook(ctx context.Context, header http.Header, body []byte) error {
+	ctx, span := tracing.Start(ctx, "payment.HandleWebhook")
+	defer span.End()
+
+	event, err := s.provider.ParseWebhook(header, body)
+	if err != nil {
+		s.logger.WarnContext(ctx, "Rejected payment webhook", "error", err)
+		return domain.ErrForbidden
+	}
+
+	intent, err := s.intents.Get(ctx, event.IntentID)
+	if err != nil {
+		return err
+	}
+	if intent.ProviderID != event.ProviderID {
+		s.logger.WarnContext(ctx, "Webhook provider ID mismatch", "intent_id", intent.ID)
+		return domain.ErrBadParamInput
+	}
+
+	switch event.Status {
+	case domain.PaymentIntentSucceeded:
+		if event.Amount != intent.Amount {
+			s.logger.WarnContext(ctx, "Webhook amount mismatch", "intent_id", intent.ID, "amount", event.Amount.String(), "expected", intent.Amount.String())
+			return domain.ErrBadParamInput
+		}
+		return s.credit(ctx, intent)
+	case domain.PaymentIntentCanceled:
+		err := s.intents.TransitionStatus(ctx, intent.ID, domain.PaymentIntentPending, domain.PaymentIntentCanceled)
+		if errors.Is(err, domain.ErrConflict) {
+			return nil
+		}
+		return err
+	default:
+		// Intermediate provider states need no action.
+		return nil
+	}
+}
+
+// SavePaymentMethod saves a tokenised payment method for recurring charges.
+func (s *TopUpService) SavePaymentMethod(ctx context.Context, paymentToken string) (domain.PaymentMethod, error) {
+	ctx, span := tracing.Start(ctx, "payment.SavePaymentMethod")
+	defer span.End()
+
+	recurring, ok := s.provider.(RecurringProvider)
+	if !ok {
+		return domain.PaymentMethod{}, domain.ErrForbidden
+	}
+	if paymentToken == "" || middleware.ContainsForbiddenChars(paymentToken) {
+		return domain.PaymentMethod{}, domain.ErrBadParamInput
+	}
+	method, err := recurring.SavePaymentMethod(ctx, paymentToken)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Provider failed to save payment method", "error", err)
+		return domain.PaymentMethod{}, domain.ErrPaymentDeclined
+	}
+	return method, nil
+}
+
+// Charge charges a saved payment method and credits the contract once the
+// provider confirms the payment. Retrying with the same idempotency key
+// returns the intent of the first attempt, or charges it again if the
+// provider declined it. Once the provider has accepted the payment, Charge
+// returns the pending intent rather than an error even if crediting fails:
+// the webhook credits it later, and charging again would take the money twice.
+func (s *TopUpService) Charge(ctx context.Context, contractID, methodID string, amount domain.Money, idempotencyKey string) (domain.PaymentIntent, error) {
+	ctx, span := tracing.Start(ctx, "payment.Charge")
+	defer span.End()
+
+	recurring, ok := s.provider.(RecurringProvider)
+	if !ok {
+		return domain.PaymentIntent{}, domain.ErrForbidden
+	}
+	if !validAmount(amount) {
+		return domain.PaymentIntent{}, domain.ErrBadParamInput
+	}
+
+	intent := domain.PaymentIntent{
+		ID:             newID(),
+		ContractID:     contractID,
+		Amount:         amount,
+		Status:         domain.PaymentIntentPending,
+		IdempotencyKey: idempotencyKey,
+		CreatedAt:      time.Now(),
+	}
+	if err := s.intents.Create(ctx, intent); err != nil {
+		if !errors.Is(err, domain.ErrConflict) {
+			return domain.PaymentIntent{}, err
+		}
+		existing, err := s.intents.GetByIdempotencyKey(ctx, contractID, idempotencyKey)
+		if err != nil || existing.Status != domain.PaymentIntentCanceled {
+			return existing, err
+		}
+		// The provider declined the previous attempt; claim the intent to
+		// charge it again.
+		err = s.intents.TransitionStatus(ctx, existing.ID, domain.PaymentIntentCanceled, domain.PaymentIntentPending)
+		if errors.Is(err, domain.ErrConflict) {
+			// A concurrent retry claimed it first.
+			return s.intents.Get(ctx, existing.ID)
+		}
+		if err != nil {
+			return domain.PaymentIntent{}, err
+		}
+		intent = existing
+		intent.Status = domain.PaymentIntentPending
+	}
+
+	providerID, status, err := recurring.Charge(ctx, methodID, intent)
+	if err != nil || status == domain.PaymentIntentCanceled {
+		s.logger.WarnContext(ctx, "Provider declined charge", "intent_id", intent.ID, "error", err)
+		intent.Status = domain.PaymentIntentCanceled
+		if updateErr := s.intents.Update(ctx, intent); updateErr != nil {
+			s.logger.ErrorContext(ctx, "Failed to cancel payment intent", "intent_id", intent.ID, "error", updateErr)
+		}
+		return intent, domain.ErrPaymentDeclined
+	}
+	intent.ProviderID = providerID
+	if err := s.intents.Update(ctx, intent); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to store accepted charge", "intent_id", intent.ID, "error", err)
+		return intent, nil
+	}
+
+	if status == domain.PaymentIntentSucceeded {
+		if err := s.credit(ctx, intent); err != nil {
+			// The intent stays pending for the webhook to credit.
+			s.logger.ErrorContext(ctx, "Failed to credit accepted charge", "intent_id", intent.ID, "error", err)
+			return intent, nil
+		}
+		intent.Status = domain.PaymentIntentSucceeded
+	}
+	return intent, nil
+}
+
+// credit claims the intent, credits the balance upstream and marks it succeeded.
+func (s *TopUpService) credit(ctx context.Context, intent domain.PaymentIntent) error {
+	err := s.intents.TransitionStatus(ctx, intent.ID, domain.PaymentIntentPending, domain.PaymentIntentProcessing)
+	if errors.Is(err, domain.ErrConflict) {
+		// Already credited or being credited by a concurrent delivery.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.balanceRepo.CreditPayment(ctx, intent.ContractID, intent.Amount, intent.ID); err != nil {
+		s.logger.ErrorContext(ctx, "Failed to credit payment intent", "intent_id", intent.ID, "error", err)
+		// Release the claim so the provider's retry can credit it again.
+		if revertErr := s.intents.TransitionStatus(ctx, intent.ID, domain.PaymentIntentProcessing, domain.PaymentIntentPending); revertErr != nil {
+			s.logger.ErrorContext(ctx, "Failed to release payment intent", "intent_id", intent.ID, "error", revertErr)
+		}
+		return domain.ErrInternalServerError
+	}
+
+	s.logger.InfoContext(ctx, "Credited payment intent", "intent_id", intent.ID, "contract_id", intent.ContractID)
+	s.profiles.InvalidateContract(intent.ContractID)
+	return s.intents.TransitionStatus(ctx, intent.ID, domain.PaymentIntentProcessing, domain.PaymentIntentSucceeded)
+}
+
+// validAmount reports whether amount can be charged online.
+func validAmount(amount domain.Money) bool {
+	return amount.Currency == domain.CurrencyRUB && amount.Minor > 0 && amount.Minor <= maxTopUpAmount
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
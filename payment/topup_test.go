@@ -0,0 +1,297 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/repository/provider"
+	"github.com/llchhh/spektr-account-api/internal/storage/memory"
+	"testing"
+)
+
+type stubProfileRepo struct {
+	profile domain.Profile
+}
+
+func (s stubProfileRepo) Profile(context.Context, string) (domain.Profile, error) {
+	return s.profile, nil
+}
+
+type stubBalanceRepo struct {
+	credits []string
+	err     error
+}
+
+func (s *stubBalanceRepo) CreditPayment(_ context.Context, _ string, _ domain.Money, transactionID string) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.credits = append(s.credits, transactionID)
+	return nil
+}
+
+type stubInvalidator struct {
+	contracts []string
+}
+
+func (s *stubInvalidator) InvalidateContract(contractID string) {
+	s.contracts = append(s.contracts, contractID)
+}
+
+func newTestTopUpService(balance *stubBalanceRepo) (*TopUpService, *provider.FakeProvider) {
+	fake := provider.NewFakeProvider("http://pay.local", "secret")
+	toPay := domain.RUB(65000)
+	profiles := stubProfileRepo{profile: domain.Profile{ID: "S540100440", ToPay: &toPay}}
+	return NewTopUpService(fake, profiles, balance, memory.New().PaymentIntents(), &stubInvalidator{}, logging.Discard()), fake
+}
+
+func TestCreateTopUp(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     domain.Money
+		wantAmount domain.Money
+		wantErr    error
+	}{
+		{"Explicit amount", domain.RUB(30000), domain.RUB(30000), nil},
+		{"Defaults to amount due", domain.Money{}, domain.RUB(65000), nil},
+		{"Negative amount", domain.RUB(-1000), domain.Money{}, domain.ErrBadParamInput},
+		{"Amount over limit", domain.RUB(maxTopUpAmount + 1), domain.Money{}, domain.ErrBadParamInput},
+		{"Other currency", domain.Money{Minor: 30000, Currency: "USD"}, domain.Money{}, domain.ErrBadParamInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _ := newTestTopUpService(&stubBalanceRepo{})
+			intent, err := svc.CreateTopUp(context.Background(), "token", "", domain.TopUp{Amount: tt.amount})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateTopUp() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && intent.Amount != tt.wantAmount {
+				t.Errorf("CreateTopUp() amount = %v, want %v", intent.Amount, tt.wantAmount)
+			}
+		})
+	}
+}
+
+func TestCreateTopUpIdempotency(t *testing.T) {
+	svc, _ := newTestTopUpService(&stubBalanceRepo{})
+	ctx := context.Background()
+
+	first, err := svc.CreateTopUp(ctx, "token", "key-1", domain.TopUp{Amount: domain.RUB(10000)})
+	if err != nil {
+		t.Fatalf("CreateTopUp() error = %v", err)
+	}
+	again, err := svc.CreateTopUp(ctx, "token", "key-1", domain.TopUp{Amount: domain.RUB(10000)})
+	if err != nil {
+		t.Fatalf("CreateTopUp() retry error = %v", err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("retry created intent %s, want %s", again.ID, first.ID)
+	}
+	other, err := svc.CreateTopUp(ctx, "token", "key-2", domain.TopUp{Amount: domain.RUB(10000)})
+	if err != nil {
+		t.Fatalf("CreateTopUp() other key error = %v", err)
+	}
+	if other.ID == first.ID {
+		t.Errorf("different key reused intent %s", first.ID)
+	}
+}
+
+// flakyProvider fails to create payments while fail is set.
+type flakyProvider struct {
+	*provider.FakeProvider
+	fail bool
+}
+
+func (p *flakyProvider) CreatePayment(ctx context.Context, intent domain.PaymentIntent, returnURL string) (string, string, error) {
+	if p.fail {
+		return "", "", errors.New("provider unavailable")
+	}
+	return p.FakeProvider.CreatePayment(ctx, intent, returnURL)
+}
+
+func TestCreateTopUpRetryAfterCancel(t *testing.T) {
+	fake := &flakyProvider{FakeProvider: provider.NewFakeProvider("http://pay.local", "secret"), fail: true}
+	profiles := stubProfileRepo{profile: domain.Profile{ID: "S540100440"}}
+	svc := NewTopUpService(fake, profiles, &stubBalanceRepo{}, memory.New().PaymentIntents(), &stubInvalidator{}, logging.Discard())
+	ctx := context.Background()
+
+	if _, err := svc.CreateTopUp(ctx, "token", "key-1", domain.TopUp{Amount: domain.RUB(10000)}); err == nil {
+		t.Fatal("CreateTopUp() succeeded with the provider down")
+	}
+	fake.fail = false
+	// The retry keeps the key and starts a new payment for the same intent.
+	retry, err := svc.CreateTopUp(ctx, "token", "key-1", domain.TopUp{Amount: domain.RUB(10000)})
+	if err != nil {
+		t.Fatalf("retry error = %v", err)
+	}
+	if retry.Status != domain.PaymentIntentPending || retry.ConfirmationURL == "" {
+		t.Errorf("retry = %+v, want a pending intent with a confirmation URL", retry)
+	}
+	if stored, _ := svc.intents.GetByIdempotencyKey(ctx, "S540100440", "key-1"); stored.ID != retry.ID || stored.ProviderID != retry.ProviderID {
+		t.Errorf("stored = %+v, want %+v", stored, retry)
+	}
+}
+
+func TestCreateTopUpInProgress(t *testing.T) {
+	svc, _ := newTestTopUpService(&stubBalanceRepo{})
+	ctx := context.Background()
+
+	// Another request created the intent and is waiting for the provider.
+	if err := svc.intents.Create(ctx, domain.PaymentIntent{
+		ID: "i1", ContractID: "S540100440", Amount: domain.RUB(10000),
+		Status: domain.PaymentIntentPending, IdempotencyKey: "key-1",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateTopUp(ctx, "token", "key-1", domain.TopUp{Amount: domain.RUB(10000)}); !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("CreateTopUp() error = %v, want %v", err, domain.ErrConflict)
+	}
+}
+
+func TestHandleWebhook(t *testing.T) {
+	balance := &stubBalanceRepo{}
+	svc, fake := newTestTopUpService(balance)
+	ctx := context.Background()
+
+	intent, err := svc.CreateTopUp(ctx, "token", "", domain.TopUp{Amount: domain.RUB(50000)})
+	if err != nil {
+		t.Fatalf("CreateTopUp() error = %v", err)
+	}
+	stored, _ := svc.intents.Get(ctx, intent.ID)
+
+	header, body := fake.Webhook(stored.ProviderID, stored, domain.PaymentIntentSucceeded)
+	for i := 0; i < 2; i++ {
+		if err := svc.HandleWebhook(ctx, header, body); err != nil {
+			t.Fatalf("HandleWebhook() delivery %d error = %v", i+1, err)
+		}
+	}
+	if len(balance.credits) != 1 {
+		t.Errorf("credited %d times, want exactly once", len(balance.credits))
+	}
+
+	got, _ := svc.TopUp(ctx, "token", intent.ID)
+	if got.Status != domain.PaymentIntentSucceeded {
+		t.Errorf("status = %s, want %s", got.Status, domain.PaymentIntentSucceeded)
+	}
+
+	header.Set(provider.SignatureHeader, "00")
+	if err := svc.HandleWebhook(ctx, header, body); !errors.Is(err, domain.ErrForbidden) {
+		t.Errorf("HandleWebhook() with bad signature error = %v, want %v", err, domain.ErrForbidden)
+	}
+}
+
+func TestHandleWebhookCreditFailure(t *testing.T) {
+	balance := &stubBalanceRepo{err: errors.New("billing unavailable")}
+	svc, fake := newTestTopUpService(balance)
+	ctx := context.Background()
+
+	intent, _ := svc.CreateTopUp(ctx, "token", "", domain.TopUp{Amount: domain.RUB(50000)})
+	stored, _ := svc.intents.Get(ctx, intent.ID)
+	header, body := fake.Webhook(stored.ProviderID, stored, domain.PaymentIntentSucceeded)
+
+	if err := svc.HandleWebhook(ctx, header, body); err == nil {
+		t.Fatal("HandleWebhook() error = nil, want failure so the provider retries")
+	}
+
+	// The retry must be able to credit the payment once billing recovers.
+	balance.err = nil
+	if err := svc.HandleWebhook(ctx, header, body); err != nil {
+		t.Fatalf("HandleWebhook() retry error = %v", err)
+	}
+	if len(balance.credits) != 1 {
+		t.Errorf("credited %d times, want exactly once", len(balance.credits))
+	}
+}
+
+func TestHandleWebhookAmountMismatch(t *testing.T) {
+	balance := &stubBalanceRepo{}
+	svc, fake := newTestTopUpService(balance)
+	ctx := context.Background()
+
+	intent, _ := svc.CreateTopUp(ctx, "token", "", domain.TopUp{Amount: domain.RUB(50000)})
+	stored, _ := svc.intents.Get(ctx, intent.ID)
+	// A kopeck short of the intent.
+	stored.Amount = domain.RUB(49999)
+	header, body := fake.Webhook(stored.ProviderID, stored, domain.PaymentIntentSucceeded)
+
+	if err := svc.HandleWebhook(ctx, header, body); !errors.Is(err, domain.ErrBadParamInput) {
+		t.Errorf("HandleWebhook() error = %v, want %v", err, domain.ErrBadParamInput)
+	}
+	if len(balance.credits) != 0 {
+		t.Errorf("credited %d times, want none", len(balance.credits))
+	}
+}
+
+// countingProvider counts the charges that reach the provider.
+type countingProvider struct {
+	*provider.FakeProvider
+	charges int
+}
+
+func (p *countingProvider) Charge(ctx context.Context, methodID string, intent domain.PaymentIntent) (string, domain.PaymentIntentStatus, error) {
+	p.charges++
+	return p.FakeProvider.Charge(ctx, methodID, intent)
+}
+
+func TestChargeCreditFailure(t *testing.T) {
+	fake := &countingProvider{FakeProvider: provider.NewFakeProvider("http://pay.local", "secret")}
+	balance := &stubBalanceRepo{err: errors.New("billing unavailable")}
+	svc := NewTopUpService(fake, stubProfileRepo{}, balance, memory.New().PaymentIntents(), &stubInvalidator{}, logging.Discard())
+	ctx := context.Background()
+	method, _ := fake.SavePaymentMethod(ctx, "tok")
+
+	// The provider took the money, so the charge must not look failed.
+	intent, err := svc.Charge(ctx, "S540100440", method.ID, domain.RUB(50000), "autopay:S540100440:2024-06-01")
+	if err != nil {
+		t.Fatalf("Charge() error = %v, want the pending intent", err)
+	}
+	if intent.Status != domain.PaymentIntentPending {
+		t.Errorf("status = %s, want %s", intent.Status, domain.PaymentIntentPending)
+	}
+
+	again, err := svc.Charge(ctx, "S540100440", method.ID, domain.RUB(50000), "autopay:S540100440:2024-06-01")
+	if err != nil || again.ID != intent.ID {
+		t.Errorf("retry = %s, %v, want intent %s", again.ID, err, intent.ID)
+	}
+	if fake.charges != 1 {
+		t.Errorf("provider charged %d times, want once", fake.charges)
+	}
+
+	// The webhook credits it once billing recovers.
+	balance.err = nil
+	stored, _ := svc.intents.Get(ctx, intent.ID)
+	header, body := fake.Webhook(stored.ProviderID, stored, domain.PaymentIntentSucceeded)
+	if err := svc.HandleWebhook(ctx, header, body); err != nil {
+		t.Fatalf("HandleWebhook() error = %v", err)
+	}
+	if len(balance.credits) != 1 {
+		t.Errorf("credited %d times, want exactly once", len(balance.credits))
+	}
+}
+
+func TestChargeRetryAfterDecline(t *testing.T) {
+	fake := &countingProvider{FakeProvider: provider.NewFakeProvider("http://pay.local", "secret")}
+	balance := &stubBalanceRepo{}
+	svc := NewTopUpService(fake, stubProfileRepo{}, balance, memory.New().PaymentIntents(), &stubInvalidator{}, logging.Discard())
+	ctx := context.Background()
+	declined, _ := fake.SavePaymentMethod(ctx, provider.DeclinedToken)
+	method, _ := fake.SavePaymentMethod(ctx, "tok")
+
+	first, err := svc.Charge(ctx, "S540100440", declined.ID, domain.RUB(50000), "autopay:S540100440:2024-06-01")
+	if !errors.Is(err, domain.ErrPaymentDeclined) {
+		t.Fatalf("Charge() error = %v, want %v", err, domain.ErrPaymentDeclined)
+	}
+	// The retry keeps the key and charges the same intent again.
+	retry, err := svc.Charge(ctx, "S540100440", method.ID, domain.RUB(50000), "autopay:S540100440:2024-06-01")
+	if err != nil {
+		t.Fatalf("retry error = %v", err)
+	}
+	if retry.ID != first.ID || retry.Status != domain.PaymentIntentSucceeded {
+		t.Errorf("retry = %+v, want intent %s succeeded", retry, first.ID)
+	}
+	if fake.charges != 2 || len(balance.credits) != 1 {
+		t.Errorf("%d charges and %d credits, want 2 and 1", fake.charges, len(balance.credits))
+	}
+}
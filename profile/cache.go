@@ -0,0 +1,216 @@
+package profile
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
+	"golang.org/x/sync/singleflight"
+	"maps"
+	"sync"
+	"time"
+)
+
+// sweepThreshold is the number of cached profiles above which expired
+// entries are dropped on the next write.
+const sweepThreshold = 1000
+
+// Cache keeps profiles per session for a short TTL and coalesces concurrent
+// fetches of the same profile into one billing call. Services that change
+// what the profile shows invalidate it through Invalidate or
+// InvalidateContract.
+type Cache struct {
+	ttl   time.Duration
+	now   func() time.Time
+	group singleflight.Group
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	// contracts maps a contract ID to the session keys caching its profile.
+	contracts map[string]map[string]struct{}
+	// seq orders fetches and invalidations. A fetch that started at seq
+	// doesn't put its profile back if its session or its contract was
+	// invalidated since.
+	seq                  uint64
+	invalidatedSessions  map[string]uint64
+	invalidatedContracts map[string]uint64
+	// inflight counts the fetches in progress by the seq they started at.
+	// Invalidations older than all of them can't matter and are dropped.
+	inflight map[uint64]int
+}
+
+type cacheEntry struct {
+	profile domain.Profile
+	expires time.Time
+}
+
+// NewCache creates a Cache keeping profiles for ttl. A zero ttl disables
+// caching but still coalesces concurrent fetches.
+func NewCache(ttl time.Duration) *Cache {
+	return &Cache{
+		ttl:                  ttl,
+		now:                  time.Now,
+		entries:              make(map[string]cacheEntry),
+		contracts:            make(map[string]map[string]struct{}),
+		invalidatedSessions:  make(map[string]uint64),
+		invalidatedContracts: make(map[string]uint64),
+		inflight:             make(map[uint64]int),
+	}
+}
+
+// TTL is how long a profile is served from the cache.
+func (c *Cache) TTL() time.Duration {
+	return c.ttl
+}
+
+// Invalidate drops the profile cached for the session and its selected contract.
+func (c *Cache) Invalidate(ctx context.Context, token string) {
+	c.invalidate(sessionKey(ctx, token))
+}
+
+// InvalidateContract drops the profile of a contract from every session
+// that cached it, e.g. when a payment arrives through a provider webhook.
+func (c *Cache) InvalidateContract(contractID string) {
+	c.mu.Lock()
+	keys := c.contracts[contractID]
+	delete(c.contracts, contractID)
+	if len(c.inflight) > 0 {
+		// A session whose fetch is in flight isn't indexed under the
+		// contract yet.
+		c.seq++
+		c.invalidatedContracts[contractID] = c.seq
+	}
+	c.mu.Unlock()
+
+	for key := range keys {
+		c.invalidate(key)
+	}
+}
+
+// load returns the cached profile or calls fetch once for all concurrent
+// callers with the same key. The fetch outlives the caller that started it,
+// so its cancellation doesn't fail the others; each caller still stops
+// waiting when its own ctx is done.
+func (c *Cache) load(ctx context.Context, key string, fetch func(context.Context) (domain.Profile, error)) (domain.Profile, error) {
+	profile, ok := c.get(key)
+	metrics.CacheLookup("profile", ok)
+	if ok {
+		return profile, nil
+	}
+
+	fetchCtx := context.WithoutCancel(ctx)
+	ch := c.group.DoChan(key, func() (interface{}, error) {
+		start := c.startFetch()
+		profile, err := fetch(fetchCtx)
+		c.finishFetch(key, start, profile, err)
+		return profile, err
+	})
+	select {
+	case res := <-ch:
+		return res.Val.(domain.Profile), res.Err
+	case <-ctx.Done():
+		return domain.Profile{}, ctx.Err()
+	}
+}
+
+func (c *Cache) get(key string) (domain.Profile, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !c.now().Before(entry.expires) {
+		return domain.Profile{}, false
+	}
+	return entry.profile, true
+}
+
+func (c *Cache) startFetch() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inflight[c.seq]++
+	return c.seq
+}
+
+// finishFetch caches the profile a fetch that started at start returned,
+// unless it has been invalidated since.
+func (c *Cache) finishFetch(key string, start uint64, profile domain.Profile, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stale := c.invalidatedSessions[key] > start || c.invalidatedContracts[profile.ID] > start
+	if c.inflight[start]--; c.inflight[start] == 0 {
+		delete(c.inflight, start)
+	}
+	c.dropInvalidations()
+	if err != nil || stale || c.ttl <= 0 {
+		return
+	}
+
+	now := c.now()
+	if len(c.entries) >= sweepThreshold {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				c.forgetContract(k)
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[key] = cacheEntry{profile: profile, expires: now.Add(c.ttl)}
+	if c.contracts[profile.ID] == nil {
+		c.contracts[profile.ID] = make(map[string]struct{})
+	}
+	c.contracts[profile.ID][key] = struct{}{}
+}
+
+// dropInvalidations forgets the invalidations no fetch in flight started
+// before. c.mu must be held.
+func (c *Cache) dropInvalidations() {
+	if len(c.inflight) == 0 {
+		clear(c.invalidatedSessions)
+		clear(c.invalidatedContracts)
+		return
+	}
+	oldest := c.seq
+	for start := range c.inflight {
+		oldest = min(oldest, start)
+	}
+	for _, invalidated := range []map[string]uint64{c.invalidatedSessions, c.invalidatedContracts} {
+		maps.DeleteFunc(invalidated, func(_ string, seq uint64) bool { return seq <= oldest })
+	}
+}
+
+func (c *Cache) invalidate(key string) {
+	c.mu.Lock()
+	c.forgetContract(key)
+	delete(c.entries, key)
+	if len(c.inflight) > 0 {
+		c.seq++
+		c.invalidatedSessions[key] = c.seq
+	}
+	c.mu.Unlock()
+
+	// Callers arriving after the invalidation must not join a fetch that
+	// started before it.
+	c.group.Forget(key)
+}
+
+// forgetContract removes key from the contract index. c.mu must be held.
+func (c *Cache) forgetContract(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.contracts[entry.profile.ID], key)
+	if len(c.contracts[entry.profile.ID]) == 0 {
+		delete(c.contracts, entry.profile.ID)
+	}
+}
+
+// sessionKey identifies the session and the contract it is scoped to.
+func sessionKey(ctx context.Context, token string) string {
+	key := token
+	if principal, ok := domain.PrincipalFromContext(ctx); ok && principal.ContractID != "" {
+		key += "|" + principal.ContractID
+	}
+	return key
+}
@@ -0,0 +1,177 @@
+package profile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/llchhh/spektr-account-api/domain"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheTTL(t *testing.T) {
+	c := NewCache(30 * time.Second)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	var fetches int
+	fetch := func(context.Context) (domain.Profile, error) {
+		fetches++
+		return domain.Profile{ID: "1"}, nil
+	}
+
+	c.load(context.Background(), "a", fetch)
+	now = now.Add(29 * time.Second)
+	c.load(context.Background(), "a", fetch)
+	if fetches != 1 {
+		t.Errorf("fetched %d times within the TTL, want 1", fetches)
+	}
+	now = now.Add(time.Second)
+	c.load(context.Background(), "a", fetch)
+	if fetches != 2 {
+		t.Errorf("fetched %d times after the TTL, want 2", fetches)
+	}
+}
+
+func TestCacheCoalescesConcurrentLoads(t *testing.T) {
+	c := NewCache(time.Minute)
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	fetch := func(context.Context) (domain.Profile, error) {
+		fetches.Add(1)
+		<-release
+		return domain.Profile{ID: "1"}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.load(context.Background(), "a", fetch)
+		}()
+	}
+	// Give the goroutines time to join the in-flight fetch.
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetched %d times, want 1", n)
+	}
+}
+
+func TestCacheFetchOutlivesCancelledCaller(t *testing.T) {
+	c := NewCache(time.Minute)
+	started, release := make(chan struct{}), make(chan struct{})
+	fetch := func(ctx context.Context) (domain.Profile, error) {
+		close(started)
+		<-release
+		if err := ctx.Err(); err != nil {
+			return domain.Profile{}, err
+		}
+		return domain.Profile{ID: "1"}, nil
+	}
+
+	first, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := c.load(first, "a", fetch)
+		firstErr <- err
+	}()
+	<-started
+
+	second := make(chan error)
+	go func() {
+		p, err := c.load(context.Background(), "a", fetch)
+		if err == nil && p.ID != "1" {
+			err = fmt.Errorf("profile ID = %q", p.ID)
+		}
+		second <- err
+	}()
+	// Give the second caller time to join the in-flight fetch.
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller: err = %v, want context.Canceled", err)
+	}
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("waiting caller: %v", err)
+	}
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	c := NewCache(time.Minute)
+	ctx := domain.ContextWithPrincipal(context.Background(), domain.Principal{Token: "token", ContractID: "7"})
+	email := "old@example.com"
+	fetch := func(context.Context) (domain.Profile, error) {
+		return domain.Profile{ID: "S1", Email: email}, nil
+	}
+
+	c.load(context.Background(), sessionKey(ctx, "token"), fetch)
+	c.load(context.Background(), "other-session", fetch)
+	email = "new@example.com"
+
+	c.Invalidate(ctx, "token")
+	if p, _ := c.load(context.Background(), sessionKey(ctx, "token"), fetch); p.Email != email {
+		t.Errorf("after Invalidate email = %q, want %q", p.Email, email)
+	}
+
+	c.InvalidateContract("S1")
+	if p, _ := c.load(context.Background(), "other-session", fetch); p.Email != email {
+		t.Errorf("after InvalidateContract email = %q, want %q", p.Email, email)
+	}
+}
+
+func TestCacheDropsFetchStartedBeforeInvalidation(t *testing.T) {
+	c := NewCache(time.Minute)
+	c.load(context.Background(), "a", func(context.Context) (domain.Profile, error) {
+		// The profile changes while the old one is being fetched.
+		c.invalidate("a")
+		return domain.Profile{Email: "stale@example.com"}, nil
+	})
+
+	p, _ := c.load(context.Background(), "a", func(context.Context) (domain.Profile, error) {
+		return domain.Profile{Email: "fresh@example.com"}, nil
+	})
+	if p.Email != "fresh@example.com" {
+		t.Errorf("email = %q, a stale fetch was cached", p.Email)
+	}
+}
+
+func TestCacheDropsFetchStartedBeforeContractInvalidation(t *testing.T) {
+	c := NewCache(time.Minute)
+	c.load(context.Background(), "a", func(context.Context) (domain.Profile, error) {
+		// A payment arrives for the contract before the session indexed it.
+		c.InvalidateContract("c1")
+		return domain.Profile{ID: "c1", Email: "stale@example.com"}, nil
+	})
+
+	p, _ := c.load(context.Background(), "a", func(context.Context) (domain.Profile, error) {
+		return domain.Profile{ID: "c1", Email: "fresh@example.com"}, nil
+	})
+	if p.Email != "fresh@example.com" {
+		t.Errorf("email = %q, a stale fetch was cached", p.Email)
+	}
+}
+
+func TestCacheForgetsInvalidations(t *testing.T) {
+	c := NewCache(time.Minute)
+	for _, key := range []string{"a", "b", "c"} {
+		c.load(context.Background(), key, func(context.Context) (domain.Profile, error) {
+			c.invalidate(key)
+			c.InvalidateContract("c-" + key)
+			return domain.Profile{ID: "c-" + key}, nil
+		})
+	}
+	c.Invalidate(context.Background(), "d")
+	c.InvalidateContract("c-d")
+
+	if n := len(c.invalidatedSessions) + len(c.invalidatedContracts) + len(c.inflight); n != 0 {
+		t.Errorf("%d invalidations kept with no fetch in flight", n)
+	}
+}
@@ -0,0 +1,205 @@
+package profile
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/i18n"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"time"
+)
+
+const (
+	// promisedPaymentDays is how long a promised payment keeps the service on.
+	promisedPaymentDays = 3
+	// At most promisedPaymentsPerPeriod promised payments may be taken
+	// within any promisedPaymentPeriod.
+	promisedPaymentsPerPeriod = 2
+	promisedPaymentPeriod     = 30 * 24 * time.Hour
+	// promisedPaymentCooldown is the minimum gap between the deadline of a
+	// promised payment and the next one.
+	promisedPaymentCooldown = 7 * 24 * time.Hour
+)
+
+// Reasons a promised payment is not offered.
+const (
+	reasonNotInDebt      = "not_in_debt"
+	reasonAlreadyActive  = "already_active"
+	reasonLimitReached   = "limit_reached"
+	reasonCooldown       = "cooldown"
+	reasonDebtTooLarge   = "debt_too_large"
+	reasonBalanceUnknown = "balance_unknown"
+	reasonTariffUnknown  = "tariff_unknown"
+	reasonFreeTariff     = "free_tariff"
+)
+
+// PromisedPaymentOffer tells whether the user can take a promised payment now.
+func (s *Service) PromisedPaymentOffer(ctx context.Context, token string) (domain.PromisedPaymentOffer, error) {
+	ctx, span := tracing.Start(ctx, "profile.PromisedPaymentOffer")
+	defer span.End()
+
+	if token == "" || middleware.ContainsForbiddenChars(token) {
+		return domain.PromisedPaymentOffer{}, domain.ErrInvalidToken
+	}
+
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		return domain.PromisedPaymentOffer{}, err
+	}
+	history, err := s.profileRepo.PromisedPayments(ctx, token)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching promised payments", "error", err)
+		return domain.PromisedPaymentOffer{}, err
+	}
+
+	offer := promisedPaymentOffer(profile, s.tariffPrice(ctx, token, profile), history, time.Now())
+	if offer.Reason != "" {
+		offer.Message = reasonMessage(i18n.FromContext(ctx), offer.Reason)
+	}
+	return offer, nil
+}
+
+// CreatePromisedPayment takes a promised payment. A zero amount takes the
+// maximum the account is offered.
+func (s *Service) CreatePromisedPayment(ctx context.Context, token string, amount domain.Money) (domain.PromisedPayment, error) {
+	ctx, span := tracing.Start(ctx, "profile.CreatePromisedPayment")
+	defer span.End()
+
+	offer, err := s.PromisedPaymentOffer(ctx, token)
+	if err != nil {
+		return domain.PromisedPayment{}, err
+	}
+	if !offer.Eligible {
+		s.logger.InfoContext(ctx, "Promised payment refused", "reason", offer.Reason)
+		return domain.PromisedPayment{}, &domain.NotEligibleError{Reason: offer.Reason, Message: offer.Message}
+	}
+
+	if amount.Minor == 0 {
+		amount = offer.MaxAmount
+	}
+	if amount.Currency != domain.CurrencyRUB || amount.Minor < offer.MinAmount.Minor || amount.Minor > offer.MaxAmount.Minor {
+		return domain.PromisedPayment{}, domain.ErrBadParamInput
+	}
+
+	s.logger.InfoContext(ctx, "Creating promised payment")
+	promised, err := s.profileRepo.CreatePromisedPayment(ctx, token, amount, offer.Days)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error creating promised payment", "error", err)
+		return domain.PromisedPayment{}, err
+	}
+	s.cache.Invalidate(ctx, token)
+	return promised, nil
+}
+
+// reasonMessage explains why a promised payment is not offered.
+func reasonMessage(l *i18n.Localizer, reason string) string {
+	key := "promised_payment." + reason
+	switch reason {
+	case reasonLimitReached:
+		return l.T(key, l.Plural("common.days", int(promisedPaymentPeriod/(24*time.Hour))))
+	case reasonCooldown:
+		return l.T(key, l.Plural("common.days", int(promisedPaymentCooldown/(24*time.Hour))))
+	default:
+		return l.T(key)
+	}
+}
+
+// tariffPrice returns the monthly price of the user's tariff, or nil if it
+// can't be found.
+func (s *Service) tariffPrice(ctx context.Context, token string, profile domain.Profile) *domain.Money {
+	tariffs, err := s.tariffRepo.Tariffs(ctx, token)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching tariffs for promised payment", "error", err)
+		return nil
+	}
+	for _, tariff := range tariffs {
+		if tariff.ID == profile.TariffID {
+			return &tariff.Price
+		}
+	}
+	s.logger.WarnContext(ctx, "Current tariff not among available tariffs", "tariff_id", profile.TariffID)
+	return nil
+}
+
+// promisedPaymentOffer applies the eligibility rules. The promised payment
+// must cover the debt and may not exceed price, the monthly price of the
+// tariff, which is nil when unknown.
+func promisedPaymentOffer(profile domain.Profile, price *domain.Money, history domain.PromisedPaymentHistory, now time.Time) domain.PromisedPaymentOffer {
+	offer := domain.PromisedPaymentOffer{
+		Days:    promisedPaymentDays,
+		Current: history.Current,
+	}
+	if price != nil {
+		offer.MaxAmount = wholeRoubles(max(price.Minor, 0))
+	}
+	if profile.Balance != nil {
+		offer.MinAmount = wholeRoubles(max(-profile.Balance.Minor, 0))
+	}
+
+	switch {
+	case history.Current != nil && history.Current.Deadline.After(now):
+		offer.Reason = reasonAlreadyActive
+		return offer
+	case profile.Balance == nil:
+		// Without the debt the amount can't be worked out.
+		offer.Reason = reasonBalanceUnknown
+		return offer
+	case profile.Balance.Minor >= 0:
+		offer.Reason = reasonNotInDebt
+		return offer
+	case price == nil:
+		offer.Reason = reasonTariffUnknown
+		return offer
+	case offer.MaxAmount.Minor == 0:
+		// Nothing to promise against a tariff without a monthly charge.
+		offer.Reason = reasonFreeTariff
+		return offer
+	case offer.MinAmount.Minor > offer.MaxAmount.Minor:
+		offer.Reason = reasonDebtTooLarge
+		return offer
+	}
+
+	past := history.Past
+	if history.Current != nil {
+		past = append(past, *history.Current)
+	}
+
+	var recent []time.Time
+	var lastDeadline time.Time
+	for _, p := range past {
+		if now.Sub(p.CreatedAt) < promisedPaymentPeriod {
+			recent = append(recent, p.CreatedAt)
+		}
+		if p.Deadline.After(lastDeadline) {
+			lastDeadline = p.Deadline
+		}
+	}
+
+	if len(recent) >= promisedPaymentsPerPeriod {
+		// Available again once the oldest one in the window drops out.
+		oldest := recent[0]
+		for _, t := range recent[1:] {
+			if t.Before(oldest) {
+				oldest = t
+			}
+		}
+		from := oldest.Add(promisedPaymentPeriod)
+		offer.Reason = reasonLimitReached
+		offer.AvailableFrom = &from
+		return offer
+	}
+	if !lastDeadline.IsZero() && now.Before(lastDeadline.Add(promisedPaymentCooldown)) {
+		from := lastDeadline.Add(promisedPaymentCooldown)
+		offer.Reason = reasonCooldown
+		offer.AvailableFrom = &from
+		return offer
+	}
+
+	offer.Eligible = true
+	return offer
+}
+
+// wholeRoubles rounds a non-negative amount in kopecks up to whole roubles.
+func wholeRoubles(minor int64) domain.Money {
+	return domain.RUB((minor + 99) / 100 * 100)
+}
@@ -0,0 +1,89 @@
+package profile
+
+import (
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/i18n"
+	"testing"
+	"time"
+)
+
+func TestPromisedPaymentOffer(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	days := func(n int) time.Time { return now.AddDate(0, 0, n) }
+	taken := func(created int) domain.PromisedPayment {
+		return domain.PromisedPayment{Amount: domain.RUB(50000), CreatedAt: days(created), Deadline: days(created + promisedPaymentDays)}
+	}
+	inDebt := domain.Profile{Balance: rub(-12050), ToPay: rub(65000)}
+	price := rub(65000)
+
+	tests := []struct {
+		name       string
+		profile    domain.Profile
+		price      *domain.Money
+		history    domain.PromisedPaymentHistory
+		wantReason string
+		wantMin    int64
+		wantMax    int64
+	}{
+		{"Eligible without history", inDebt, price, domain.PromisedPaymentHistory{}, "", 12100, 65000},
+		{"Capped by the tariff, not the amount due", domain.Profile{Balance: rub(-12050), ToPay: rub(12050)}, rub(49950), domain.PromisedPaymentHistory{}, "", 12100, 50000},
+		{"Positive balance", domain.Profile{Balance: rub(1000), ToPay: rub(65000)}, price, domain.PromisedPaymentHistory{}, reasonNotInDebt, 0, 65000},
+		{"Debt above tariff", domain.Profile{Balance: rub(-90000), ToPay: rub(65000)}, price, domain.PromisedPaymentHistory{}, reasonDebtTooLarge, 90000, 65000},
+		{"Unknown balance", domain.Profile{ToPay: rub(65000)}, price, domain.PromisedPaymentHistory{}, reasonBalanceUnknown, 0, 65000},
+		{"Unknown tariff", inDebt, nil, domain.PromisedPaymentHistory{}, reasonTariffUnknown, 12100, 0},
+		{"Free tariff", inDebt, rub(0), domain.PromisedPaymentHistory{}, reasonFreeTariff, 12100, 0},
+		{"Active promised payment", inDebt, price, domain.PromisedPaymentHistory{Current: ptr(taken(-1))}, reasonAlreadyActive, 12100, 65000},
+		{"Within cooldown", inDebt, price, domain.PromisedPaymentHistory{Past: []domain.PromisedPayment{taken(-6)}}, reasonCooldown, 12100, 65000},
+		{"After cooldown", inDebt, price, domain.PromisedPaymentHistory{Past: []domain.PromisedPayment{taken(-11)}}, "", 12100, 65000},
+		{"Frequency limit", inDebt, price, domain.PromisedPaymentHistory{Past: []domain.PromisedPayment{taken(-28), taken(-14)}}, reasonLimitReached, 12100, 65000},
+		{"Old payments drop out of the window", inDebt, price, domain.PromisedPaymentHistory{Past: []domain.PromisedPayment{taken(-45), taken(-20)}}, "", 12100, 65000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			offer := promisedPaymentOffer(tt.profile, tt.price, tt.history, now)
+			if offer.Reason != tt.wantReason {
+				t.Errorf("reason = %q, want %q", offer.Reason, tt.wantReason)
+			}
+			if offer.Eligible != (tt.wantReason == "") {
+				t.Errorf("eligible = %v with reason %q", offer.Eligible, offer.Reason)
+			}
+			if offer.MinAmount.Minor != tt.wantMin {
+				t.Errorf("min amount = %v, want %d kopecks", offer.MinAmount, tt.wantMin)
+			}
+			if offer.MaxAmount.Minor != tt.wantMax {
+				t.Errorf("max amount = %v, want %d kopecks", offer.MaxAmount, tt.wantMax)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func rub(minor int64) *domain.Money {
+	m := domain.RUB(minor)
+	return &m
+}
+
+func TestReasonMessage(t *testing.T) {
+	ru := i18n.Default().Localizer("ru")
+	en := i18n.Default().Localizer("en")
+
+	tests := []struct {
+		l      *i18n.Localizer
+		reason string
+		want   string
+	}{
+		{ru, reasonCooldown, "Новый обещанный платёж можно взять не раньше чем через 7 дней после окончания предыдущего"},
+		{en, reasonCooldown, "A new promised payment is available 7 days after the previous one ends"},
+		{ru, reasonLimitReached, "Лимит обещанных платежей за 30 дней исчерпан"},
+		{en, reasonNotInDebt, "A promised payment is only available when the balance is negative"},
+	}
+	for _, tt := range tests {
+		if got := reasonMessage(tt.l, tt.reason); got != tt.want {
+			t.Errorf("reasonMessage(%s, %s) = %q, want %q", tt.l.Language(), tt.reason, got, tt.want)
+		}
+	}
+}
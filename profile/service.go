@@ -4,8 +4,9 @@ import (
 	"context"
 	"errors"
 	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
-	"log"
-	"strings"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+	"time"
 
 	"github.com/llchhh/spektr-account-api/domain"
 )
@@ -13,63 +14,117 @@ import (
 type ProfileRepository interface {
 	Profile(ctx context.Context, token string) (domain.Profile, error)
 	ChangePassword(ctx context.Context, token string, password string) error
-	ChangeEmail(ctx context.Context, token string, email string) error
-	ChangePhone(ctx context.Context, token string, newPhone string) error
+	UpdateUserInfo(ctx context.Context, token string, update domain.ProfileUpdate) error
+	PromisedPayments(ctx context.Context, token string) (domain.PromisedPaymentHistory, error)
+	CreatePromisedPayment(ctx context.Context, token string, amount domain.Money, days int) (domain.PromisedPayment, error)
+}
+
+// TariffRepository lists the tariffs available to the user, for the price
+// of the current one.
+type TariffRepository interface {
+	Tariffs(ctx context.Context, token string) ([]domain.Tariff, error)
+}
+
+// SuspensionChecker reports the voluntary suspension in effect, if any.
+type SuspensionChecker interface {
+	Active(ctx context.Context, token string) (*domain.Suspension, error)
 }
 
 type Service struct {
 	profileRepo ProfileRepository
+	suspensions SuspensionChecker
+	tariffRepo  TariffRepository
+	cache       *Cache
+	logger      *slog.Logger
 }
 
 // NewService creates a new Service instance with the provided ProfileRepository.
-func NewService(p ProfileRepository) *Service {
+// Profiles are served from c, which other services invalidate when they
+// change what the profile shows.
+func NewService(p ProfileRepository, s SuspensionChecker, t TariffRepository, c *Cache, logger *slog.Logger) *Service {
 	return &Service{
 		profileRepo: p,
+		suspensions: s,
+		tariffRepo:  t,
+		cache:       c,
+		logger:      logger,
 	}
 }
 
-// Profile retrieves the user's profile using the provided token.
+// Profile retrieves the user's profile using the provided token. It is
+// served from the per-session cache while fresh.
 func (s *Service) Profile(ctx context.Context, token string) (domain.Profile, error) {
+	ctx, span := tracing.Start(ctx, "profile.Profile")
+	defer span.End()
+
 	if token == "" {
-		log.Println("Profile request failed: missing authorization token")
+		s.logger.WarnContext(ctx, "Profile request failed: missing authorization token")
 		return domain.Profile{}, domain.ErrInvalidToken
 	}
 	if middleware.ContainsForbiddenChars(token) {
 		return domain.Profile{}, domain.ErrInvalidToken
 	}
-	log.Printf("Fetching profile for token: %s", token)
+	return s.cache.load(ctx, sessionKey(ctx, token), func(ctx context.Context) (domain.Profile, error) {
+		return s.fetch(ctx, token)
+	})
+}
+
+// fetch reads the profile from the billing with the promised payment and
+// suspension in effect.
+func (s *Service) fetch(ctx context.Context, token string) (domain.Profile, error) {
+	s.logger.DebugContext(ctx, "Fetching profile")
 
 	profile, err := s.profileRepo.Profile(ctx, token)
 	if err != nil {
-		log.Printf("Error fetching profile for token %s: %v", token, err)
+		s.logger.ErrorContext(ctx, "Error fetching profile", "error", err)
 		return domain.Profile{}, err
 	}
 
-	log.Printf("Successfully fetched profile for token: %s", token)
+	// The profile is still useful without the promised payment, so a
+	// failure here is not fatal.
+	promised, err := s.profileRepo.PromisedPayments(ctx, token)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching promised payments", "error", err)
+	} else if promised.Current != nil && promised.Current.Deadline.After(time.Now()) {
+		profile.PromisedPayment = promised.Current
+	}
+
+	suspension, err := s.suspensions.Active(ctx, token)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching suspensions", "error", err)
+	} else if suspension != nil {
+		// The internet is off on purpose, not because of the balance.
+		profile.Suspension = suspension
+		profile.InternetStatus = false
+	}
+
+	s.logger.DebugContext(ctx, "Successfully fetched profile")
 	return profile, nil
 }
 
 // ChangePassword updates the user's password using the provided token and new password.
 func (s *Service) ChangePassword(ctx context.Context, token string, password string) error {
+	ctx, span := tracing.Start(ctx, "profile.ChangePassword")
+	defer span.End()
 
 	if token == "" {
-		log.Println("ChangePassword request failed: missing authorization token")
+		s.logger.WarnContext(ctx, "ChangePassword request failed: missing authorization token")
 		return domain.ErrUnauthorized
 	}
 	err := middleware.ValidatePassword(password)
 	if err != nil {
-		log.Println("Invalid phone number format detected")
+		s.logger.WarnContext(ctx, "Invalid password format detected")
 		return domain.ErrInvalidCredentials
 	}
 	if middleware.ContainsForbiddenChars(token) {
-		log.Println("Invalid token format detected")
+		s.logger.WarnContext(ctx, "Invalid token format detected")
 		return domain.ErrInvalidToken
 	}
-	log.Printf("Changing password for token: %s", token)
+	s.logger.InfoContext(ctx, "Changing password")
 
 	err = s.profileRepo.ChangePassword(ctx, token, password)
 	if err != nil {
-		log.Printf("Error changing password for token %s: %v", token, err)
+		s.logger.ErrorContext(ctx, "Error changing password", "error", err)
 
 		// Check if the error indicates an expired token
 		if errors.Is(err, domain.ErrSessionExpired) {
@@ -79,62 +134,24 @@ func (s *Service) ChangePassword(ctx context.Context, token string, password str
 		}
 	}
 
-	log.Printf("Password successfully changed for token: %s", token)
+	s.logger.InfoContext(ctx, "Password successfully changed")
 	return nil
 }
 
 // ChangeEmail updates the user's email using the provided token and new email.
 func (s *Service) ChangeEmail(ctx context.Context, token string, email string) error {
-	if middleware.ContainsForbiddenChars(email) {
-		log.Println("Invalid phone number format detected")
-		return domain.ErrInvalidCredentials
-	}
-	if middleware.ContainsForbiddenChars(token) {
-		log.Println("Invalid token format detected")
-		return domain.ErrInvalidToken
-	}
-	log.Printf("Changing email for token: %s", token)
-
-	err := s.profileRepo.ChangeEmail(ctx, token, email)
-	if err != nil {
-		log.Printf("Error changing email for token %s: %v", token, err)
+	ctx, span := tracing.Start(ctx, "profile.ChangeEmail")
+	defer span.End()
 
-		// Check if the error indicates an expired token
-		if strings.Contains(err.Error(), "Необходимо авторизоваться") {
-			log.Println("Token has expired or is invalid.")
-			return domain.ErrSessionExpired
-		}
-		return err
-	}
-
-	log.Printf("Email successfully changed for token: %s", token)
-	return nil
+	_, err := s.UpdateProfile(ctx, token, domain.ProfileUpdate{Email: &email}, "")
+	return err
 }
 
-// ChangePhone updates the user's phone using the provided token and new email.
+// ChangePhone updates the user's phone using the provided token and new phone.
 func (s *Service) ChangePhone(ctx context.Context, token string, phone string) error {
-	if middleware.ContainsForbiddenChars(phone) {
-		log.Println("Invalid phone number format detected")
-		return domain.ErrInvalidCredentials
-	}
-	if middleware.ContainsForbiddenChars(token) {
-		log.Println("Invalid token format detected")
-		return domain.ErrInvalidToken
-	}
-	log.Printf("Changing phone for token: %s", token)
-
-	err := s.profileRepo.ChangePhone(ctx, token, phone)
-	if err != nil {
-		log.Printf("Error changing phone for token %s: %v", token, err)
+	ctx, span := tracing.Start(ctx, "profile.ChangePhone")
+	defer span.End()
 
-		// Check if the error indicates an expired token
-		if strings.Contains(err.Error(), "Необходимо авторизоваться") {
-			log.Println("Token has expired or is invalid.")
-			return domain.ErrSessionExpired
-		}
-		return err
-	}
-
-	log.Printf("phone successfully changed for token: %s", token)
-	return nil
+	_, err := s.UpdateProfile(ctx, token, domain.ProfileUpdate{Phone: &phone}, "")
+	return err
 }
@@ -0,0 +1,95 @@
+package profile
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"net/mail"
+	"regexp"
+	"strings"
+)
+
+// phonePattern is a phone number in international format without separators.
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{10,15}$`)
+
+// UpdateProfile applies a merge patch of the contact details with a single
+// billing call and returns the updated profile. When ifMatch is set the
+// update is refused with domain.ErrPreconditionFailed unless its EditVersion
+// matches that of the profile GetProfile serves.
+func (s *Service) UpdateProfile(ctx context.Context, token string, update domain.ProfileUpdate, ifMatch string) (domain.Profile, error) {
+	ctx, span := tracing.Start(ctx, "profile.UpdateProfile")
+	defer span.End()
+
+	if token == "" || middleware.ContainsForbiddenChars(token) {
+		return domain.Profile{}, domain.ErrInvalidToken
+	}
+	if err := normalizeUpdate(&update); err != nil {
+		return domain.Profile{}, err
+	}
+
+	if ifMatch != "" && ifMatch != "*" {
+		// The client's ETag came from Profile, possibly from the cache,
+		// so compare against the same source. Updates made through this
+		// service drop the cached profile of every session of the contract.
+		current, err := s.Profile(ctx, token)
+		if err != nil {
+			return domain.Profile{}, err
+		}
+		if !etagMatches(ifMatch, current.EditVersion()) {
+			s.logger.InfoContext(ctx, "Profile update refused: profile has changed")
+			return domain.Profile{}, domain.ErrPreconditionFailed
+		}
+	}
+
+	if update.Empty() {
+		return s.Profile(ctx, token)
+	}
+
+	s.logger.InfoContext(ctx, "Updating profile")
+	if err := s.profileRepo.UpdateUserInfo(ctx, token, update); err != nil {
+		s.logger.ErrorContext(ctx, "Error updating profile", "error", err)
+		return domain.Profile{}, err
+	}
+	s.cache.Invalidate(ctx, token)
+	profile, err := s.fetch(ctx, token)
+	if err != nil {
+		return domain.Profile{}, err
+	}
+	// Other sessions of the contract must not keep showing the old details.
+	s.cache.InvalidateContract(profile.ID)
+	return profile, nil
+}
+
+// normalizeUpdate validates every field of the patch together and brings
+// the phone to digits with an optional leading plus.
+func normalizeUpdate(update *domain.ProfileUpdate) error {
+	if update.Email != nil {
+		email := strings.TrimSpace(*update.Email)
+		if email != "" {
+			addr, err := mail.ParseAddress(email)
+			if err != nil || addr.Address != email || middleware.ContainsForbiddenChars(email) {
+				return domain.ErrBadParamInput
+			}
+		}
+		update.Email = &email
+	}
+	if update.Phone != nil {
+		phone := strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(*update.Phone)
+		if !phonePattern.MatchString(phone) {
+			return domain.ErrBadParamInput
+		}
+		update.Phone = &phone
+	}
+	return nil
+}
+
+// etagMatches reports whether an If-Match header lists an ETag of version.
+func etagMatches(ifMatch, version string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		if domain.EditVersionOf(strings.TrimSpace(candidate)) == version {
+			return true
+		}
+	}
+	return false
+}
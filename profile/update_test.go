@@ -0,0 +1,152 @@
+package profile
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"testing"
+	"time"
+)
+
+type stubProfileRepo struct {
+	profile domain.Profile
+	updates []domain.ProfileUpdate
+}
+
+func (s *stubProfileRepo) Profile(context.Context, string) (domain.Profile, error) {
+	return s.profile, nil
+}
+
+func (s *stubProfileRepo) ChangePassword(context.Context, string, string) error {
+	return nil
+}
+
+func (s *stubProfileRepo) UpdateUserInfo(_ context.Context, _ string, update domain.ProfileUpdate) error {
+	s.updates = append(s.updates, update)
+	if update.Email != nil {
+		s.profile.Email = *update.Email
+	}
+	if update.Phone != nil {
+		s.profile.Phone = *update.Phone
+	}
+	return nil
+}
+
+func (s *stubProfileRepo) PromisedPayments(context.Context, string) (domain.PromisedPaymentHistory, error) {
+	return domain.PromisedPaymentHistory{}, nil
+}
+
+func (s *stubProfileRepo) CreatePromisedPayment(context.Context, string, domain.Money, int) (domain.PromisedPayment, error) {
+	return domain.PromisedPayment{}, nil
+}
+
+type noSuspensions struct{}
+
+func (noSuspensions) Active(context.Context, string) (*domain.Suspension, error) {
+	return nil, nil
+}
+
+type noTariffs struct{}
+
+func (noTariffs) Tariffs(context.Context, string) ([]domain.Tariff, error) {
+	return nil, nil
+}
+
+func TestUpdateProfile(t *testing.T) {
+	tests := []struct {
+		name      string
+		patch     string
+		wantErr   bool
+		wantEmail string
+		wantPhone string
+	}{
+		{"Both fields", `{"email":"new@example.com","phone":"+7 (913) 477-36-49"}`, false, "new@example.com", "+79134773649"},
+		{"Only phone", `{"phone":"+79130000000"}`, false, "old@example.com", "+79130000000"},
+		{"Remove email", `{"email":null}`, false, "", "+79134773649"},
+		{"Empty patch", `{}`, false, "old@example.com", "+79134773649"},
+		{"Invalid email", `{"email":"Ivan <ivan@example.com>"}`, true, "", ""},
+		{"Invalid phone with valid email", `{"email":"new@example.com","phone":"12"}`, true, "", ""},
+		{"Remove phone", `{"phone":null}`, true, "", ""},
+		{"Read-only field", `{"balance":100}`, true, "", ""},
+		{"Not an object", `["email"]`, true, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &stubProfileRepo{profile: domain.Profile{Email: "old@example.com", Phone: "+79134773649"}}
+			s := NewService(repo, noSuspensions{}, noTariffs{}, NewCache(time.Minute), logging.Discard())
+
+			var update domain.ProfileUpdate
+			err := json.Unmarshal([]byte(tt.patch), &update)
+			if err == nil {
+				_, err = s.UpdateProfile(context.Background(), "token", update, "")
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if len(repo.updates) != 0 {
+					t.Errorf("sent %d updates for an invalid patch", len(repo.updates))
+				}
+				return
+			}
+			if len(repo.updates) > 1 {
+				t.Errorf("sent %d set_user_info calls, want at most one", len(repo.updates))
+			}
+			if repo.profile.Email != tt.wantEmail || repo.profile.Phone != tt.wantPhone {
+				t.Errorf("profile = %q %q, want %q %q", repo.profile.Email, repo.profile.Phone, tt.wantEmail, tt.wantPhone)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileIfMatch(t *testing.T) {
+	repo := &stubProfileRepo{profile: domain.Profile{Email: "old@example.com", Phone: "+79134773649"}}
+	s := NewService(repo, noSuspensions{}, noTariffs{}, NewCache(time.Minute), logging.Discard())
+	ctx := context.Background()
+
+	current, _ := s.Profile(ctx, "token")
+	etag := current.ETag()
+	email := "first@example.com"
+
+	updated, err := s.UpdateProfile(ctx, "token", domain.ProfileUpdate{Email: &email}, etag)
+	if err != nil {
+		t.Fatalf("first update: %v", err)
+	}
+	if updated.ETag() == etag {
+		t.Error("ETag did not change after the update")
+	}
+
+	// A second device still holding the old ETag must not overwrite it.
+	email = "second@example.com"
+	if _, err := s.UpdateProfile(ctx, "token", domain.ProfileUpdate{Email: &email}, etag); !errors.Is(err, domain.ErrPreconditionFailed) {
+		t.Errorf("stale update error = %v, want %v", err, domain.ErrPreconditionFailed)
+	}
+	if repo.profile.Email != "first@example.com" {
+		t.Errorf("email = %q, stale update was applied", repo.profile.Email)
+	}
+}
+
+func TestUpdateProfileIfMatchIgnoresBalance(t *testing.T) {
+	repo := &stubProfileRepo{profile: domain.Profile{ID: "1", Email: "old@example.com", Phone: "+79134773649", Balance: rub(10000)}}
+	cache := NewCache(time.Minute)
+	s := NewService(repo, noSuspensions{}, noTariffs{}, cache, logging.Discard())
+	ctx := context.Background()
+
+	current, _ := s.Profile(ctx, "token")
+	etag := current.ETag()
+
+	// A payment arrives and the cached profile expires before the update.
+	repo.profile.Balance = rub(60000)
+	cache.InvalidateContract("1")
+	if refreshed, _ := s.Profile(ctx, "token"); refreshed.ETag() == etag {
+		t.Error("ETag did not change with the balance")
+	}
+
+	email := "new@example.com"
+	if _, err := s.UpdateProfile(ctx, "token", domain.ProfileUpdate{Email: &email}, etag); err != nil {
+		t.Errorf("UpdateProfile() error = %v, want the update applied", err)
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"github.com/llchhh/spektr-account-api/domain"
 	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
-	"log"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
 	"strings"
 )
 
@@ -14,17 +15,22 @@ type RepairRepository interface {
 
 type Service struct {
 	repairRepo RepairRepository
+	logger     *slog.Logger
 }
 
 // NewService creates a new RepairService instance with the provided RepairRepository.
-func NewService(r RepairRepository) *Service {
+func NewService(r RepairRepository, logger *slog.Logger) *Service {
 	return &Service{
 		repairRepo: r,
+		logger:     logger,
 	}
 }
 
 // CreateRepair creates a new repair request using the provided token and repair details.
 func (s *Service) CreateRepair(ctx context.Context, token string, repair domain.Repair) error {
+	ctx, span := tracing.Start(ctx, "repair.CreateRepair")
+	defer span.End()
+
 	if middleware.ContainsForbiddenChars(repair.Text) {
 		return domain.ErrInvalidToken
 	}
@@ -35,23 +41,19 @@ func (s *Service) CreateRepair(ctx context.Context, token string, repair domain.
 		return domain.ErrUnauthorized
 	}
 
-	log.Printf("Creating repair request for token: %s", token)
+	s.logger.InfoContext(ctx, "Creating repair request")
 
 	err := s.repairRepo.CreateRepair(ctx, token, repair)
 	if err != nil {
-		log.Printf("Error changing password for token %s: %v", token, err)
-		if err != nil {
-			log.Printf("Error changing phone for token %s: %v", token, err)
-
-			// Check if the error indicates an expired token
-			if strings.Contains(err.Error(), "Необходимо авторизоваться") {
-				log.Println("Token has expired or is invalid.")
-				return domain.ErrSessionExpired
-			}
-			return err
+		s.logger.ErrorContext(ctx, "Error creating repair request", "error", err)
+
+		// Check if the error indicates an expired token
+		if strings.Contains(err.Error(), "Необходимо авторизоваться") {
+			return domain.ErrSessionExpired
 		}
+		return err
 	}
 
-	log.Printf("Repair created successfully for token: %s", token)
+	s.logger.InfoContext(ctx, "Repair created successfully")
 	return nil
 }
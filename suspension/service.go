@@ -0,0 +1,267 @@
+package suspension
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+const (
+	// allowedDaysPerYear is how many days a year the service may be suspended.
+	allowedDaysPerYear = 90
+	// minDays is the shortest suspension the billing accepts.
+	minDays = 7
+)
+
+type SuspensionRepository interface {
+	Suspensions(ctx context.Context, token string) ([]domain.Suspension, error)
+	CreateSuspension(ctx context.Context, token string, start, end time.Time) (string, error)
+	CancelSuspension(ctx context.Context, token, id string) error
+}
+
+// ProfileRepository resolves the contract a session belongs to.
+type ProfileRepository interface {
+	Profile(ctx context.Context, token string) (domain.Profile, error)
+}
+
+// ProfileInvalidator drops the cached profiles of a contract once a change
+// makes them stale.
+type ProfileInvalidator interface {
+	InvalidateContract(contractID string)
+}
+
+type Service struct {
+	suspensionRepo SuspensionRepository
+	profileRepo    ProfileRepository
+	profiles       ProfileInvalidator
+	watcher        *Watcher
+	now            func() time.Time
+	logger         *slog.Logger
+}
+
+// NewService creates a new Service instance. Scheduled suspensions are
+// handed to the watcher so the user is notified when they start and end.
+func NewService(s SuspensionRepository, p ProfileRepository, i ProfileInvalidator, w *Watcher, logger *slog.Logger) *Service {
+	return &Service{
+		suspensionRepo: s,
+		profileRepo:    p,
+		profiles:       i,
+		watcher:        w,
+		now:            time.Now,
+		logger:         logger,
+	}
+}
+
+// Suspensions lists the suspensions and the days left for the current year.
+func (s *Service) Suspensions(ctx context.Context, token string) (domain.SuspensionSummary, error) {
+	ctx, span := tracing.Start(ctx, "suspension.Suspensions")
+	defer span.End()
+
+	suspensions, err := s.list(ctx, token)
+	if err != nil {
+		return domain.SuspensionSummary{}, err
+	}
+
+	used := usedDays(suspensions, s.now().Year())
+	return domain.SuspensionSummary{
+		Items:         suspensions,
+		AllowedDays:   allowedDaysPerYear,
+		UsedDays:      used,
+		RemainingDays: max(allowedDaysPerYear-used, 0),
+	}, nil
+}
+
+// Active returns the suspension in effect today, or nil.
+func (s *Service) Active(ctx context.Context, token string) (*domain.Suspension, error) {
+	ctx, span := tracing.Start(ctx, "suspension.Active")
+	defer span.End()
+
+	suspensions, err := s.list(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	for i := range suspensions {
+		if suspensions[i].Status == domain.SuspensionActive {
+			return &suspensions[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// Schedule suspends the service between the requested dates.
+func (s *Service) Schedule(ctx context.Context, token string, req domain.SuspensionRequest) (domain.Suspension, error) {
+	ctx, span := tracing.Start(ctx, "suspension.Schedule")
+	defer span.End()
+
+	start, err := time.ParseInLocation(time.DateOnly, req.StartDate, time.Local)
+	if err != nil {
+		return domain.Suspension{}, domain.ErrBadParamInput
+	}
+	end, err := time.ParseInLocation(time.DateOnly, req.EndDate, time.Local)
+	if err != nil {
+		return domain.Suspension{}, domain.ErrBadParamInput
+	}
+
+	suspensions, err := s.list(ctx, token)
+	if err != nil {
+		return domain.Suspension{}, err
+	}
+	suspension := domain.Suspension{StartDate: start, EndDate: end, Days: days(start, end)}
+	if err := validate(suspension, suspensions, today(s.now())); err != nil {
+		return domain.Suspension{}, err
+	}
+
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		return domain.Suspension{}, err
+	}
+
+	s.logger.InfoContext(ctx, "Scheduling suspension", "start_date", req.StartDate, "end_date", req.EndDate)
+	suspension.ID, err = s.suspensionRepo.CreateSuspension(ctx, token, start, end)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error scheduling suspension", "error", err)
+		return domain.Suspension{}, err
+	}
+	suspension.Status = domain.SuspensionScheduled
+
+	s.watcher.Watch(ctx, profile.ID, suspension)
+	return suspension, nil
+}
+
+// Cancel cancels a scheduled suspension or ends an active one early.
+func (s *Service) Cancel(ctx context.Context, token, id string) error {
+	ctx, span := tracing.Start(ctx, "suspension.Cancel")
+	defer span.End()
+
+	suspensions, err := s.list(ctx, token)
+	if err != nil {
+		return err
+	}
+	var found *domain.Suspension
+	for i := range suspensions {
+		if suspensions[i].ID == id {
+			found = &suspensions[i]
+		}
+	}
+	if found == nil {
+		return domain.ErrNotFound
+	}
+	if found.Status != domain.SuspensionScheduled && found.Status != domain.SuspensionActive {
+		return domain.ErrConflict
+	}
+
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	s.logger.InfoContext(ctx, "Cancelling suspension", "suspension_id", id)
+	if err := s.suspensionRepo.CancelSuspension(ctx, token, id); err != nil {
+		s.logger.ErrorContext(ctx, "Error cancelling suspension", "error", err)
+		return err
+	}
+	s.watcher.Unwatch(ctx, profile.ID, id)
+	s.profiles.InvalidateContract(profile.ID)
+	return nil
+}
+
+// list fetches the suspensions with their status as of today, newest first.
+func (s *Service) list(ctx context.Context, token string) ([]domain.Suspension, error) {
+	if token == "" || middleware.ContainsForbiddenChars(token) {
+		return nil, domain.ErrInvalidToken
+	}
+	suspensions, err := s.suspensionRepo.Suspensions(ctx, token)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching suspensions", "error", err)
+		return nil, err
+	}
+
+	now := today(s.now())
+	for i := range suspensions {
+		suspensions[i].Days = days(suspensions[i].StartDate, suspensions[i].EndDate)
+		if suspensions[i].Status == domain.SuspensionCancelled {
+			continue
+		}
+		suspensions[i].Status = status(suspensions[i], now)
+	}
+	sort.Slice(suspensions, func(i, j int) bool {
+		return suspensions[i].StartDate.After(suspensions[j].StartDate)
+	})
+	return suspensions, nil
+}
+
+// validate checks the requested suspension against the calendar, overlaps
+// and the yearly allowance.
+func validate(suspension domain.Suspension, existing []domain.Suspension, now time.Time) error {
+	if !suspension.StartDate.After(now) || suspension.EndDate.Before(suspension.StartDate) {
+		return domain.ErrBadParamInput
+	}
+	if suspension.Days < minDays || suspension.Days > allowedDaysPerYear {
+		return domain.ErrBadParamInput
+	}
+	for _, other := range existing {
+		if other.Status == domain.SuspensionCancelled {
+			continue
+		}
+		if !suspension.StartDate.After(other.EndDate) && !other.StartDate.After(suspension.EndDate) {
+			return domain.ErrConflict
+		}
+	}
+
+	// A suspension over New Year counts against both years.
+	all := append([]domain.Suspension{suspension}, existing...)
+	for year := suspension.StartDate.Year(); year <= suspension.EndDate.Year(); year++ {
+		if usedDays(all, year) > allowedDaysPerYear {
+			return domain.ErrNotEligible
+		}
+	}
+	return nil
+}
+
+// usedDays counts the suspended days that fall into year.
+func usedDays(suspensions []domain.Suspension, year int) int {
+	yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	yearEnd := time.Date(year, time.December, 31, 0, 0, 0, 0, time.Local)
+
+	used := 0
+	for _, s := range suspensions {
+		if s.Status == domain.SuspensionCancelled {
+			continue
+		}
+		start, end := s.StartDate, s.EndDate
+		if start.Before(yearStart) {
+			start = yearStart
+		}
+		if end.After(yearEnd) {
+			end = yearEnd
+		}
+		if !end.Before(start) {
+			used += days(start, end)
+		}
+	}
+	return used
+}
+
+func status(s domain.Suspension, today time.Time) domain.SuspensionStatus {
+	switch {
+	case today.Before(s.StartDate):
+		return domain.SuspensionScheduled
+	case today.After(s.EndDate):
+		return domain.SuspensionFinished
+	default:
+		return domain.SuspensionActive
+	}
+}
+
+// days counts the days between two dates, both inclusive.
+func days(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24+0.5) + 1
+}
+
+func today(now time.Time) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+}
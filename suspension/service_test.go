@@ -0,0 +1,122 @@
+package suspension
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"github.com/llchhh/spektr-account-api/internal/storage/memory"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, _ := time.ParseInLocation(time.DateOnly, s, time.Local)
+	return t
+}
+
+func suspended(start, end string) domain.Suspension {
+	return domain.Suspension{StartDate: date(start), EndDate: date(end), Days: days(date(start), date(end))}
+}
+
+func TestValidate(t *testing.T) {
+	now := date("2024-06-10")
+	existing := []domain.Suspension{
+		suspended("2024-02-01", "2024-03-31"), // 60 days
+		{StartDate: date("2024-07-01"), EndDate: date("2024-07-20"), Status: domain.SuspensionCancelled},
+	}
+
+	tests := []struct {
+		name       string
+		suspension domain.Suspension
+		wantErr    error
+	}{
+		{"Valid", suspended("2024-07-01", "2024-07-14"), nil},
+		{"Starts today", suspended("2024-06-10", "2024-06-30"), domain.ErrBadParamInput},
+		{"Ends before start", suspended("2024-07-10", "2024-07-01"), domain.ErrBadParamInput},
+		{"Too short", suspended("2024-07-01", "2024-07-03"), domain.ErrBadParamInput},
+		{"Overlaps", suspended("2024-03-25", "2024-04-10"), domain.ErrBadParamInput},
+		{"Exceeds allowance", suspended("2024-07-01", "2024-08-15"), domain.ErrNotEligible},
+		{"Allowance is per year", suspended("2024-12-25", "2025-01-31"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validate(tt.suspension, existing, now); !errors.Is(err, tt.wantErr) {
+				t.Errorf("validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOverlap(t *testing.T) {
+	existing := []domain.Suspension{suspended("2024-07-01", "2024-07-14")}
+	err := validate(suspended("2024-07-14", "2024-07-25"), existing, date("2024-06-10"))
+	if !errors.Is(err, domain.ErrConflict) {
+		t.Errorf("validate() error = %v, want %v", err, domain.ErrConflict)
+	}
+}
+
+type stubNotifier struct {
+	keys []string
+}
+
+func (s *stubNotifier) Notify(_ context.Context, _, _, key string, _ ...any) error {
+	s.keys = append(s.keys, key)
+	return nil
+}
+
+func TestWatcher(t *testing.T) {
+	notifier := &stubNotifier{}
+	w := NewWatcher(memory.New().WatchedSuspensions(), notifier, time.Hour, logging.Discard())
+	now := date("2024-06-30")
+	w.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	w.Watch(ctx, "1", domain.Suspension{ID: "s1", StartDate: date("2024-07-01"), EndDate: date("2024-07-14")})
+
+	w.RunOnce(ctx)
+	if len(notifier.keys) != 0 {
+		t.Fatalf("notified %v before the suspension started", notifier.keys)
+	}
+
+	now = date("2024-07-01").Add(9 * time.Hour)
+	w.RunOnce(ctx)
+	w.RunOnce(ctx)
+	now = date("2024-07-15")
+	w.RunOnce(ctx)
+
+	want := []string{"suspension.started", "suspension.ended"}
+	if len(notifier.keys) != len(want) || notifier.keys[0] != want[0] || notifier.keys[1] != want[1] {
+		t.Errorf("notifications = %v, want %v", notifier.keys, want)
+	}
+}
+
+func TestWatcherSurvivesRestart(t *testing.T) {
+	store := memory.New()
+	notifier := &stubNotifier{}
+	now := date("2024-07-01").Add(9 * time.Hour)
+	ctx := context.Background()
+
+	w := NewWatcher(store.WatchedSuspensions(), notifier, time.Hour, logging.Discard())
+	w.now = func() time.Time { return now }
+	w.Watch(ctx, "1", domain.Suspension{ID: "s1", StartDate: date("2024-07-01"), EndDate: date("2024-07-14")})
+	w.Watch(ctx, "1", domain.Suspension{ID: "s2", StartDate: date("2024-08-01"), EndDate: date("2024-08-14")})
+	w.RunOnce(ctx)
+
+	// A new watcher over the same storage neither repeats the start nor
+	// forgets the end.
+	w = NewWatcher(store.WatchedSuspensions(), notifier, time.Hour, logging.Discard())
+	w.now = func() time.Time { return now }
+	w.RunOnce(ctx)
+	w.Unwatch(ctx, "1", "s1")
+	w.Unwatch(ctx, "1", "s2")
+
+	want := []string{"suspension.started", "suspension.ended"}
+	if len(notifier.keys) != len(want) || notifier.keys[0] != want[0] || notifier.keys[1] != want[1] {
+		t.Errorf("notifications = %v, want %v", notifier.keys, want)
+	}
+	if list, _ := store.WatchedSuspensions().List(ctx); len(list) != 0 {
+		t.Errorf("still watching %+v", list)
+	}
+}
@@ -0,0 +1,150 @@
+package suspension
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/storage"
+	"github.com/llchhh/spektr-account-api/notification"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// notificationType groups suspension messages with other service notices.
+const notificationType = "info"
+
+// Notifier tells the user a suspension started or ended.
+type Notifier interface {
+	Notify(ctx context.Context, contractID, notificationType, key string, args ...any) error
+}
+
+// Watcher notifies users when the suspensions they scheduled start and end.
+// It only knows about suspensions scheduled through this service; they are
+// kept in storage so a restart doesn't lose the notifications.
+type Watcher struct {
+	// mu serialises changes so a suspension is announced only once.
+	mu       sync.Mutex
+	watched  storage.WatchedSuspensionRepository
+	notifier Notifier
+	interval time.Duration
+	now      func() time.Time
+	logger   *slog.Logger
+}
+
+// NewWatcher creates a Watcher that checks the dates every interval.
+func NewWatcher(r storage.WatchedSuspensionRepository, n Notifier, interval time.Duration, logger *slog.Logger) *Watcher {
+	return &Watcher{
+		watched:  r,
+		notifier: n,
+		interval: interval,
+		now:      time.Now,
+		logger:   logger,
+	}
+}
+
+// Watch starts tracking a scheduled suspension. The suspension is already
+// scheduled upstream, so failing to track it is logged rather than returned.
+func (w *Watcher) Watch(ctx context.Context, contractID string, suspension domain.Suspension) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.watched.Save(ctx, storage.WatchedSuspension{
+		ContractID:   contractID,
+		SuspensionID: suspension.ID,
+		StartDate:    suspension.StartDate,
+		EndDate:      suspension.EndDate,
+	})
+	if err != nil {
+		w.logger.ErrorContext(ctx, "Suspension: failed to watch", "contract_id", contractID, "suspension_id", suspension.ID, "error", err)
+	}
+}
+
+// Unwatch stops tracking a cancelled suspension. Cancelling one that is
+// already in effect ends it, so the user is told the service is back.
+func (w *Watcher) Unwatch(ctx context.Context, contractID, id string) {
+	w.mu.Lock()
+	item, err := w.watched.Get(ctx, contractID, id)
+	if err == nil {
+		err = w.watched.Delete(ctx, contractID, id)
+	}
+	w.mu.Unlock()
+
+	if errors.Is(err, domain.ErrNotFound) {
+		return
+	}
+	if err != nil {
+		w.logger.ErrorContext(ctx, "Suspension: failed to unwatch", "contract_id", contractID, "suspension_id", id, "error", err)
+		return
+	}
+	if item.Started {
+		w.notify(ctx, contractID, "suspension.ended")
+	}
+}
+
+// Run checks the watched suspensions every interval until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.RunOnce(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce sends the notifications that are due.
+func (w *Watcher) RunOnce(ctx context.Context) {
+	now := today(w.now())
+
+	type event struct {
+		contractID string
+		key        string
+		args       []any
+	}
+	var events []event
+
+	w.mu.Lock()
+	watched, err := w.watched.List(ctx)
+	if err != nil {
+		w.mu.Unlock()
+		w.logger.ErrorContext(ctx, "Suspension: failed to list watched suspensions", "error", err)
+		return
+	}
+	for _, item := range watched {
+		switch {
+		case now.After(item.EndDate):
+			if err := w.watched.Delete(ctx, item.ContractID, item.SuspensionID); err != nil {
+				w.logger.ErrorContext(ctx, "Suspension: failed to unwatch", "contract_id", item.ContractID, "suspension_id", item.SuspensionID, "error", err)
+				continue
+			}
+			events = append(events, event{item.ContractID, "suspension.ended", nil})
+		case !item.Started && !now.Before(item.StartDate):
+			item.Started = true
+			if err := w.watched.Save(ctx, item); err != nil {
+				w.logger.ErrorContext(ctx, "Suspension: failed to record start", "contract_id", item.ContractID, "suspension_id", item.SuspensionID, "error", err)
+				continue
+			}
+			remaining := days(now, item.EndDate)
+			events = append(events, event{item.ContractID, "suspension.started", []any{
+				item.EndDate.Format("02.01.2006"),
+				notification.Plural{Key: "common.days", N: remaining},
+			}})
+		}
+	}
+	w.mu.Unlock()
+
+	for _, e := range events {
+		w.notify(ctx, e.contractID, e.key, e.args...)
+	}
+}
+
+func (w *Watcher) notify(ctx context.Context, contractID, key string, args ...any) {
+	if err := w.notifier.Notify(ctx, contractID, notificationType, key, args...); err != nil {
+		w.logger.ErrorContext(ctx, "Suspension: failed to notify", "contract_id", contractID, "error", err)
+	}
+}
@@ -0,0 +1,172 @@
+package tariff
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+	"math"
+	"time"
+)
+
+type TariffRepository interface {
+	Tariffs(ctx context.Context, token string) ([]domain.Tariff, error)
+	ChangeTariff(ctx context.Context, token, tariffID string, from time.Time) error
+}
+
+// ProfileRepository reads the current tariff, balance and billing period.
+type ProfileRepository interface {
+	Profile(ctx context.Context, token string) (domain.Profile, error)
+}
+
+// ProfileInvalidator drops the cached profiles of a contract once a change
+// makes them stale.
+type ProfileInvalidator interface {
+	InvalidateContract(contractID string)
+}
+
+type Service struct {
+	tariffRepo  TariffRepository
+	profileRepo ProfileRepository
+	profiles    ProfileInvalidator
+	logger      *slog.Logger
+}
+
+// NewService creates a new Service instance with the provided repositories.
+func NewService(t TariffRepository, p ProfileRepository, i ProfileInvalidator, logger *slog.Logger) *Service {
+	return &Service{
+		tariffRepo:  t,
+		profileRepo: p,
+		profiles:    i,
+		logger:      logger,
+	}
+}
+
+// Tariffs lists the tariffs available at the user's address, marking the current one.
+func (s *Service) Tariffs(ctx context.Context, token string) ([]domain.Tariff, error) {
+	ctx, span := tracing.Start(ctx, "tariff.Tariffs")
+	defer span.End()
+
+	profile, tariffs, err := s.load(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	for i := range tariffs {
+		tariffs[i].Current = tariffs[i].ID == profile.TariffID
+	}
+	return tariffs, nil
+}
+
+// PreviewChange calculates the cost of a tariff change without making it.
+func (s *Service) PreviewChange(ctx context.Context, token string, change domain.TariffChange) (domain.TariffChangePreview, error) {
+	ctx, span := tracing.Start(ctx, "tariff.PreviewChange")
+	defer span.End()
+
+	profile, tariffs, err := s.load(ctx, token)
+	if err != nil {
+		return domain.TariffChangePreview{}, err
+	}
+	return preview(profile, tariffs, change, time.Now())
+}
+
+// ChangeTariff switches the tariff now or from the next billing period.
+// An immediate change is refused when the balance can't cover it.
+func (s *Service) ChangeTariff(ctx context.Context, token string, change domain.TariffChange) (domain.TariffChangePreview, error) {
+	ctx, span := tracing.Start(ctx, "tariff.ChangeTariff")
+	defer span.End()
+
+	profile, tariffs, err := s.load(ctx, token)
+	if err != nil {
+		return domain.TariffChangePreview{}, err
+	}
+	p, err := preview(profile, tariffs, change, time.Now())
+	if err != nil {
+		return domain.TariffChangePreview{}, err
+	}
+	if change.When == domain.TariffChangeNow && !p.Sufficient {
+		return p, domain.ErrInsufficientFunds
+	}
+
+	s.logger.InfoContext(ctx, "Changing tariff", "tariff_id", change.TariffID, "when", change.When)
+	if err := s.tariffRepo.ChangeTariff(ctx, token, change.TariffID, p.EffectiveFrom); err != nil {
+		s.logger.ErrorContext(ctx, "Error changing tariff", "error", err)
+		return domain.TariffChangePreview{}, err
+	}
+	s.profiles.InvalidateContract(profile.ID)
+	return p, nil
+}
+
+func (s *Service) load(ctx context.Context, token string) (domain.Profile, []domain.Tariff, error) {
+	if token == "" || middleware.ContainsForbiddenChars(token) {
+		return domain.Profile{}, nil, domain.ErrInvalidToken
+	}
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		return domain.Profile{}, nil, err
+	}
+	tariffs, err := s.tariffRepo.Tariffs(ctx, token)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching tariffs", "error", err)
+		return domain.Profile{}, nil, err
+	}
+	return profile, tariffs, nil
+}
+
+// preview prices a change. Switching now writes off the price difference
+// for the rest of the month; switching from the next period costs nothing
+// today but the new price must be on the balance by then.
+func preview(profile domain.Profile, tariffs []domain.Tariff, change domain.TariffChange, now time.Time) (domain.TariffChangePreview, error) {
+	var target, current *domain.Tariff
+	for i := range tariffs {
+		if tariffs[i].ID == change.TariffID {
+			target = &tariffs[i]
+		}
+		if tariffs[i].ID == profile.TariffID {
+			current = &tariffs[i]
+		}
+	}
+	if target == nil {
+		return domain.TariffChangePreview{}, domain.ErrNotFound
+	}
+	if current != nil && current.ID == target.ID {
+		return domain.TariffChangePreview{}, domain.ErrConflict
+	}
+	target.Current = false
+	if profile.Balance == nil {
+		return domain.TariffChangePreview{}, domain.ErrBalanceUnknown
+	}
+
+	p := domain.TariffChangePreview{Tariff: *target}
+	switch change.When {
+	case domain.TariffChangeNow:
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		daysInMonth := today.AddDate(0, 1, -today.Day()).Day()
+		remaining := float64(daysInMonth - today.Day() + 1)
+
+		diff := target.Price.Minor
+		if current != nil {
+			diff -= current.Price.Minor
+		}
+		p.EffectiveFrom = today
+		p.Charge = domain.RUB(max(0, int64(math.Round(float64(diff)*remaining/float64(daysInMonth)))))
+		p.BalanceAfter = domain.RUB(profile.Balance.Minor - p.Charge.Minor)
+		p.Sufficient = p.BalanceAfter.Minor >= 0
+	case domain.TariffChangeNextPeriod:
+		p.EffectiveFrom = nextPeriodStart(profile, now)
+		p.BalanceAfter = *profile.Balance
+		p.Sufficient = p.BalanceAfter.Minor >= target.Price.Minor
+	default:
+		return domain.TariffChangePreview{}, domain.ErrBadParamInput
+	}
+	return p, nil
+}
+
+// nextPeriodStart is the next payment date, or the first day of next month
+// when the billing API did not report one.
+func nextPeriodStart(profile domain.Profile, now time.Time) time.Time {
+	if next, err := time.ParseInLocation(time.DateOnly, profile.NextPayDate, now.Location()); err == nil && next.After(now) {
+		return next
+	}
+	return time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())
+}
@@ -0,0 +1,61 @@
+package tariff
+
+import (
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"testing"
+	"time"
+)
+
+func TestPreview(t *testing.T) {
+	// June has 30 days; on the 16th there are 15 days left including today.
+	now := time.Date(2024, 6, 16, 10, 0, 0, 0, time.UTC)
+	tariffs := []domain.Tariff{
+		{ID: "1", Name: "Базовый", Price: domain.RUB(60000)},
+		{ID: "2", Name: "Быстрый", Price: domain.RUB(90000)},
+		{ID: "3", Name: "Эконом", Price: domain.RUB(40000)},
+	}
+	profile := domain.Profile{Tariff: "Базовый (архив)", TariffID: "1", Balance: rub(10000), NextPayDate: "2024-07-01"}
+
+	tests := []struct {
+		name           string
+		change         domain.TariffChange
+		wantCharge     int64
+		wantSufficient bool
+		wantFrom       time.Time
+		wantErr        error
+	}{
+		{"Upgrade now", domain.TariffChange{TariffID: "2", When: domain.TariffChangeNow}, 15000, false, time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC), nil},
+		{"Downgrade now is free", domain.TariffChange{TariffID: "3", When: domain.TariffChangeNow}, 0, true, time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC), nil},
+		{"Next period", domain.TariffChange{TariffID: "3", When: domain.TariffChangeNextPeriod}, 0, false, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"Unknown tariff", domain.TariffChange{TariffID: "9", When: domain.TariffChangeNow}, 0, false, time.Time{}, domain.ErrNotFound},
+		{"Current tariff", domain.TariffChange{TariffID: "1", When: domain.TariffChangeNow}, 0, false, time.Time{}, domain.ErrConflict},
+		{"Unknown schedule", domain.TariffChange{TariffID: "2", When: "tomorrow"}, 0, false, time.Time{}, domain.ErrBadParamInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := preview(profile, tariffs, tt.change, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("preview() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Charge.Minor != tt.wantCharge {
+				t.Errorf("charge = %v, want %d kopecks", got.Charge, tt.wantCharge)
+			}
+			if got.Sufficient != tt.wantSufficient {
+				t.Errorf("sufficient = %v, want %v", got.Sufficient, tt.wantSufficient)
+			}
+			if !got.EffectiveFrom.Equal(tt.wantFrom) {
+				t.Errorf("effective from = %v, want %v", got.EffectiveFrom, tt.wantFrom)
+			}
+		})
+	}
+}
+
+func rub(minor int64) *domain.Money {
+	m := domain.RUB(minor)
+	return &m
+}
@@ -0,0 +1,55 @@
+package usage
+
+import (
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/metrics"
+	"sync"
+	"time"
+)
+
+// maxCachedMonths bounds the cache; when it is full an arbitrary month is evicted.
+const maxCachedMonths = 10000
+
+// monthUsage is the usage of one calendar month.
+type monthUsage struct {
+	traffic  []domain.TrafficPoint
+	sessions []domain.ConnectionSession
+}
+
+// monthCache keeps the usage of past months per contract. Past months never
+// change, so entries don't expire.
+type monthCache struct {
+	mu      sync.Mutex
+	entries map[monthKey]monthUsage
+}
+
+type monthKey struct {
+	contractID string
+	month      string
+}
+
+func newMonthCache() *monthCache {
+	return &monthCache{entries: make(map[monthKey]monthUsage)}
+}
+
+func (c *monthCache) get(contractID string, month time.Time) (monthUsage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	m, ok := c.entries[monthKey{contractID, month.Format("2006-01")}]
+	metrics.CacheLookup("usage_month", ok)
+	return m, ok
+}
+
+func (c *monthCache) put(contractID string, month time.Time, m monthUsage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCachedMonths {
+		for key := range c.entries {
+			delete(c.entries, key)
+			break
+		}
+	}
+	c.entries[monthKey{contractID, month.Format("2006-01")}] = m
+}
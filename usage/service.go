@@ -0,0 +1,220 @@
+package usage
+
+import (
+	"context"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/rest/middleware"
+	"github.com/llchhh/spektr-account-api/internal/tracing"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+const (
+	// defaultDays is the range returned when the query has no dates.
+	defaultDays = 30
+	// maxDays is the longest range a single query may cover.
+	maxDays = 366
+	// maxSessions caps the number of sessions returned, most recent first.
+	maxSessions = 100
+)
+
+type UsageRepository interface {
+	DailyTraffic(ctx context.Context, token string, from, to time.Time) ([]domain.TrafficPoint, error)
+	Sessions(ctx context.Context, token string, from, to time.Time) ([]domain.ConnectionSession, error)
+}
+
+// ProfileRepository resolves the contract a session belongs to.
+type ProfileRepository interface {
+	Profile(ctx context.Context, token string) (domain.Profile, error)
+}
+
+type Service struct {
+	usageRepo   UsageRepository
+	profileRepo ProfileRepository
+	cache       *monthCache
+	now         func() time.Time
+	logger      *slog.Logger
+}
+
+// NewService creates a new Service instance.
+func NewService(u UsageRepository, p ProfileRepository, logger *slog.Logger) *Service {
+	return &Service{
+		usageRepo:   u,
+		profileRepo: p,
+		cache:       newMonthCache(),
+		now:         time.Now,
+		logger:      logger,
+	}
+}
+
+// Usage returns the traffic aggregated into buckets and the most recent
+// connection sessions over the requested range. Months that are over are
+// served from the cache; only the current month is fetched every time.
+func (s *Service) Usage(ctx context.Context, token string, query domain.UsageQuery) (domain.Usage, error) {
+	ctx, span := tracing.Start(ctx, "usage.Usage")
+	defer span.End()
+
+	if token == "" || middleware.ContainsForbiddenChars(token) {
+		return domain.Usage{}, domain.ErrInvalidToken
+	}
+	from, to, bucket, err := parseQuery(query, s.now())
+	if err != nil {
+		return domain.Usage{}, err
+	}
+
+	profile, err := s.profileRepo.Profile(ctx, token)
+	if err != nil {
+		return domain.Usage{}, err
+	}
+
+	var days []domain.TrafficPoint
+	var sessions []domain.ConnectionSession
+	for month := startOfMonth(from); !month.After(to); month = month.AddDate(0, 1, 0) {
+		m, err := s.month(ctx, token, profile.ID, month)
+		if err != nil {
+			return domain.Usage{}, err
+		}
+		for _, p := range m.traffic {
+			if !p.Period.Before(from) && !p.Period.After(to) {
+				days = append(days, p)
+			}
+		}
+		for _, session := range m.sessions {
+			if !session.Start.Before(from) && session.Start.Before(to.AddDate(0, 0, 1)) {
+				sessions = append(sessions, session)
+			}
+		}
+	}
+
+	usage := domain.Usage{
+		From:     from,
+		To:       to,
+		Bucket:   bucket,
+		Traffic:  aggregate(days, bucket),
+		Sessions: recentSessions(sessions),
+	}
+	for _, p := range days {
+		usage.BytesIn += p.BytesIn
+		usage.BytesOut += p.BytesOut
+	}
+	return usage, nil
+}
+
+// month returns the traffic and sessions of one calendar month, from the
+// cache if the month is over.
+func (s *Service) month(ctx context.Context, token, contractID string, month time.Time) (monthUsage, error) {
+	today := startOfDay(s.now())
+	end := month.AddDate(0, 1, -1)
+	closed := end.Before(today)
+
+	if closed {
+		if m, ok := s.cache.get(contractID, month); ok {
+			return m, nil
+		}
+	}
+
+	traffic, err := s.usageRepo.DailyTraffic(ctx, token, month, end)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching traffic", "contract_id", contractID, "error", err)
+		return monthUsage{}, err
+	}
+	sessions, err := s.usageRepo.Sessions(ctx, token, month, end)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "Error fetching sessions", "contract_id", contractID, "error", err)
+		return monthUsage{}, err
+	}
+
+	m := monthUsage{traffic: traffic, sessions: sessions}
+	// A session still open at the end of the month keeps counting, so the
+	// month is only final once all of its sessions have ended.
+	if closed && allEnded(sessions) {
+		s.cache.put(contractID, month, m)
+	}
+	return m, nil
+}
+
+// parseQuery validates the query and applies the defaults.
+func parseQuery(query domain.UsageQuery, now time.Time) (from, to time.Time, bucket domain.UsageBucket, err error) {
+	today := startOfDay(now)
+
+	to = today
+	if query.To != "" {
+		if to, err = time.ParseInLocation(time.DateOnly, query.To, time.Local); err != nil {
+			return time.Time{}, time.Time{}, "", domain.ErrBadParamInput
+		}
+	}
+	from = to.AddDate(0, 0, -(defaultDays - 1))
+	if query.From != "" {
+		if from, err = time.ParseInLocation(time.DateOnly, query.From, time.Local); err != nil {
+			return time.Time{}, time.Time{}, "", domain.ErrBadParamInput
+		}
+	}
+	if to.After(today) {
+		to = today
+	}
+	if from.After(to) || to.Sub(from) >= maxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, "", domain.ErrBadParamInput
+	}
+
+	switch query.Bucket {
+	case "", domain.UsageBucketDay:
+		bucket = domain.UsageBucketDay
+	case domain.UsageBucketMonth:
+		bucket = domain.UsageBucketMonth
+	default:
+		return time.Time{}, time.Time{}, "", domain.ErrBadParamInput
+	}
+	return from, to, bucket, nil
+}
+
+// aggregate sums daily traffic into buckets in chronological order.
+func aggregate(days []domain.TrafficPoint, bucket domain.UsageBucket) []domain.TrafficPoint {
+	points := make([]domain.TrafficPoint, 0, len(days))
+	index := make(map[time.Time]int)
+	for _, day := range days {
+		period := startOfDay(day.Period)
+		if bucket == domain.UsageBucketMonth {
+			period = startOfMonth(period)
+		}
+		i, ok := index[period]
+		if !ok {
+			i = len(points)
+			index[period] = i
+			points = append(points, domain.TrafficPoint{Period: period})
+		}
+		points[i].BytesIn += day.BytesIn
+		points[i].BytesOut += day.BytesOut
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Period.Before(points[j].Period) })
+	return points
+}
+
+// recentSessions orders sessions from the most recent and keeps at most maxSessions.
+func recentSessions(sessions []domain.ConnectionSession) []domain.ConnectionSession {
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Start.After(sessions[j].Start) })
+	if len(sessions) > maxSessions {
+		sessions = sessions[:maxSessions]
+	}
+	if sessions == nil {
+		sessions = []domain.ConnectionSession{}
+	}
+	return sessions
+}
+
+func allEnded(sessions []domain.ConnectionSession) bool {
+	for _, session := range sessions {
+		if session.End == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func startOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+}
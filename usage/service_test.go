@@ -0,0 +1,128 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"github.com/llchhh/spektr-account-api/domain"
+	"github.com/llchhh/spektr-account-api/internal/logging"
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, _ := time.ParseInLocation(time.DateOnly, s, time.Local)
+	return t
+}
+
+func TestParseQuery(t *testing.T) {
+	now := date("2024-06-10").Add(15 * time.Hour)
+
+	tests := []struct {
+		name       string
+		query      domain.UsageQuery
+		wantFrom   string
+		wantTo     string
+		wantBucket domain.UsageBucket
+		wantErr    error
+	}{
+		{"Defaults", domain.UsageQuery{}, "2024-05-12", "2024-06-10", domain.UsageBucketDay, nil},
+		{"Range", domain.UsageQuery{From: "2024-01-01", To: "2024-03-31", Bucket: "month"}, "2024-01-01", "2024-03-31", domain.UsageBucketMonth, nil},
+		{"Future end is capped", domain.UsageQuery{From: "2024-06-01", To: "2024-07-01"}, "2024-06-01", "2024-06-10", domain.UsageBucketDay, nil},
+		{"Bad date", domain.UsageQuery{From: "01.06.2024"}, "", "", "", domain.ErrBadParamInput},
+		{"Reversed", domain.UsageQuery{From: "2024-06-05", To: "2024-06-01"}, "", "", "", domain.ErrBadParamInput},
+		{"Too long", domain.UsageQuery{From: "2023-01-01", To: "2024-06-01"}, "", "", "", domain.ErrBadParamInput},
+		{"Unknown bucket", domain.UsageQuery{Bucket: "week"}, "", "", "", domain.ErrBadParamInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, bucket, err := parseQuery(tt.query, now)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parseQuery() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got := from.Format(time.DateOnly); got != tt.wantFrom {
+				t.Errorf("from = %s, want %s", got, tt.wantFrom)
+			}
+			if got := to.Format(time.DateOnly); got != tt.wantTo {
+				t.Errorf("to = %s, want %s", got, tt.wantTo)
+			}
+			if bucket != tt.wantBucket {
+				t.Errorf("bucket = %s, want %s", bucket, tt.wantBucket)
+			}
+		})
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	days := []domain.TrafficPoint{
+		{Period: date("2024-02-01"), BytesIn: 5, BytesOut: 1},
+		{Period: date("2024-01-30"), BytesIn: 10, BytesOut: 2},
+		{Period: date("2024-01-31"), BytesIn: 20, BytesOut: 3},
+	}
+
+	got := aggregate(days, domain.UsageBucketMonth)
+	want := []domain.TrafficPoint{
+		{Period: date("2024-01-01"), BytesIn: 30, BytesOut: 5},
+		{Period: date("2024-02-01"), BytesIn: 5, BytesOut: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("aggregate() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if !got[i].Period.Equal(want[i].Period) || got[i].BytesIn != want[i].BytesIn || got[i].BytesOut != want[i].BytesOut {
+			t.Errorf("aggregate()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if got := aggregate(days, domain.UsageBucketDay); len(got) != 3 || !got[0].Period.Equal(date("2024-01-30")) {
+		t.Errorf("aggregate() by day = %v", got)
+	}
+}
+
+type stubRepo struct {
+	calls map[string]int
+}
+
+func (s *stubRepo) Profile(context.Context, string) (domain.Profile, error) {
+	return domain.Profile{ID: "1"}, nil
+}
+
+func (s *stubRepo) DailyTraffic(_ context.Context, _ string, from, _ time.Time) ([]domain.TrafficPoint, error) {
+	s.calls[from.Format("2006-01")]++
+	return []domain.TrafficPoint{{Period: from, BytesIn: 100, BytesOut: 10}}, nil
+}
+
+func (s *stubRepo) Sessions(_ context.Context, _ string, from, _ time.Time) ([]domain.ConnectionSession, error) {
+	end := from.Add(time.Hour)
+	return []domain.ConnectionSession{{ID: from.Format("2006-01"), Start: from, End: &end}}, nil
+}
+
+func TestUsageCachesPastMonths(t *testing.T) {
+	repo := &stubRepo{calls: make(map[string]int)}
+	s := NewService(repo, repo, logging.Discard())
+	s.now = func() time.Time { return date("2024-03-15") }
+	query := domain.UsageQuery{From: "2024-01-01", To: "2024-03-15", Bucket: domain.UsageBucketMonth}
+
+	for range 2 {
+		usage, err := s.Usage(context.Background(), "token", query)
+		if err != nil {
+			t.Fatalf("Usage() error = %v", err)
+		}
+		if len(usage.Traffic) != 3 || usage.BytesIn != 300 || len(usage.Sessions) != 3 {
+			t.Fatalf("Usage() = %+v", usage)
+		}
+		if usage.Sessions[0].ID != "2024-03" {
+			t.Errorf("first session = %s, want the most recent", usage.Sessions[0].ID)
+		}
+	}
+
+	want := map[string]int{"2024-01": 1, "2024-02": 1, "2024-03": 2}
+	for month, n := range want {
+		if repo.calls[month] != n {
+			t.Errorf("fetched %s %d times, want %d", month, repo.calls[month], n)
+		}
+	}
+}